@@ -41,6 +41,14 @@ type ConfigProvider interface {
 	GetConfig(resourceName string, target any) error
 }
 
+// WatchableConfigProvider notifies about changes of the resources content
+type WatchableConfigProvider interface {
+	ConfigProvider
+	// Watch calls the callback each time the resource is changed, created or removed.
+	// The callback is called from a background goroutine. The returned function stops the watching.
+	Watch(resourceName string, callback func()) (cancel func(), err error)
+}
+
 type Factory interface {
 	GetBoxConfig() BoxConfig
 	Register(factories ...func(ConfigProvider) (Module, error)) error
@@ -49,3 +57,10 @@ type Factory interface {
 	GetCustomConfiguration(any any) error
 	io.Closer
 }
+
+// ConfigWatcher is implemented by factories that can watch the custom configuration
+type ConfigWatcher interface {
+	// WatchCustomConfiguration calls the callback each time the custom configuration is changed.
+	// The callback is called from a background goroutine. The returned function stops the watching.
+	WatchCustomConfiguration(callback func()) (cancel func(), err error)
+}
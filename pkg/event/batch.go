@@ -0,0 +1,95 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package event
+
+import (
+	"fmt"
+
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// eventsFieldNumber is the number of the events field in EventBatch
+const eventsFieldNumber = 2
+
+// ToBatches converts the event tree into batches which serialized size does not exceed maxBatchSize.
+// Each batch contains the events with the same parent that is set as the batch parent.
+// Batches with parents are placed before batches with their children.
+func (e *Event) ToBatches(maxBatchSize int, parentID *p_buff.EventID) ([]*p_buff.EventBatch, error) {
+	if maxBatchSize <= 0 {
+		return nil, fmt.Errorf("max batch size must be positive but was %d", maxBatchSize)
+	}
+	root, err := e.ToProto(parentID)
+	if err != nil {
+		return nil, err
+	}
+	var batches []*p_buff.EventBatch
+	batches, err = appendBatches(batches, parentID, []*p_buff.Event{root}, maxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	return e.subEventBatches(batches, maxBatchSize)
+}
+
+func (e *Event) subEventBatches(batches []*p_buff.EventBatch, maxBatchSize int) ([]*p_buff.EventBatch, error) {
+	if len(e.subEvents) == 0 {
+		return batches, nil
+	}
+	children := make([]*p_buff.Event, 0, len(e.subEvents))
+	for _, subEvent := range e.subEvents {
+		child, err := subEvent.ToProto(e.id)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	batches, err := appendBatches(batches, e.id, children, maxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, subEvent := range e.subEvents {
+		batches, err = subEvent.subEventBatches(batches, maxBatchSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func appendBatches(batches []*p_buff.EventBatch, parentID *p_buff.EventID, events []*p_buff.Event, maxBatchSize int) ([]*p_buff.EventBatch, error) {
+	parentSize := 0
+	if parentID != nil {
+		parentSize = protowire.SizeTag(1) + protowire.SizeBytes(proto.Size(parentID))
+	}
+	var current *p_buff.EventBatch
+	currentSize := 0
+	for _, event := range events {
+		eventSize := protowire.SizeTag(eventsFieldNumber) + protowire.SizeBytes(proto.Size(event))
+		if parentSize+eventSize > maxBatchSize {
+			return nil, fmt.Errorf("event %s has size %d that exceeds max batch size %d",
+				event.GetId().GetId(), parentSize+eventSize, maxBatchSize)
+		}
+		if current == nil || currentSize+eventSize > maxBatchSize {
+			current = &p_buff.EventBatch{ParentEventId: parentID}
+			currentSize = parentSize
+			batches = append(batches, current)
+		}
+		current.Events = append(current.Events, event)
+		currentSize += eventSize
+	}
+	return batches, nil
+}
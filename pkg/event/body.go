@@ -0,0 +1,145 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package event
+
+import "encoding/json"
+
+// Component is a part of the event body. The body is serialized as a JSON array of components.
+type Component interface {
+	json.Marshaler
+}
+
+// Message is a text component
+type Message struct {
+	Data string
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	}{"message", m.Data})
+}
+
+// Table is a component with rows of the same structure. Each row is serialized as a JSON object.
+type Table struct {
+	Rows []any
+}
+
+func (t Table) MarshalJSON() ([]byte, error) {
+	rows := t.Rows
+	if rows == nil {
+		rows = []any{}
+	}
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Rows []any  `json:"rows"`
+	}{"table", rows})
+}
+
+// TreeTableEntry is either TreeTableRow or TreeTableCollection
+type TreeTableEntry interface {
+	json.Marshaler
+}
+
+// TreeTable is a component with nested named rows. The entries are ordered by name.
+type TreeTable struct {
+	Rows map[string]TreeTableEntry
+}
+
+func (t TreeTable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string                    `json:"type"`
+		Rows map[string]TreeTableEntry `json:"rows"`
+	}{"treeTable", nonNil(t.Rows)})
+}
+
+type TreeTableRow struct {
+	Columns map[string]any
+}
+
+func (r TreeTableRow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string         `json:"type"`
+		Columns map[string]any `json:"columns"`
+	}{"row", nonNil(r.Columns)})
+}
+
+type TreeTableCollection struct {
+	Rows map[string]TreeTableEntry
+}
+
+func (c TreeTableCollection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string                    `json:"type"`
+		Rows map[string]TreeTableEntry `json:"rows"`
+	}{"collection", nonNil(c.Rows)})
+}
+
+type VerificationStatus string
+
+const (
+	VerificationPassed       VerificationStatus = "PASSED"
+	VerificationFailed       VerificationStatus = "FAILED"
+	VerificationNotAvailable VerificationStatus = "NA"
+)
+
+// Verification is a component with the result of a message comparison. The fields are ordered by name.
+type Verification struct {
+	Fields map[string]VerificationEntry
+}
+
+func (v Verification) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type   string                       `json:"type"`
+		Fields map[string]VerificationEntry `json:"fields"`
+	}{"verification", nonNil(v.Fields)})
+}
+
+// VerificationEntry is a compared field. It is serialized as a collection if it has nested fields.
+type VerificationEntry struct {
+	Operation string
+	Status    VerificationStatus
+	Key       bool
+	Actual    string
+	Expected  string
+	Hint      string
+	Fields    map[string]VerificationEntry
+}
+
+func (v VerificationEntry) MarshalJSON() ([]byte, error) {
+	entryType := "field"
+	if len(v.Fields) > 0 {
+		entryType = "collection"
+	}
+	return json.Marshal(struct {
+		Type      string                       `json:"type"`
+		Operation string                       `json:"operation,omitempty"`
+		Status    VerificationStatus           `json:"status,omitempty"`
+		Key       bool                         `json:"key"`
+		Actual    string                       `json:"actual"`
+		Expected  string                       `json:"expected"`
+		Hint      string                       `json:"hint,omitempty"`
+		Fields    map[string]VerificationEntry `json:"fields,omitempty"`
+	}{entryType, v.Operation, v.Status, v.Key, v.Actual, v.Expected, v.Hint, v.Fields})
+}
+
+func nonNil[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return map[string]V{}
+	}
+	return m
+}
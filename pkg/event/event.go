@@ -0,0 +1,220 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package event helps to build th2 events and to pack them into batches for event.Router.
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/th2-net/th2-common-go/pkg/common"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Status int
+
+const (
+	Success Status = iota
+	Failed
+)
+
+// IDGenerator creates unique event IDs in the book and the scope of the box
+type IDGenerator struct {
+	book    string
+	scope   string
+	prefix  string
+	counter atomic.Uint64
+}
+
+func NewIDGenerator(boxConfig common.BoxConfig) (*IDGenerator, error) {
+	if boxConfig.Book == "" {
+		return nil, errors.New("book name is not set in the box configuration")
+	}
+	if boxConfig.Name == "" {
+		return nil, errors.New("box name is not set in the box configuration")
+	}
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("cannot generate ID prefix: %w", err)
+	}
+	return &IDGenerator{
+		book:   boxConfig.Book,
+		scope:  boxConfig.Name,
+		prefix: hex.EncodeToString(random),
+	}, nil
+}
+
+func (g *IDGenerator) next(start time.Time) *p_buff.EventID {
+	return &p_buff.EventID{
+		Id:             fmt.Sprintf("%s-%d", g.prefix, g.counter.Add(1)),
+		BookName:       g.book,
+		Scope:          g.scope,
+		StartTimestamp: timestamppb.New(start),
+	}
+}
+
+// Start creates a new event with the current time as the start timestamp
+func (g *IDGenerator) Start() *Event {
+	return &Event{
+		generator: g,
+		id:        g.next(time.Now()),
+		status:    Success,
+	}
+}
+
+// Event is a builder of th2 event and its sub-events. It is not safe for concurrent use.
+type Event struct {
+	generator  *IDGenerator
+	id         *p_buff.EventID
+	end        time.Time
+	name       string
+	eventType  string
+	status     Status
+	body       []Component
+	messageIDs []*p_buff.MessageID
+	subEvents  []*Event
+}
+
+func (e *Event) Name(name string) *Event {
+	e.name = name
+	return e
+}
+
+func (e *Event) Type(eventType string) *Event {
+	e.eventType = eventType
+	return e
+}
+
+func (e *Event) Status(status Status) *Event {
+	e.status = status
+	return e
+}
+
+// Body appends the components to the event body
+func (e *Event) Body(components ...Component) *Event {
+	e.body = append(e.body, components...)
+	return e
+}
+
+// MessageID attaches the messages to the event. The messages must belong to the same book as the event.
+func (e *Event) MessageID(ids ...*p_buff.MessageID) *Event {
+	e.messageIDs = append(e.messageIDs, ids...)
+	return e
+}
+
+// AddSubEvent adds the event created by the same generator as a child of this event
+func (e *Event) AddSubEvent(subEvent *Event) *Event {
+	e.subEvents = append(e.subEvents, subEvent)
+	return e
+}
+
+// Child creates a new event and adds it as a child of this event
+func (e *Event) Child() *Event {
+	child := e.generator.Start()
+	e.AddSubEvent(child)
+	return child
+}
+
+// End sets the end timestamp of the event. The time of the conversion to protobuf is used if End is not called.
+func (e *Event) End() *Event {
+	e.end = time.Now()
+	return e
+}
+
+func (e *Event) ID() *p_buff.EventID {
+	return e.id
+}
+
+// IsSuccess returns false if the event or any of its sub-events has the Failed status
+func (e *Event) IsSuccess() bool {
+	if e.status == Failed {
+		return false
+	}
+	for _, subEvent := range e.subEvents {
+		if !subEvent.IsSuccess() {
+			return false
+		}
+	}
+	return true
+}
+
+// ToProto converts the event without its sub-events
+func (e *Event) ToProto(parentID *p_buff.EventID) (*p_buff.Event, error) {
+	for _, messageID := range e.messageIDs {
+		if messageID.GetBookName() != e.id.BookName {
+			return nil, fmt.Errorf("attached message %v belongs to book '%s' but event belongs to '%s'",
+				messageID, messageID.GetBookName(), e.id.BookName)
+		}
+	}
+	components := e.body
+	if components == nil {
+		components = []Component{}
+	}
+	body, err := json.Marshal(components)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize body of event %s: %w", e.id.Id, err)
+	}
+	end := e.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	status := p_buff.EventStatus_SUCCESS
+	if !e.IsSuccess() {
+		status = p_buff.EventStatus_FAILED
+	}
+	return &p_buff.Event{
+		Id:                 e.id,
+		ParentId:           parentID,
+		EndTimestamp:       timestamppb.New(end),
+		Status:             status,
+		Name:               e.name,
+		Type:               e.eventType,
+		Body:               body,
+		AttachedMessageIds: e.messageIDs,
+	}, nil
+}
+
+// ToProtoList converts the event and all its sub-events. Parents go before their children.
+func (e *Event) ToProtoList(parentID *p_buff.EventID) ([]*p_buff.Event, error) {
+	var result []*p_buff.Event
+	err := e.walk(parentID, func(event *p_buff.Event) {
+		result = append(result, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Event) walk(parentID *p_buff.EventID, consumer func(event *p_buff.Event)) error {
+	event, err := e.ToProto(parentID)
+	if err != nil {
+		return err
+	}
+	consumer(event)
+	for _, subEvent := range e.subEvents {
+		if err := subEvent.walk(e.id, consumer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/th2-net/th2-common-go/pkg/common"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+func newGenerator(t *testing.T) *IDGenerator {
+	generator, err := NewIDGenerator(common.BoxConfig{Name: "box", Book: "book"})
+	require.NoError(t, err)
+	return generator
+}
+
+func TestNewIDGeneratorRequiresBook(t *testing.T) {
+	_, err := NewIDGenerator(common.BoxConfig{Name: "box"})
+	assert.Error(t, err)
+}
+
+func TestEventToProto(t *testing.T) {
+	generator := newGenerator(t)
+	root := generator.Start().Name("root").Type("test")
+	child := root.Child().Name("child").Status(Failed).
+		Body(Message{Data: "text"}).
+		MessageID(&p_buff.MessageID{BookName: "book", Sequence: 1}).
+		End()
+
+	events, err := root.ToProtoList(nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "book", events[0].Id.BookName)
+	assert.Equal(t, "box", events[0].Id.Scope)
+	assert.Nil(t, events[0].ParentId)
+	assert.Equal(t, p_buff.EventStatus_FAILED, events[0].Status, "failed child must fail the parent")
+	assert.JSONEq(t, "[]", string(events[0].Body))
+
+	assert.NotEqual(t, events[0].Id.Id, events[1].Id.Id)
+	assert.True(t, proto.Equal(root.ID(), events[1].ParentId))
+	assert.Equal(t, child.ID(), events[1].Id)
+	assert.Equal(t, p_buff.EventStatus_FAILED, events[1].Status)
+	assert.JSONEq(t, `[{"type":"message","data":"text"}]`, string(events[1].Body))
+	assert.Len(t, events[1].AttachedMessageIds, 1)
+}
+
+func TestEventRejectsMessageFromAnotherBook(t *testing.T) {
+	event := newGenerator(t).Start().MessageID(&p_buff.MessageID{BookName: "another"})
+	_, err := event.ToProto(nil)
+	assert.Error(t, err)
+}
+
+func TestBodyComponents(t *testing.T) {
+	body, err := json.Marshal([]Component{
+		Table{Rows: []any{map[string]string{"a": "1"}}},
+		TreeTable{Rows: map[string]TreeTableEntry{
+			"row":        TreeTableRow{Columns: map[string]any{"c": 1}},
+			"collection": TreeTableCollection{Rows: map[string]TreeTableEntry{}},
+		}},
+		Verification{Fields: map[string]VerificationEntry{
+			"group": {Fields: map[string]VerificationEntry{
+				"field": {Operation: "EQUAL", Status: VerificationPassed, Actual: "1", Expected: "1"},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type":"table","rows":[{"a":"1"}]},
+		{"type":"treeTable","rows":{
+			"row":{"type":"row","columns":{"c":1}},
+			"collection":{"type":"collection","rows":{}}
+		}},
+		{"type":"verification","fields":{
+			"group":{"type":"collection","key":false,"actual":"","expected":"","fields":{
+				"field":{"type":"field","operation":"EQUAL","status":"PASSED","key":false,"actual":"1","expected":"1"}
+			}}
+		}}
+	]`, string(body))
+}
+
+func TestToBatchesSplitsBySize(t *testing.T) {
+	generator := newGenerator(t)
+	root := generator.Start().Name("root")
+	for i := 0; i < 10; i++ {
+		root.Child().Name("child").Body(Message{Data: "some data to make the event bigger"})
+	}
+	child, err := root.subEvents[0].ToProto(root.ID())
+	require.NoError(t, err)
+	maxSize := 3*proto.Size(&p_buff.EventBatch{Events: []*p_buff.Event{child}}) + proto.Size(root.ID()) + 10
+
+	batches, err := root.ToBatches(maxSize, nil)
+	require.NoError(t, err)
+	require.Greater(t, len(batches), 2)
+	assert.Len(t, batches[0].Events, 1)
+	assert.Nil(t, batches[0].ParentEventId)
+	count := 0
+	for _, batch := range batches[1:] {
+		assert.LessOrEqual(t, proto.Size(batch), maxSize)
+		assert.True(t, proto.Equal(root.ID(), batch.ParentEventId))
+		count += len(batch.Events)
+	}
+	assert.Equal(t, 10, count)
+
+	_, err = root.ToBatches(10, nil)
+	assert.Error(t, err)
+}
@@ -26,8 +26,11 @@ import (
 	"github.com/magiconair/properties"
 	"github.com/th2-net/th2-common-go/pkg/common"
 	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
+	"io"
+	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 )
 
 const (
@@ -39,13 +42,19 @@ const (
 type Config struct {
 	ConfigurationsDir string
 	FileExtension     string
+	// WatchInterval is the interval of checking the configuration files for changes.
+	// The default interval is used if it is not set
+	WatchInterval time.Duration
 }
 
 type commonFactory struct {
 	modules     map[common.ModuleKey]common.Module
 	cfgProvider common.ConfigProvider
-	zLogger     zerolog.Logger
-	boxConfig   common.BoxConfig
+	// moduleProvider is passed to the modules.
+	// It gives access to the health arbiters and the metrics registry once the prometheus module is registered
+	moduleProvider common.ConfigProvider
+	zLogger        zerolog.Logger
+	boxConfig      common.BoxConfig
 }
 
 func New() common.Factory {
@@ -71,20 +80,31 @@ func NewFromConfig(config Config) (common.Factory, error) {
 	}
 	loadZeroLogConfig(config)
 
-	provider := NewFileProvider(
-		config.ConfigurationsDir,
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = defaultWatchInterval
+	}
+	provider := NewWatchingFileProviderForFS(
+		os.DirFS(config.ConfigurationsDir),
 		config.FileExtension,
+		watchInterval,
 		log.ForComponent("file_provider"),
 	)
 	cf := &commonFactory{
-		modules:     make(map[common.ModuleKey]common.Module),
-		cfgProvider: provider,
-		boxConfig:   provider.GetBoxConfig(),
+		modules:        make(map[common.ModuleKey]common.Module),
+		cfgProvider:    provider,
+		moduleProvider: provider,
+		boxConfig:      provider.GetBoxConfig(),
 	}
 	err := cf.Register(prometheus.NewModule)
 	if err != nil {
 		return nil, err
 	}
+	promModule, err := prometheus.ModuleID.GetModule(cf)
+	if err != nil {
+		return nil, err
+	}
+	cf.moduleProvider = withPrometheus(provider, promModule)
 
 	return cf, nil
 }
@@ -107,7 +127,7 @@ func loadZeroLogConfig(config Config) {
 
 func (cf *commonFactory) Register(factories ...func(common.ConfigProvider) (common.Module, error)) error {
 	for _, factory := range factories {
-		module, err := factory(cf.cfgProvider)
+		module, err := factory(cf.moduleProvider)
 		if err != nil {
 			return err
 		}
@@ -141,6 +161,11 @@ func (cf *commonFactory) Close() error {
 			cf.zLogger.Error().Err(err).Msgf("Module %v raised error", moduleKey)
 		}
 	}
+	if closer, ok := cf.cfgProvider.(io.Closer); ok {
+		if err = closer.Close(); err != nil {
+			cf.zLogger.Error().Err(err).Msg("Config provider raised error")
+		}
+	}
 	return nil
 }
 
@@ -148,6 +173,34 @@ func (cf *commonFactory) GetCustomConfiguration(any any) error {
 	return cf.cfgProvider.GetConfig(customFileName, any)
 }
 
+func (cf *commonFactory) WatchCustomConfiguration(callback func()) (func(), error) {
+	watchable, ok := cf.cfgProvider.(common.WatchableConfigProvider)
+	if !ok {
+		return nil, errors.New("config provider does not support watching")
+	}
+	return watchable.Watch(customFileName, callback)
+}
+
 func (cf *commonFactory) GetBoxConfig() common.BoxConfig {
 	return cf.boxConfig
 }
+
+type prometheusProvider struct {
+	common.ConfigProvider
+	prometheus.Health
+	prometheus.Metrics
+}
+
+type watchablePrometheusProvider struct {
+	common.WatchableConfigProvider
+	prometheus.Health
+	prometheus.Metrics
+}
+
+// withPrometheus adds the health arbiters and the metrics registry to the provider keeping its ability to watch resources
+func withPrometheus(provider common.ConfigProvider, module prometheus.Module) common.ConfigProvider {
+	if watchable, ok := provider.(common.WatchableConfigProvider); ok {
+		return &watchablePrometheusProvider{WatchableConfigProvider: watchable, Health: module, Metrics: module}
+	}
+	return &prometheusProvider{ConfigProvider: provider, Health: module, Metrics: module}
+}
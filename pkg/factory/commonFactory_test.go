@@ -16,7 +16,59 @@
 
 package factory_test
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/th2-net/th2-common-go/pkg/common"
+	"github.com/th2-net/th2-common-go/pkg/factory"
+)
 
 func NewFactory_test(t *testing.T) {
 }
+
+func TestWatchCustomConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "custom.json")
+	if err := os.WriteFile(file, []byte(`{ "key": "value" }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commonFactory, err := factory.NewFromConfig(factory.Config{
+		ConfigurationsDir: dir,
+		FileExtension:     ".json",
+		WatchInterval:     10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = commonFactory.Close() })
+
+	changes := make(chan struct{}, 10)
+	watcher, ok := commonFactory.(common.ConfigWatcher)
+	if !ok {
+		t.Fatal("factory doesn't watch the custom configuration")
+	}
+	cancel, err := watcher.WatchCustomConfiguration(func() { changes <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if err := os.WriteFile(file, []byte(`{ "key": "new" }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change was not reported")
+	}
+	var custom struct {
+		Key string `json:"key"`
+	}
+	if err := commonFactory.GetCustomConfiguration(&custom); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "new", custom.Key)
+}
@@ -17,6 +17,7 @@
 package factory
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"github.com/rs/zerolog"
@@ -24,8 +25,12 @@ import (
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"io/fs"
 	"os"
+	"sync"
+	"time"
 )
 
+const defaultWatchInterval = 5 * time.Second
+
 var (
 	ResourceNotFound = errors.New("resource not found")
 )
@@ -39,10 +44,18 @@ func NewFileProvider(configPath string, extension string, logger zerolog.Logger)
 }
 
 func NewFileProviderForFS(fs fs.FS, extension string, logger zerolog.Logger) common.ConfigProvider {
+	return NewWatchingFileProviderForFS(fs, extension, defaultWatchInterval, logger)
+}
+
+// NewWatchingFileProviderForFS creates a provider that checks the content of watched resources with the interval
+func NewWatchingFileProviderForFS(fs fs.FS, extension string, watchInterval time.Duration, logger zerolog.Logger) common.WatchableConfigProvider {
 	provider := fileConfigProvider{
 		configFS:      fs,
 		fileExtension: extension,
 		zLogger:       &logger,
+		watchInterval: watchInterval,
+		watched:       make(map[string]*watchedResource),
+		done:          make(chan struct{}),
 	}
 	boxConfig := common.BoxConfig{}
 	if err := provider.GetConfig("box", &boxConfig); err != nil {
@@ -57,6 +70,20 @@ type fileConfigProvider struct {
 	fileExtension string
 	zLogger       *zerolog.Logger
 	boxConfig     common.BoxConfig
+
+	watchInterval time.Duration
+	mutex         sync.Mutex
+	watched       map[string]*watchedResource
+	nextWatcherID int
+	watching      bool
+	closed        bool
+	done          chan struct{}
+}
+
+type watchedResource struct {
+	exists    bool
+	hash      [sha256.Size]byte
+	callbacks map[int]func()
 }
 
 func (cfd *fileConfigProvider) GetBoxConfig() common.BoxConfig {
@@ -105,3 +132,88 @@ func (cfd *fileConfigProvider) GetConfig(resourceName string, target any) error
 
 	return nil
 }
+
+func (cfd *fileConfigProvider) Watch(resourceName string, callback func()) (func(), error) {
+	cfd.mutex.Lock()
+	defer cfd.mutex.Unlock()
+	if cfd.closed {
+		return nil, errors.New("config provider is closed")
+	}
+	resource, exists := cfd.watched[resourceName]
+	if !exists {
+		resource = &watchedResource{callbacks: make(map[int]func())}
+		resource.exists, resource.hash = cfd.readHash(resourceName)
+		cfd.watched[resourceName] = resource
+	}
+	id := cfd.nextWatcherID
+	cfd.nextWatcherID++
+	resource.callbacks[id] = callback
+	if !cfd.watching {
+		cfd.watching = true
+		go cfd.watch()
+	}
+	cfd.zLogger.Debug().Str("resource", resourceName).Msg("watching resource")
+	return func() {
+		cfd.mutex.Lock()
+		defer cfd.mutex.Unlock()
+		delete(resource.callbacks, id)
+	}, nil
+}
+
+// Close stops watching the resources
+func (cfd *fileConfigProvider) Close() error {
+	cfd.mutex.Lock()
+	defer cfd.mutex.Unlock()
+	if !cfd.closed {
+		cfd.closed = true
+		close(cfd.done)
+	}
+	return nil
+}
+
+func (cfd *fileConfigProvider) watch() {
+	ticker := time.NewTicker(cfd.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cfd.done:
+			return
+		case <-ticker.C:
+			cfd.checkChanges()
+		}
+	}
+}
+
+func (cfd *fileConfigProvider) checkChanges() {
+	var toNotify []func()
+	cfd.mutex.Lock()
+	for resourceName, resource := range cfd.watched {
+		exists, hash := cfd.readHash(resourceName)
+		if exists == resource.exists && hash == resource.hash {
+			continue
+		}
+		resource.exists, resource.hash = exists, hash
+		cfd.zLogger.Info().
+			Str("resource", resourceName).
+			Bool("exists", exists).
+			Msg("resource changed")
+		for _, callback := range resource.callbacks {
+			toNotify = append(toNotify, callback)
+		}
+	}
+	cfd.mutex.Unlock()
+	for _, callback := range toNotify {
+		callback()
+	}
+}
+
+func (cfd *fileConfigProvider) readHash(resourceName string) (bool, [sha256.Size]byte) {
+	content, err := fs.ReadFile(cfd.configFS, resourceName+cfd.fileExtension)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			cfd.zLogger.Warn().Err(err).Str("resource", resourceName).Msg("cannot read watched resource")
+		}
+		return false, [sha256.Size]byte{}
+	}
+	return true, sha256.Sum256(content)
+}
@@ -20,9 +20,12 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/th2-net/th2-common-go/pkg/factory"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 var logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
@@ -93,3 +96,42 @@ func TestProcessesEnvVariables(t *testing.T) {
 	}
 	assert.Equal(t, str.Key, "value", "unexpected value deserialized")
 }
+
+func TestWatchNotifiesAboutChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.json")
+	if err := os.WriteFile(file, []byte(`{ "key": "value" }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	provider := factory.NewWatchingFileProviderForFS(os.DirFS(dir), ".json", 10*time.Millisecond, logger)
+	t.Cleanup(func() { _ = provider.(io.Closer).Close() })
+
+	changes := make(chan struct{}, 10)
+	cancel, err := provider.Watch("test", func() { changes <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(`{ "key": "new" }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change was not reported")
+	}
+	var str testStr
+	if err := provider.GetConfig("test", &str); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "new", str.Key)
+
+	cancel()
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("change was reported after cancel")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -17,6 +17,8 @@
 package grpc
 
 import (
+	"context"
+
 	"google.golang.org/grpc"
 	"io"
 )
@@ -27,5 +29,12 @@ type Router interface {
 	StartServer(registrar func(grpc.ServiceRegistrar)) error
 	StartServerAsync(registrar func(grpc.ServiceRegistrar)) (StopServer, error)
 	GetConnection(serviceName string) (grpc.ClientConnInterface, error)
+	// GetConnectionCtx is the same as GetConnection but returns an error if ctx is done before the connection is created
+	GetConnectionCtx(ctx context.Context, serviceName string) (grpc.ClientConnInterface, error)
 	io.Closer
 }
+
+// ConfigUpdater is implemented by routers that apply changes of services at runtime
+type ConfigUpdater interface {
+	UpdateConfig(config Config)
+}
@@ -36,12 +36,26 @@ func (cc *connectionCacheMapData[T]) Get(key T) (*grpc.ClientConn, bool) {
 	return conn, exists
 }
 
+func (cc *connectionCacheMapData[T]) Delete(key T) {
+	delete(*cc, key)
+}
+
+func (cc *connectionCacheMapData[T]) Keys() []T {
+	keys := make([]T, 0, len(*cc))
+	for key := range *cc {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 //the following caching design offers the flexibility of having an internal cache of different type than Address,
 //and importantly, insert additional logic in put and get
 
 type connectionCache interface {
 	Put(key Address, conn *grpc.ClientConn)
 	Get(key Address) (*grpc.ClientConn, bool)
+	Delete(key Address)
+	Addresses() []Address
 }
 
 type connectionAddressKeyCache struct {
@@ -59,3 +73,11 @@ func (sc *connectionAddressKeyCache) Put(key Address, conn *grpc.ClientConn) {
 func (sc *connectionAddressKeyCache) Get(key Address) (*grpc.ClientConn, bool) {
 	return sc.internalCache.Get(key)
 }
+
+func (sc *connectionAddressKeyCache) Delete(key Address) {
+	sc.internalCache.Delete(key)
+}
+
+func (sc *connectionAddressKeyCache) Addresses() []Address {
+	return sc.internalCache.Keys()
+}
@@ -38,10 +38,64 @@ type Endpoint struct {
 	Address
 }
 
+// ServerTLSConfig enables TLS for the server.
+// If ClientCACertificate is set the server requires and verifies the client certificate (mTLS)
+type ServerTLSConfig struct {
+	Certificate         string `json:"certificate"`
+	PrivateKey          string `json:"privateKey"`
+	ClientCACertificate string `json:"clientCaCertificate"`
+}
+
+// ClientTLSConfig enables TLS for connections to the services.
+// Certificate and PrivateKey are presented to the server if it requires client authentication
+type ClientTLSConfig struct {
+	CACertificate      string `json:"caCertificate"`
+	Certificate        string `json:"certificate"`
+	PrivateKey         string `json:"privateKey"`
+	ServerName         string `json:"serverName"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+}
+
+// KeepAlive configures HTTP/2 pings. Time and Timeout are in milliseconds
+type KeepAlive struct {
+	Time                int  `json:"time"`
+	Timeout             int  `json:"timeout"`
+	PermitWithoutStream bool `json:"permitWithoutStream"`
+}
+
+// ServerKeepAlive configures HTTP/2 pings of the server and the pings accepted from clients.
+// MinTime is the minimum interval in milliseconds between client pings, the gRPC default is used if it is not set
+type ServerKeepAlive struct {
+	KeepAlive
+	MinTime int `json:"minTime"`
+}
+
 type Server struct {
 	Endpoint
-	Workers int `json:"workers"`
+	Workers               int              `json:"workers"`
+	TLS                   *ServerTLSConfig `json:"tls"`
+	KeepAlive             *ServerKeepAlive `json:"keepAlive"`
+	MaxReceiveMessageSize int              `json:"maxReceiveMessageSize"`
+	MaxSendMessageSize    int              `json:"maxSendMessageSize"`
 }
+
+// Client holds the settings applied to all connections created by the router
+type Client struct {
+	TLS                   *ClientTLSConfig `json:"tls"`
+	KeepAlive             *KeepAlive       `json:"keepAlive"`
+	MaxReceiveMessageSize int              `json:"maxReceiveMessageSize"`
+	MaxSendMessageSize    int              `json:"maxSendMessageSize"`
+}
+
+const (
+	// RobinStrategy sends calls to the endpoints in turn. It is used if the strategy name is not set
+	RobinStrategy = "robin"
+	// FilterStrategy sends calls to the endpoints which have all the attributes added with WithAttributes
+	FilterStrategy = "filter"
+)
+
+// Strategy defines how calls are distributed between the endpoints of the service.
+// Endpoints limit the endpoints used by the strategy, all the endpoints are used if it is empty
 type Strategy struct {
 	Endpoints []string `json:"endpoints"`
 	Name      string   `json:"name"`
@@ -57,21 +111,31 @@ type Services map[string]Service
 
 type Config struct {
 	ServerConfig Server   `json:"server"`
+	ClientConfig Client   `json:"client"`
 	ServicesMap  Services `json:"services"`
 	ZLogger      zerolog.Logger
 }
 
+// ValidatePins checks that strategies of the services are known and refer to existing endpoints
 func (gc *Config) ValidatePins() error {
 	for pinName, service := range gc.ServicesMap {
-		if len(service.Endpoints) > 1 {
-			return fmt.Errorf("config is invalid. pin '%s' has more than 1 endpoint", pinName)
+		switch service.Strategy.Name {
+		case "", RobinStrategy, FilterStrategy:
+		default:
+			return fmt.Errorf("config is invalid. pin '%s' has unknown strategy '%s'", pinName, service.Strategy.Name)
+		}
+		for _, endpointName := range service.Strategy.Endpoints {
+			if _, exists := service.Endpoints[endpointName]; !exists {
+				return fmt.Errorf("config is invalid. pin '%s' strategy refers to unknown endpoint '%s'", pinName, endpointName)
+			}
 		}
-		gc.ZLogger.Info().Msg("Pins validated.")
 	}
+	gc.ZLogger.Info().Msg("Pins validated.")
 	return nil
 }
 
-func (gc *Config) findEndpointAddrViaServiceName(srvName string) (Address, error) {
+// findServiceViaServiceName returns the service whose class (with or without the package) is srvName
+func (gc *Config) findServiceViaServiceName(srvName string) (Service, error) {
 	for _, service := range gc.ServicesMap {
 		serviceName := service.ServiceClass
 		if strings.Contains(serviceName, ".") {
@@ -80,18 +144,15 @@ func (gc *Config) findEndpointAddrViaServiceName(srvName string) (Address, error
 			serviceName = serviceNameList[index-1]
 		}
 		if serviceName == srvName {
-			if len(service.Endpoints) > 1 {
-				return Address{}, fmt.Errorf("number of endpoints should equal to 1")
-			} else {
-				for _, endpoint := range service.Endpoints {
-					gc.ZLogger.Debug().Msg("Endpoint was found")
-					return endpoint.Address, nil
-				}
+			if len(service.Endpoints) == 0 {
+				return Service{}, fmt.Errorf("service %s has no endpoints", srvName)
 			}
+			gc.ZLogger.Debug().Msg("Service was found")
+			return service, nil
 		}
 	}
 	gc.ZLogger.Error().Str("service", srvName).Msg("No endpoint exists")
-	return Address{}, errors.New("endpoint with provided service name does not exist")
+	return Service{}, errors.New("endpoint with provided service name does not exist")
 }
 
 func (gc *Config) getServerAddress() string {
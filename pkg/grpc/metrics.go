@@ -0,0 +1,322 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	serviceLabel = "service"
+	methodLabel  = "method"
+	codeLabel    = "code"
+)
+
+var (
+	callLabels       = []string{serviceLabel, methodLabel}
+	callResultLabels = []string{serviceLabel, methodLabel, codeLabel}
+)
+
+var th2GrpcServerRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_server_requests_total",
+		Help: "Amount of calls handled by the server",
+	},
+	callResultLabels,
+)
+
+var th2GrpcServerRequestDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "th2_grpc_server_request_duration_seconds",
+		Help:    "Time of handling the call by the server",
+		Buckets: metrics.DefaultBuckets,
+	},
+	callLabels,
+)
+
+var th2GrpcServerMessageSizeReceivedBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_server_message_size_received_bytes",
+		Help: "Number of bytes received by the server",
+	},
+	callLabels,
+)
+
+var th2GrpcServerMessageSizeSentBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_server_message_size_sent_bytes",
+		Help: "Number of bytes sent by the server",
+	},
+	callLabels,
+)
+
+var th2GrpcClientRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_client_requests_total",
+		Help: "Amount of calls made by the client",
+	},
+	callResultLabels,
+)
+
+var th2GrpcClientRequestDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "th2_grpc_client_request_duration_seconds",
+		Help:    "Time of the call made by the client",
+		Buckets: metrics.DefaultBuckets,
+	},
+	callLabels,
+)
+
+var th2GrpcClientMessageSizeReceivedBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_client_message_size_received_bytes",
+		Help: "Number of bytes received by the client",
+	},
+	callLabels,
+)
+
+var th2GrpcClientMessageSizeSentBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_grpc_client_message_size_sent_bytes",
+		Help: "Number of bytes sent by the client",
+	},
+	callLabels,
+)
+
+var collectors = []prometheus.Collector{
+	th2GrpcServerRequestsTotal,
+	th2GrpcServerRequestDurationSeconds,
+	th2GrpcServerMessageSizeReceivedBytes,
+	th2GrpcServerMessageSizeSentBytes,
+	th2GrpcClientRequestsTotal,
+	th2GrpcClientRequestDurationSeconds,
+	th2GrpcClientMessageSizeReceivedBytes,
+	th2GrpcClientMessageSizeSentBytes,
+}
+
+// registerMetrics adds the metrics to the registerer.
+// The returned function releases them when the router is closed
+func registerMetrics(registerer prometheus.Registerer) (func(), error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if err := metrics.Register(registerer, collectors...); err != nil {
+		return func() {}, err
+	}
+	return func() { metrics.Unregister(registerer, collectors...) }, nil
+}
+
+// callMetrics holds the metrics of a single service method either on the server or on the client side
+type callMetrics struct {
+	service  string
+	method   string
+	requests *prometheus.CounterVec
+	duration prometheus.Observer
+	received prometheus.Counter
+	sent     prometheus.Counter
+}
+
+func newServerCallMetrics(fullMethod string) *callMetrics {
+	service, method := splitMethodName(fullMethod)
+	return &callMetrics{
+		service:  service,
+		method:   method,
+		requests: th2GrpcServerRequestsTotal,
+		duration: th2GrpcServerRequestDurationSeconds.WithLabelValues(service, method),
+		received: th2GrpcServerMessageSizeReceivedBytes.WithLabelValues(service, method),
+		sent:     th2GrpcServerMessageSizeSentBytes.WithLabelValues(service, method),
+	}
+}
+
+func newClientCallMetrics(fullMethod string) *callMetrics {
+	service, method := splitMethodName(fullMethod)
+	return &callMetrics{
+		service:  service,
+		method:   method,
+		requests: th2GrpcClientRequestsTotal,
+		duration: th2GrpcClientRequestDurationSeconds.WithLabelValues(service, method),
+		received: th2GrpcClientMessageSizeReceivedBytes.WithLabelValues(service, method),
+		sent:     th2GrpcClientMessageSizeSentBytes.WithLabelValues(service, method),
+	}
+}
+
+func (m *callMetrics) onReceived(message any) {
+	m.received.Add(float64(messageSize(message)))
+}
+
+func (m *callMetrics) onSent(message any) {
+	m.sent.Add(float64(messageSize(message)))
+}
+
+func (m *callMetrics) onFinished(start time.Time, err error) {
+	m.duration.Observe(time.Since(start).Seconds())
+	m.requests.WithLabelValues(m.service, m.method, status.Code(err).String()).Inc()
+}
+
+// splitMethodName splits /package.Service/Method into the service and the method names
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if index := strings.LastIndex(fullMethod, "/"); index >= 0 {
+		return fullMethod[:index], fullMethod[index+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func messageSize(message any) int {
+	if protoMessage, ok := message.(proto.Message); ok {
+		return proto.Size(protoMessage)
+	}
+	return 0
+}
+
+func metricsUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	callMetrics := newServerCallMetrics(info.FullMethod)
+	start := time.Now()
+	callMetrics.onReceived(req)
+	resp, err := handler(ctx, req)
+	if err == nil {
+		callMetrics.onSent(resp)
+	}
+	callMetrics.onFinished(start, err)
+	return resp, err
+}
+
+func metricsStreamServerInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	callMetrics := newServerCallMetrics(info.FullMethod)
+	start := time.Now()
+	err := handler(srv, &serverStreamWithMetrics{ServerStream: stream, metrics: callMetrics})
+	callMetrics.onFinished(start, err)
+	return err
+}
+
+type serverStreamWithMetrics struct {
+	grpc.ServerStream
+	metrics *callMetrics
+}
+
+func (s *serverStreamWithMetrics) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.metrics.onSent(m)
+	}
+	return err
+}
+
+func (s *serverStreamWithMetrics) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.metrics.onReceived(m)
+	}
+	return err
+}
+
+func metricsUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	callMetrics := newClientCallMetrics(method)
+	start := time.Now()
+	callMetrics.onSent(req)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err == nil {
+		callMetrics.onReceived(reply)
+	}
+	callMetrics.onFinished(start, err)
+	return err
+}
+
+func metricsStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	callMetrics := newClientCallMetrics(method)
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		callMetrics.onFinished(start, err)
+		return nil, err
+	}
+	wrapped := &clientStreamWithMetrics{
+		ClientStream:  stream,
+		metrics:       callMetrics,
+		start:         start,
+		serverStreams: desc.ServerStreams,
+		finished:      make(chan struct{}),
+	}
+	go wrapped.finishOnDone(ctx)
+	return wrapped, nil
+}
+
+// clientStreamWithMetrics records the call when the stream ends with an error or io.EOF on receiving,
+// when the single response of the call without server streaming is received
+// or when the context of the call is done before the stream ends
+type clientStreamWithMetrics struct {
+	grpc.ClientStream
+	metrics       *callMetrics
+	start         time.Time
+	serverStreams bool
+	finishOnce    sync.Once
+	finished      chan struct{}
+}
+
+func (s *clientStreamWithMetrics) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.metrics.onSent(m)
+	}
+	return err
+}
+
+func (s *clientStreamWithMetrics) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.metrics.onReceived(m)
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStreamWithMetrics) finishOnDone(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.finish(status.FromContextError(ctx.Err()).Err())
+	case <-s.finished:
+	}
+}
+
+func (s *clientStreamWithMetrics) finish(err error) {
+	s.finishOnce.Do(func() {
+		close(s.finished)
+		s.metrics.onFinished(s.start, err)
+	})
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Options holds additional options passed to the server and the connections created by the router.
+// They are applied after the options built from the configuration, so they take precedence
+type Options struct {
+	ServerOptions []grpc.ServerOption
+	DialOptions   []grpc.DialOption
+	// OnServerStateChange is notified when the server started by the router changes its state
+	OnServerStateChange func(state ServerState)
+	// Registerer registers the metrics of the router. The prometheus.DefaultRegisterer is used if it is not set
+	Registerer prometheus.Registerer
+	// UnaryServerInterceptors and StreamServerInterceptors are added to the server after the built-in metrics and tracing interceptors
+	UnaryServerInterceptors  []grpc.UnaryServerInterceptor
+	StreamServerInterceptors []grpc.StreamServerInterceptor
+	// UnaryClientInterceptors and StreamClientInterceptors are added to the connections after the built-in metrics and tracing interceptors
+	UnaryClientInterceptors  []grpc.UnaryClientInterceptor
+	StreamClientInterceptors []grpc.StreamClientInterceptor
+}
+
+func (o Options) serverInterceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{metricsUnaryServerInterceptor, tracingUnaryServerInterceptor},
+			o.UnaryServerInterceptors...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{metricsStreamServerInterceptor, tracingStreamServerInterceptor},
+			o.StreamServerInterceptors...)...),
+	}
+}
+
+func (o Options) clientInterceptors() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(append([]grpc.UnaryClientInterceptor{metricsUnaryClientInterceptor, tracingUnaryClientInterceptor},
+			o.UnaryClientInterceptors...)...),
+		grpc.WithChainStreamInterceptor(append([]grpc.StreamClientInterceptor{metricsStreamClientInterceptor, tracingStreamClientInterceptor},
+			o.StreamClientInterceptors...)...),
+	}
+}
+
+func (s *Server) serverOptions() ([]grpc.ServerOption, error) {
+	var options []grpc.ServerOption
+	if s.TLS != nil {
+		tlsConfig, err := s.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if s.Workers > 0 {
+		options = append(options, grpc.NumStreamWorkers(uint32(s.Workers)))
+	}
+	if s.KeepAlive != nil {
+		options = append(options,
+			grpc.KeepaliveParams(keepalive.ServerParameters{
+				Time:    millis(s.KeepAlive.Time),
+				Timeout: millis(s.KeepAlive.Timeout),
+			}),
+			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+				MinTime:             millis(s.KeepAlive.MinTime),
+				PermitWithoutStream: s.KeepAlive.PermitWithoutStream,
+			}),
+		)
+	}
+	if s.MaxReceiveMessageSize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(s.MaxReceiveMessageSize))
+	}
+	if s.MaxSendMessageSize > 0 {
+		options = append(options, grpc.MaxSendMsgSize(s.MaxSendMessageSize))
+	}
+	return options, nil
+}
+
+func (c *Client) dialOptions() ([]grpc.DialOption, error) {
+	var options []grpc.DialOption
+	if c.TLS != nil {
+		tlsConfig, err := c.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if c.KeepAlive != nil {
+		options = append(options, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                millis(c.KeepAlive.Time),
+			Timeout:             millis(c.KeepAlive.Timeout),
+			PermitWithoutStream: c.KeepAlive.PermitWithoutStream,
+		}))
+	}
+	var callOptions []grpc.CallOption
+	if c.MaxReceiveMessageSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallRecvMsgSize(c.MaxReceiveMessageSize))
+	}
+	if c.MaxSendMessageSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(c.MaxSendMessageSize))
+	}
+	if len(callOptions) > 0 {
+		options = append(options, grpc.WithDefaultCallOptions(callOptions...))
+	}
+	return options, nil
+}
+
+func (c *ServerTLSConfig) build() (*tls.Config, error) {
+	if c.Certificate == "" || c.PrivateKey == "" {
+		return nil, errors.New("server TLS requires both certificate and privateKey")
+	}
+	certificate, err := tls.LoadX509KeyPair(c.Certificate, c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCACertificate != "" {
+		pool, err := loadCertPool(c.ClientCACertificate)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+func (c *ClientTLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.CACertificate != "" {
+		pool, err := loadCertPool(c.CACertificate)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (c.Certificate == "") != (c.PrivateKey == "") {
+		return nil, errors.New("client TLS requires both certificate and privateKey or none of them")
+	}
+	if c.Certificate != "" {
+		certificate, err := tls.LoadX509KeyPair(c.Certificate, c.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+func millis(value int) time.Duration {
+	return time.Duration(value) * time.Millisecond
+}
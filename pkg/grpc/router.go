@@ -17,35 +17,63 @@
 package grpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func NewRouter(config Config, logger zerolog.Logger) Router {
+	return NewRouterWithOptions(config, Options{}, logger)
+}
+
+// NewRouterWithOptions creates a router that passes additional options to the server and the connections
+func NewRouterWithOptions(config Config, options Options, logger zerolog.Logger) Router {
+	unregisterMetrics, err := registerMetrics(options.Registerer)
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Msg("cannot register gRPC metrics")
+	}
 	return &commonGrpcRouter{
-		Config:    config,
-		connCache: newConnectionCache(),
-		logger:    logger,
+		Config:            config,
+		options:           options,
+		unregisterMetrics: unregisterMetrics,
+		connCache:         newConnectionCache(),
+		routingConns:      make(map[string]*routingConnection),
+		logger:            logger,
 	}
 }
 
 /*
-checks whether there is just 1 endpoint in all the pins in the config.
-mainly intended for first release
+GetConnection returns the connection to the endpoint if the service has a single endpoint.
+Otherwise, the returned connection selects the endpoint for each call according to the service strategy.
 */
 type commonGrpcRouter struct {
 	Config    Config
+	options   Options
 	connCache connectionCache
-	logger    zerolog.Logger
+	// routingConns holds connections of the services with several endpoints by the service name
+	routingConns map[string]*routingConnection
+	// unregisterMetrics releases the metrics registered for the router on closing
+	unregisterMetrics func()
+	closeOnce         sync.Once
+	logger            zerolog.Logger
+	mutex             sync.Mutex
 }
 
 func (gr *commonGrpcRouter) createListener() (net.Listener, error) {
+	gr.mutex.Lock()
 	address := gr.Config.getServerAddress()
+	gr.mutex.Unlock()
 
 	listener, netErr := net.Listen("tcp", address)
 	if netErr != nil {
@@ -61,14 +89,26 @@ func (gr *commonGrpcRouter) createListener() (net.Listener, error) {
 	return listener, nil
 }
 
-func (gr *commonGrpcRouter) createServerWithRegisteredService(registrar func(grpc.ServiceRegistrar)) *grpc.Server {
-	s := grpc.NewServer()
+func (gr *commonGrpcRouter) createServerWithRegisteredService(registrar func(grpc.ServiceRegistrar)) (*grpc.Server, error) {
+	gr.mutex.Lock()
+	options, err := gr.Config.ServerConfig.serverOptions()
+	gr.mutex.Unlock()
+	if err != nil {
+		gr.logger.Error().
+			Err(err).
+			Msg("invalid server configuration")
+		return nil, err
+	}
+	options = append(options, gr.options.serverInterceptors()...)
+	s := grpc.NewServer(append(options, gr.options.ServerOptions...)...)
 	registrar(s)
 	gr.logger.Info().Msg("created server")
-	return s
+	return s, nil
 }
 
 func (gr *commonGrpcRouter) Close() error {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
 	for name, service := range gr.Config.ServicesMap {
 		for endpointName, endpoint := range service.Endpoints {
 			con, exists := gr.connCache.Get(endpoint.Address)
@@ -87,41 +127,56 @@ func (gr *commonGrpcRouter) Close() error {
 			Str("service-name", name).
 			Msg("connections for service closed")
 	}
+	gr.closeOnce.Do(gr.unregisterMetrics)
 	gr.logger.Info().Msg("grpc router closed")
 	return nil
 }
 
 func (gr *commonGrpcRouter) StartServer(registrar func(grpc.ServiceRegistrar)) error {
+	s, err := gr.createServerWithRegisteredService(registrar)
+	if err != nil {
+		return err
+	}
 	listener, netErr := gr.createListener()
 	if netErr != nil {
 		return netErr
 	}
 
-	s := gr.createServerWithRegisteredService(registrar)
-	if err := s.Serve(listener); err != nil {
+	gr.reportServerState(ServerStarted)
+	// the server stopped before serving returns ErrServerStopped
+	if err := s.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		gr.reportServerState(ServerFailed)
 		gr.logger.Error().
 			Err(err).
 			Msg("register listener to accept incoming requests failure")
 		return err
 	}
-	gr.logger.Debug().Msg("server started")
+	gr.reportServerState(ServerStopped)
+	gr.logger.Debug().Msg("server stopped")
 	return nil
 }
 
 func (gr *commonGrpcRouter) StartServerAsync(registrar func(grpc.ServiceRegistrar)) (StopServer, error) {
+	s, err := gr.createServerWithRegisteredService(registrar)
+	if err != nil {
+		return nil, err
+	}
 	listener, netErr := gr.createListener()
 	if netErr != nil {
 		return nil, netErr
 	}
 
-	s := gr.createServerWithRegisteredService(registrar)
-
+	gr.reportServerState(ServerStarted)
 	go func() {
-		if err := s.Serve(listener); err != nil {
-			gr.logger.Panic().
-				Err(err).
-				Msg("error reading requests")
+		err := s.Serve(listener)
+		if err == nil || errors.Is(err, grpc.ErrServerStopped) {
+			gr.reportServerState(ServerStopped)
+			return
 		}
+		gr.reportServerState(ServerFailed)
+		gr.logger.Panic().
+			Err(err).
+			Msg("error reading requests")
 	}()
 
 	return s.GracefulStop, nil
@@ -137,13 +192,67 @@ func (ce connError) make() error {
 }
 
 func (gr *commonGrpcRouter) GetConnection(ServiceName string) (grpc.ClientConnInterface, error) {
+	return gr.GetConnectionCtx(context.Background(), ServiceName)
+}
+
+func (gr *commonGrpcRouter) GetConnectionCtx(ctx context.Context, ServiceName string) (grpc.ClientConnInterface, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, connError{specificErr: err}.make()
+	}
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
 	if gr.connCache == nil {
 		gr.connCache = newConnectionCache()
 	}
-	addr, findErr := gr.Config.findEndpointAddrViaServiceName(ServiceName)
+	service, findErr := gr.Config.findServiceViaServiceName(ServiceName)
 	if findErr != nil {
 		return nil, connError{specificErr: findErr}.make()
 	}
+	if len(service.Endpoints) > 1 {
+		return gr.getRoutingConnection(ServiceName)
+	}
+	for _, endpoint := range service.Endpoints {
+		return gr.getOrCreateConnection(ctx, endpoint.Address)
+	}
+	return nil, connError{specificErr: fmt.Errorf("service %s has no endpoints", ServiceName)}.make()
+}
+
+// getRoutingConnection must be called under the router lock
+func (gr *commonGrpcRouter) getRoutingConnection(serviceName string) (grpc.ClientConnInterface, error) {
+	if conn, exists := gr.routingConns[serviceName]; exists {
+		return conn, nil
+	}
+	if validationErr := gr.Config.ValidatePins(); validationErr != nil {
+		return nil, connError{specificErr: validationErr}.make()
+	}
+	if gr.routingConns == nil {
+		gr.routingConns = make(map[string]*routingConnection)
+	}
+	conn := &routingConnection{router: gr, serviceName: serviceName}
+	gr.routingConns[serviceName] = conn
+	gr.logger.Debug().
+		Str("service", serviceName).
+		Msg("created routing connection for the service")
+	return conn, nil
+}
+
+// selectConnection returns the connection to the endpoint selected by the strategy of the service
+func (gr *commonGrpcRouter) selectConnection(ctx context.Context, serviceName string, counter *atomic.Uint64) (grpc.ClientConnInterface, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	service, findErr := gr.Config.findServiceViaServiceName(serviceName)
+	if findErr != nil {
+		return nil, status.Error(codes.Unavailable, findErr.Error())
+	}
+	endpoint, err := service.selectEndpoint(attributesFromContext(ctx), counter)
+	if err != nil {
+		return nil, err
+	}
+	return gr.getOrCreateConnection(ctx, endpoint.Address)
+}
+
+// getOrCreateConnection must be called under the router lock
+func (gr *commonGrpcRouter) getOrCreateConnection(ctx context.Context, addr Address) (grpc.ClientConnInterface, error) {
 	if conn, exists := gr.findConnection(addr); exists {
 		gr.logger.Debug().
 			Any("address", addr).
@@ -154,20 +263,25 @@ func (gr *commonGrpcRouter) GetConnection(ServiceName string) (grpc.ClientConnIn
 		Any("address", addr).
 		Msg("couldn't found connection for the address, a new one will be establish")
 
-	return gr.newConnection(addr)
+	return gr.newConnection(ctx, addr)
 }
 
 func (gr *commonGrpcRouter) findConnection(addr Address) (grpc.ClientConnInterface, bool) {
 	return gr.connCache.Get(addr)
 }
 
-func (gr *commonGrpcRouter) newConnection(addr Address) (grpc.ClientConnInterface, error) {
+func (gr *commonGrpcRouter) newConnection(ctx context.Context, addr Address) (grpc.ClientConnInterface, error) {
 	validationErr := gr.Config.ValidatePins()
 	if validationErr != nil {
 		return nil, connError{specificErr: validationErr}.make()
 	}
 
-	conn, dialErr := grpc.Dial(addr.AsColonSeparatedString(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	options, optionsErr := gr.Config.ClientConfig.dialOptions()
+	if optionsErr != nil {
+		return nil, connError{specificErr: optionsErr}.make()
+	}
+	options = append(options, gr.options.clientInterceptors()...)
+	conn, dialErr := grpc.DialContext(ctx, addr.AsColonSeparatedString(), append(options, gr.options.DialOptions...)...)
 	if dialErr != nil {
 		return nil, connError{specificErr: dialErr}.make()
 	}
@@ -180,3 +294,36 @@ func (gr *commonGrpcRouter) newConnection(addr Address) (grpc.ClientConnInterfac
 	return conn, nil
 
 }
+
+// UpdateConfig applies the new services configuration.
+// Connections to endpoints which are not used anymore are closed. The server configuration is not changed.
+func (gr *commonGrpcRouter) UpdateConfig(config Config) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	if !reflect.DeepEqual(gr.Config.ServerConfig, config.ServerConfig) {
+		gr.logger.Warn().Msg("server configuration is changed, restart is required to apply it")
+	}
+	if !reflect.DeepEqual(gr.Config.ClientConfig, config.ClientConfig) {
+		gr.logger.Warn().Msg("client configuration is changed, restart is required to apply it")
+	}
+	used := make(map[Address]struct{})
+	for _, service := range config.ServicesMap {
+		for _, endpoint := range service.Endpoints {
+			used[endpoint.Address] = struct{}{}
+		}
+	}
+	for _, addr := range gr.connCache.Addresses() {
+		if _, exists := used[addr]; exists {
+			continue
+		}
+		if conn, exists := gr.connCache.Get(addr); exists {
+			if err := conn.Close(); err != nil {
+				gr.logger.Error().Err(err).Any("address", addr).Msg("close connection failure")
+			}
+		}
+		gr.connCache.Delete(addr)
+		gr.logger.Info().Any("address", addr).Msg("connection to removed endpoint closed")
+	}
+	gr.Config.ServicesMap = config.ServicesMap
+	gr.logger.Info().Msg("services configuration updated")
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+// ServerState is the state of the gRPC server started by the router
+type ServerState int
+
+const (
+	// ServerStarted is reported when the server starts listening to the address
+	ServerStarted ServerState = iota
+	// ServerStopped is reported when the server is stopped gracefully
+	ServerStopped
+	// ServerFailed is reported when the server stops serving because of an error
+	ServerFailed
+)
+
+func (s ServerState) String() string {
+	switch s {
+	case ServerStarted:
+		return "started"
+	case ServerStopped:
+		return "stopped"
+	case ServerFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func (gr *commonGrpcRouter) reportServerState(state ServerState) {
+	if gr.options.OnServerStateChange != nil {
+		gr.options.OnServerStateChange(state)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package grpc
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type attributesKey struct{}
+
+// WithAttributes returns the context that routes calls made with it to the endpoints having all the attributes.
+// Attributes are used by the services with the filter strategy and ignored by other strategies
+func WithAttributes(ctx context.Context, attributes ...string) context.Context {
+	// the attributes of the parent context are copied, so the contexts derived from it don't share them
+	return context.WithValue(ctx, attributesKey{}, slices.Concat(attributesFromContext(ctx), attributes))
+}
+
+func attributesFromContext(ctx context.Context) []string {
+	attributes, _ := ctx.Value(attributesKey{}).([]string)
+	return attributes
+}
+
+// routingConnection selects the endpoint of the service for each call according to the service strategy
+type routingConnection struct {
+	router      *commonGrpcRouter
+	serviceName string
+	counter     atomic.Uint64
+}
+
+func (rc *routingConnection) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	conn, err := rc.router.selectConnection(ctx, rc.serviceName, &rc.counter)
+	if err != nil {
+		return err
+	}
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (rc *routingConnection) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, err := rc.router.selectConnection(ctx, rc.serviceName, &rc.counter)
+	if err != nil {
+		return nil, err
+	}
+	return conn.NewStream(ctx, desc, method, opts...)
+}
+
+// selectEndpoint returns the endpoint for the next call. counter is incremented to rotate the endpoints
+func (s *Service) selectEndpoint(attributes []string, counter *atomic.Uint64) (Endpoint, error) {
+	names := s.Strategy.Endpoints
+	if len(names) == 0 {
+		names = make([]string, 0, len(s.Endpoints))
+		for name := range s.Endpoints {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	if s.Strategy.Name == FilterStrategy && len(attributes) > 0 {
+		names = slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+			endpointAttributes := s.Endpoints[name].Attributes
+			for _, attribute := range attributes {
+				if !slices.Contains(endpointAttributes, attribute) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if len(names) == 0 {
+		return Endpoint{}, status.Errorf(codes.Unavailable,
+			"no endpoint of service %s matches attributes %v", s.ServiceClass, attributes)
+	}
+	index := (counter.Add(1) - 1) % uint64(len(names))
+	return s.Endpoints[names[index]], nil
+}
@@ -34,6 +34,7 @@ const (
 	DefaultTh2TypeLabelName      = "th2_type"
 	MessageGroupTh2Type          = "MESSAGE_GROUP"
 	EventTh2Type                 = "EVENT"
+	TransportGroupTh2Type        = "TRANSPORT_GROUP"
 	RawMessageType               = "RAW_MESSAGE"
 	ParsedMessageType            = "MESSAGE"
 )
@@ -56,9 +57,15 @@ type HealthMetrics struct {
 }
 
 func NewHealthMetrics(obj interface{}, liveness *FlagArbiter, readiness *FlagArbiter) *HealthMetrics {
+	return NewNamedHealthMetrics(reflect.TypeOf(obj).Name(), liveness, readiness)
+}
+
+// NewNamedHealthMetrics registers the liveness and readiness monitors with the name prefix.
+// The monitors are disabled until Enable is called
+func NewNamedHealthMetrics(name string, liveness *FlagArbiter, readiness *FlagArbiter) *HealthMetrics {
 	return &HealthMetrics{
-		LivenessMonitor:  liveness.RegisterMonitor(fmt.Sprintf("%v_liveness", reflect.TypeOf(obj).Name())),
-		ReadinessMonitor: readiness.RegisterMonitor(fmt.Sprintf("%v_readiness", reflect.TypeOf(obj).Name())),
+		LivenessMonitor:  liveness.RegisterMonitor(fmt.Sprintf("%v_liveness", name)),
+		ReadinessMonitor: readiness.RegisterMonitor(fmt.Sprintf("%v_readiness", name)),
 	}
 }
 
@@ -16,6 +16,11 @@
 
 package metrics
 
+import (
+	"slices"
+	"sync"
+)
+
 type set map[interface{}]interface{}
 
 func (st set) contains(item interface{}) bool {
@@ -37,10 +42,20 @@ func (st set) isEmpty() bool {
 	return len(st) == 0
 }
 
+// FlagArbiter enables the flags when all its monitors are enabled. It is safe for concurrent use
 type FlagArbiter struct {
 	flags []Flag
 
+	mutex    sync.Mutex
 	disabled set
+	// monitors holds the names of the registered monitors in the registration order
+	monitors []string
+}
+
+// MonitorStatus is the state of the monitor registered in the arbiter
+type MonitorStatus struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
 }
 
 func NewFlagArbiter(flags ...Flag) *FlagArbiter {
@@ -51,7 +66,12 @@ func NewFlagArbiter(flags ...Flag) *FlagArbiter {
 }
 
 func (flagArb *FlagArbiter) RegisterMonitor(name string) *Monitor {
+	flagArb.mutex.Lock()
+	defer flagArb.mutex.Unlock()
 	flagArb.disabled.add(name)
+	if !slices.Contains(flagArb.monitors, name) {
+		flagArb.monitors = append(flagArb.monitors, name)
+	}
 	return &Monitor{
 		Name:        name,
 		FlagArbiter: flagArb,
@@ -59,6 +79,8 @@ func (flagArb *FlagArbiter) RegisterMonitor(name string) *Monitor {
 }
 
 func (flagArb *FlagArbiter) EnableMonitor(name string) {
+	flagArb.mutex.Lock()
+	defer flagArb.mutex.Unlock()
 	flagArb.disabled.remove(name)
 	if flagArb.disabled.isEmpty() {
 		flagArb.enableFlags()
@@ -66,10 +88,29 @@ func (flagArb *FlagArbiter) EnableMonitor(name string) {
 }
 
 func (flagArb *FlagArbiter) DisableMonitor(name string) {
+	flagArb.mutex.Lock()
+	defer flagArb.mutex.Unlock()
 	flagArb.disabled.add(name)
 	flagArb.disableFlags()
 }
 
+// Status returns true if all the monitors are enabled and the state of each monitor in the registration order
+func (flagArb *FlagArbiter) Status() (bool, []MonitorStatus) {
+	flagArb.mutex.Lock()
+	defer flagArb.mutex.Unlock()
+	statuses := make([]MonitorStatus, 0, len(flagArb.monitors))
+	for _, name := range flagArb.monitors {
+		statuses = append(statuses, MonitorStatus{Name: name, Enabled: !flagArb.disabled.contains(name)})
+	}
+	return flagArb.disabled.isEmpty(), statuses
+}
+
+func (flagArb *FlagArbiter) isMonitorEnabled(name string) bool {
+	flagArb.mutex.Lock()
+	defer flagArb.mutex.Unlock()
+	return !flagArb.disabled.contains(name)
+}
+
 func (flagArb *FlagArbiter) enableFlags() {
 	for _, flag := range flagArb.flags {
 		if !flag.IsEnabled() {
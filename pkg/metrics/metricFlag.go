@@ -27,8 +27,14 @@ type MetricFlag struct {
 	Enabled bool
 }
 
+// NewMetricFlag creates the flag with the gauge registered in the prometheus.DefaultRegisterer
 func NewMetricFlag(name string, help string) *MetricFlag {
-	gauge := promauto.NewGauge(
+	return NewMetricFlagFor(prometheus.DefaultRegisterer, name, help)
+}
+
+// NewMetricFlagFor creates the flag with the gauge registered in the registerer
+func NewMetricFlagFor(registerer prometheus.Registerer, name string, help string) *MetricFlag {
+	gauge := promauto.With(registerer).NewGauge(
 		prometheus.GaugeOpts{
 			Name: name,
 			Help: help,
@@ -17,6 +17,7 @@
 package metrics
 
 import (
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -45,3 +46,22 @@ func UpdateMessageMetrics(batch *p_buff.MessageGroupBatch, counter *prometheus.C
 		}
 	}
 }
+
+func UpdateTransportMessageMetrics(batch *transport.GroupBatch, counter *prometheus.CounterVec, th2Pin string) {
+	for _, group := range batch.Groups {
+		if len(group.Messages) == 0 {
+			continue
+		}
+		msg := group.Messages[0]
+		messageType := RawMessageType
+		if _, parsed := msg.(*transport.ParsedMessage); parsed {
+			messageType = ParsedMessageType
+		}
+		counter.WithLabelValues(
+			th2Pin,
+			msg.GetID().SessionAlias,
+			msg.GetID().Direction.ProtoName(),
+			messageType,
+		).Add(float64(len(group.Messages)))
+	}
+}
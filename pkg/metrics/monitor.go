@@ -22,7 +22,7 @@ type Monitor struct {
 }
 
 func (mon *Monitor) IsEnabled() bool {
-	return !mon.FlagArbiter.disabled.contains(mon.Name)
+	return mon.FlagArbiter.isMonitorEnabled(mon.Name)
 }
 
 func (mon *Monitor) Enable() {
@@ -26,6 +26,8 @@ type Configuration struct {
 	Host    string `json:"host"`
 	Port    int    `json:"port"`
 	Enabled bool   `json:"enabled"`
+	// DisableDefaultRegistry removes the metrics of the prometheus.DefaultRegisterer from the metrics endpoint
+	DisableDefaultRegistry bool `json:"disableDefaultRegistry"`
 }
 
 func (promConfig *Configuration) Init(path string) error {
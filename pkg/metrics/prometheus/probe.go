@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	statusUp   = "UP"
+	statusDown = "DOWN"
+)
+
+type probeResponse struct {
+	Status   string                  `json:"status"`
+	Monitors []metrics.MonitorStatus `json:"monitors"`
+}
+
+// NewProbeHandler reports the state of the arbiter monitors.
+// It responds with 200 if all the monitors are enabled and with 503 otherwise
+func NewProbeHandler(arbiter *metrics.FlagArbiter) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		enabled, monitors := arbiter.Status()
+		response := probeResponse{Status: statusUp, Monitors: monitors}
+		code := http.StatusOK
+		if !enabled {
+			response.Status = statusDown
+			code = http.StatusServiceUnavailable
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(code)
+		if request.Method == http.MethodHead {
+			return
+		}
+		_ = json.NewEncoder(writer).Encode(response)
+	})
+}
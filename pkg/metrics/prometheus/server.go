@@ -21,35 +21,74 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/log"
 )
 
 type Server struct {
 	Port int
 	Host string
 
-	stopped bool
-	server  *http.Server
+	stopped  bool
+	server   *http.Server
+	gatherer prometheus.Gatherer
+	handlers map[string]http.Handler
 }
 
+// NewServer creates the server exposing the metrics of the prometheus.DefaultGatherer
 func NewServer(host string, port int) *Server {
+	return NewServerFor(host, port, prometheus.DefaultGatherer)
+}
+
+// NewServerFor creates the server exposing the metrics of the gatherer
+func NewServerFor(host string, port int, gatherer prometheus.Gatherer) *Server {
 	return &Server{
-		Port: port,
-		Host: host,
+		Port:     port,
+		Host:     host,
+		gatherer: gatherer,
 	}
 }
 
 func (prmServ *Server) Run() {
 	if prmServ.server == nil || prmServ.stopped {
-		prmServ.server = &http.Server{Addr: fmt.Sprintf("%s:%d", prmServ.Host, prmServ.Port)}
-		http.Handle("/metrics", promhttp.Handler())
+		mux := http.NewServeMux()
+		// the metrics collected by several gathered registries are reported instead of being served partially
+		mux.Handle("/metrics", promhttp.HandlerFor(prmServ.gatherer, promhttp.HandlerOpts{
+			ErrorLog:      gatheringErrorLogger{logger: log.ForComponent("prometheus_server")},
+			ErrorHandling: promhttp.HTTPErrorOnError,
+		}))
+		for pattern, handler := range prmServ.handlers {
+			mux.Handle(pattern, handler)
+		}
+		prmServ.server = &http.Server{Addr: fmt.Sprintf("%s:%d", prmServ.Host, prmServ.Port), Handler: mux}
+		prmServ.stopped = false
 		go prmServ.server.ListenAndServe()
 	}
 }
 
+// Handle adds the handler served along with the metrics. It must be called before Run
+func (prmServ *Server) Handle(pattern string, handler http.Handler) {
+	if prmServ.handlers == nil {
+		prmServ.handlers = make(map[string]http.Handler)
+	}
+	prmServ.handlers[pattern] = handler
+}
+
 func (prmServ *Server) Stop() error {
 	if prmServ.server != nil && !prmServ.stopped {
+		prmServ.stopped = true
 		return prmServ.server.Shutdown(context.Background())
 	}
 	return nil
 }
+
+// gatheringErrorLogger reports the errors of gathering the metrics
+type gatheringErrorLogger struct {
+	logger zerolog.Logger
+}
+
+func (l gatheringErrorLogger) Println(v ...any) {
+	l.logger.Error().Msg(fmt.Sprint(v...))
+}
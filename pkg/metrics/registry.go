@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registration tracks the registerer holding the collector and the number of its users
+type registration struct {
+	registerer prometheus.Registerer
+	users      int
+}
+
+var (
+	registrationsMutex sync.Mutex
+	registrations      = make(map[prometheus.Collector]*registration)
+)
+
+// Register adds the collectors to the registerer.
+// Collectors already registered in the registerer are skipped,
+// so that the collectors shared by several modules can be registered by each of them.
+// The collectors can be held by a single registerer at a time because their values are not separated by registries,
+// so the registration fails if any of them is registered in another one. Unregister releases the collectors
+func Register(registerer prometheus.Registerer, collectors ...prometheus.Collector) error {
+	registrationsMutex.Lock()
+	defer registrationsMutex.Unlock()
+	for _, collector := range collectors {
+		if current, exists := registrations[collector]; exists && current.registerer != registerer {
+			return fmt.Errorf("collector %s is already registered in another registerer", describe(collector))
+		}
+	}
+	for index, collector := range collectors {
+		if current, exists := registrations[collector]; exists {
+			current.users++
+			continue
+		}
+		if err := registerer.Register(collector); err != nil {
+			var alreadyRegistered prometheus.AlreadyRegisteredError
+			if !errors.As(err, &alreadyRegistered) || alreadyRegistered.ExistingCollector != collector {
+				release(registerer, collectors[:index])
+				return err
+			}
+		}
+		registrations[collector] = &registration{registerer: registerer, users: 1}
+	}
+	return nil
+}
+
+// Unregister releases the collectors registered by Register.
+// The collectors are removed from the registerer when all their users release them
+func Unregister(registerer prometheus.Registerer, collectors ...prometheus.Collector) {
+	registrationsMutex.Lock()
+	defer registrationsMutex.Unlock()
+	release(registerer, collectors)
+}
+
+func release(registerer prometheus.Registerer, collectors []prometheus.Collector) {
+	for _, collector := range collectors {
+		current, exists := registrations[collector]
+		if !exists || current.registerer != registerer {
+			continue
+		}
+		current.users--
+		if current.users == 0 {
+			delete(registrations, collector)
+			registerer.Unregister(collector)
+		}
+	}
+}
+
+// describe returns the description of the first metric of the collector
+func describe(collector prometheus.Collector) string {
+	descriptions := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(descriptions)
+		close(descriptions)
+	}()
+	description := "without metrics"
+	for desc := range descriptions {
+		if description == "without metrics" {
+			description = desc.String()
+		}
+	}
+	return description
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"github.com/th2-net/th2-common-go/pkg/grpc"
 	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
 	"reflect"
 
 	"github.com/th2-net/th2-common-go/pkg/common"
@@ -36,7 +38,8 @@ type Module interface {
 }
 
 type impl struct {
-	router grpc.Router
+	router       grpc.Router
+	stopWatching func()
 }
 
 func (m *impl) GetRouter() grpc.Router {
@@ -48,27 +51,87 @@ func (m *impl) GetKey() common.ModuleKey {
 }
 
 func (m *impl) Close() error {
+	if m.stopWatching != nil {
+		m.stopWatching()
+	}
 	return m.router.Close()
 }
 
+// watchConfig applies changes of the services configuration to the router if the provider supports watching
+func (m *impl) watchConfig(provider common.ConfigProvider) error {
+	watchable, ok := provider.(common.WatchableConfigProvider)
+	if !ok {
+		return nil
+	}
+	updater, ok := m.router.(grpc.ConfigUpdater)
+	if !ok {
+		return nil
+	}
+	logger := log.ForComponent("grpc_module")
+	cancel, err := watchable.Watch(configFilename, func() {
+		config := grpc.Config{ZLogger: log.ForComponent("grpc_config")}
+		if err := provider.GetConfig(configFilename, &config); err != nil {
+			logger.Error().Err(err).Msg("cannot read updated gRPC configuration, the previous one is used")
+			return
+		}
+		updater.UpdateConfig(config)
+	})
+	if err != nil {
+		return err
+	}
+	m.stopWatching = cancel
+	return nil
+}
+
 var grpcModuleKey = common.ModuleKey(moduleKey)
 
 func NewModule(provider common.ConfigProvider) (common.Module, error) {
+	return newModule(provider, grpc.Options{})
+}
+
+// NewModuleWithOptions returns the module constructor that passes additional options to the router
+func NewModuleWithOptions(options grpc.Options) func(provider common.ConfigProvider) (common.Module, error) {
+	return func(provider common.ConfigProvider) (common.Module, error) {
+		return newModule(provider, options)
+	}
+}
 
+func newModule(provider common.ConfigProvider, options grpc.Options) (common.Module, error) {
 	grpcConfiguration := grpc.Config{ZLogger: log.ForComponent("grpc_config")}
 	err := provider.GetConfig(configFilename, &grpcConfiguration)
 	if err != nil {
 		return nil, err
 	}
-	return New(grpcConfiguration)
+	if health, ok := provider.(prometheus.Health); ok {
+		options.OnServerStateChange = withServerHealth(health, options.OnServerStateChange)
+	}
+	if metrics, ok := provider.(prometheus.Metrics); ok && options.Registerer == nil {
+		options.Registerer = metrics.GetRegistry()
+	}
+	module := newImpl(grpcConfiguration, options)
+	if err := module.watchConfig(provider); err != nil {
+		_ = module.Close()
+		return nil, err
+	}
+	return module, nil
 }
 
 func New(config grpc.Config) (Module, error) {
-	router := grpc.NewRouter(
+	return newImpl(config, grpc.Options{}), nil
+}
+
+// NewWithOptions is the same as New but passes additional options to the router
+func NewWithOptions(config grpc.Config, options grpc.Options) (Module, error) {
+	return newImpl(config, options), nil
+}
+
+func newImpl(config grpc.Config, options grpc.Options) *impl {
+	router := grpc.NewRouterWithOptions(
 		config,
+		options,
 		log.ForComponent("grpc_router"),
 	)
-	return &impl{router: router}, nil
+	return &impl{router: router}
 }
 
 type Identity struct{}
@@ -86,3 +149,24 @@ func (id *Identity) GetModule(factory common.Factory) (Module, error) {
 }
 
 var ModuleID = &Identity{}
+
+// withServerHealth registers the probe monitors for the server and chains the listener with the one from options.
+// The monitors are enabled until the server is stopped because the router can be used without the server.
+// The readiness is lost when the server is stopped and both probes are lost when the server fails
+func withServerHealth(health prometheus.Health, next func(state grpc.ServerState)) func(state grpc.ServerState) {
+	monitor := metrics.NewNamedHealthMetrics("grpc_server", health.GetLivenessArbiter(), health.GetReadinessArbiter())
+	monitor.Enable()
+	return func(state grpc.ServerState) {
+		switch state {
+		case grpc.ServerStarted:
+			monitor.Enable()
+		case grpc.ServerStopped:
+			monitor.ReadinessMonitor.Disable()
+		case grpc.ServerFailed:
+			monitor.Disable()
+		}
+		if next != nil {
+			next(state)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package prometheus
 import (
 	"errors"
 	"fmt"
+	client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"github.com/th2-net/th2-common-go/pkg/metrics/prometheus"
 	"reflect"
@@ -36,12 +38,28 @@ var prometheusModuleKey = common.ModuleKey(moduleKey)
 
 type Module interface {
 	common.Module
+	Health
+	Metrics
+}
+
+// Health gives access to the arbiters of the liveness and readiness probes.
+// The config provider passed by the factory to the modules implements it
+// to let them register their own monitors
+type Health interface {
 	GetLivenessArbiter() *metrics.FlagArbiter
 	GetReadinessArbiter() *metrics.FlagArbiter
 }
 
+// Metrics gives access to the registry exposed by the metrics server.
+// The config provider passed by the factory to the modules implements it
+// to let them register their metrics. Boxes register their own collectors there too
+type Metrics interface {
+	GetRegistry() *client.Registry
+}
+
 type module struct {
 	prometheus *prometheus.Server
+	registry   *client.Registry
 
 	livenessArbiter  *metrics.FlagArbiter
 	readinessArbiter *metrics.FlagArbiter
@@ -55,6 +73,10 @@ func (p *module) GetReadinessArbiter() *metrics.FlagArbiter {
 	return p.readinessArbiter
 }
 
+func (p *module) GetRegistry() *client.Registry {
+	return p.registry
+}
+
 func (p *module) GetKey() common.ModuleKey {
 	return prometheusModuleKey
 }
@@ -82,24 +104,40 @@ func New(config prometheus.Configuration) (Module, error) {
 		return nil, errors.New("host is not set")
 	}
 
-	serv := prometheus.NewServer(config.Host, config.Port)
+	registry := client.NewRegistry()
+	var gatherer client.Gatherer = registry
+	if config.DisableDefaultRegistry {
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	} else {
+		// the metrics registered by boxes with promauto are exposed too.
+		// The default registry has the Go and process collectors already
+		gatherer = client.Gatherers{registry, client.DefaultGatherer}
+	}
+	serv := prometheus.NewServerFor(config.Host, config.Port, gatherer)
 
 	livenessArbiter := metrics.NewFlagArbiter(
-		metrics.NewMetricFlag("th2_liveness", "Service liveness"),
+		metrics.NewMetricFlagFor(registry, "th2_liveness", "Service liveness"),
 		metrics.NewFileFlag("healthy"),
 	)
 
 	readinessArbiter := metrics.NewFlagArbiter(
-		metrics.NewMetricFlag("th2_readiness", "Service readiness"),
+		metrics.NewMetricFlagFor(registry, "th2_readiness", "Service readiness"),
 		metrics.NewFileFlag("ready"),
 	)
 
+	serv.Handle(prometheus.LivenessPath, prometheus.NewProbeHandler(livenessArbiter))
+	serv.Handle(prometheus.ReadinessPath, prometheus.NewProbeHandler(readinessArbiter))
+
 	if config.Enabled {
 		serv.Run()
 	}
 
 	return &module{
 		prometheus:       serv,
+		registry:         registry,
 		livenessArbiter:  livenessArbiter,
 		readinessArbiter: readinessArbiter,
 	}, nil
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package queue
+
+import (
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/th2-net/th2-common-go/pkg/common"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/memory"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq"
+)
+
+// InMemoryModule is a queue module that passes data between pins inside the process.
+// It is intended for tests that should not depend on a running RabbitMQ.
+type InMemoryModule interface {
+	Module
+	GetBroker() *memory.Broker
+}
+
+type inMemoryImpl struct {
+	broker *memory.Broker
+	closer io.Closer
+	baseImpl
+}
+
+func (impl *inMemoryImpl) GetBroker() *memory.Broker {
+	return impl.broker
+}
+
+func (impl *inMemoryImpl) Close() error {
+	// FIXME: error aggregation
+	impl.baseImpl.Close()
+	return impl.closer.Close()
+}
+
+func NewInMemoryModule(provider common.ConfigProvider) (common.Module, error) {
+	queueConfiguration := queue.RouterConfig{}
+	err := provider.GetConfig(routerConfigFilename, &queueConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	impl, err := newInMemoryImpl(queueConfiguration, registererOf(provider))
+	if err != nil {
+		return nil, err
+	}
+	err = impl.watchConfig(provider, func(config *queue.RouterConfig) {
+		rabbitmq.BindInMemoryPins(impl.broker, config)
+	})
+	if err != nil {
+		_ = impl.Close()
+		return nil, err
+	}
+	return impl, nil
+}
+
+func NewInMemory(queueConfiguration queue.RouterConfig) (InMemoryModule, error) {
+	return newInMemoryImpl(queueConfiguration, nil)
+}
+
+func newInMemoryImpl(queueConfiguration queue.RouterConfig, registerer prometheus.Registerer) (*inMemoryImpl, error) {
+	broker := memory.NewBroker(log.ForComponent("memory_broker"))
+	messageRouter, transportRouter, eventRouter, closer, err := rabbitmq.NewInMemoryRoutersWithRegisterer(broker, &queueConfiguration, registerer)
+	if err != nil {
+		return nil, err
+	}
+	return &inMemoryImpl{
+		broker:   broker,
+		closer:   closer,
+		baseImpl: baseImpl{messageRouter: messageRouter, transportRouter: transportRouter, eventRouter: eventRouter},
+	}, nil
+}
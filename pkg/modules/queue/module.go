@@ -18,7 +18,10 @@ package queue
 
 import (
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/th2-net/th2-common-go/pkg/common"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	promModule "github.com/th2-net/th2-common-go/pkg/modules/prometheus"
 	"github.com/th2-net/th2-common-go/pkg/queue"
 	"github.com/th2-net/th2-common-go/pkg/queue/event"
 	"github.com/th2-net/th2-common-go/pkg/queue/message"
@@ -34,11 +37,14 @@ type Module interface {
 	common.Module
 	GetEventRouter() event.Router
 	GetMessageRouter() message.Router
+	GetTransportRouter() message.TransportRouter
 }
 
 type baseImpl struct {
-	messageRouter message.Router
-	eventRouter   event.Router
+	messageRouter   message.Router
+	transportRouter message.TransportRouter
+	eventRouter     event.Router
+	stopWatching    func()
 }
 
 func (m *baseImpl) GetEventRouter() event.Router {
@@ -49,18 +55,64 @@ func (m *baseImpl) GetMessageRouter() message.Router {
 	return m.messageRouter
 }
 
+func (m *baseImpl) GetTransportRouter() message.TransportRouter {
+	return m.transportRouter
+}
+
 func (m *baseImpl) GetKey() common.ModuleKey {
 	return queueModuleKey
 }
 func (m *baseImpl) Close() error {
+	if m.stopWatching != nil {
+		m.stopWatching()
+	}
 	// FIXME: aggregate errors
 	m.messageRouter.Close()
+	m.transportRouter.Close()
 	m.eventRouter.Close()
 	return nil
 }
 
+// watchConfig applies changes of the pins configuration to the routers if the provider supports watching
+func (m *baseImpl) watchConfig(provider common.ConfigProvider, onUpdate func(config *queue.RouterConfig)) error {
+	watchable, ok := provider.(common.WatchableConfigProvider)
+	if !ok {
+		return nil
+	}
+	logger := log.ForComponent("queue_module")
+	cancel, err := watchable.Watch(routerConfigFilename, func() {
+		config := queue.RouterConfig{}
+		if err := provider.GetConfig(routerConfigFilename, &config); err != nil {
+			logger.Error().Err(err).Msg("cannot read updated pins configuration, the previous one is used")
+			return
+		}
+		if onUpdate != nil {
+			onUpdate(&config)
+		}
+		for _, router := range []any{m.messageRouter, m.transportRouter, m.eventRouter} {
+			if updater, ok := router.(queue.ConfigUpdater); ok {
+				updater.UpdateConfig(&config)
+			}
+		}
+		logger.Info().Msg("pins configuration updated")
+	})
+	if err != nil {
+		return err
+	}
+	m.stopWatching = cancel
+	return nil
+}
+
 var queueModuleKey = common.ModuleKey(moduleKey)
 
+// registererOf returns the metrics registry passed by the factory or nil if the provider does not have it
+func registererOf(provider common.ConfigProvider) prometheus.Registerer {
+	if metrics, ok := provider.(promModule.Metrics); ok {
+		return metrics.GetRegistry()
+	}
+	return nil
+}
+
 func NewRabbitMqModule(provider common.ConfigProvider) (common.Module, error) {
 	queueConfiguration := queue.RouterConfig{}
 	err := provider.GetConfig(routerConfigFilename, &queueConfiguration)
@@ -77,7 +129,19 @@ func (id *Identity) GetModule(factory common.Factory) (Module, error) {
 	if err != nil {
 		return nil, err
 	}
-	casted, success := module.(*rabbitMqImpl)
+	casted, success := module.(Module)
+	if !success {
+		return nil, fmt.Errorf("module with key %s is a %s", queueModuleKey, reflect.TypeOf(module))
+	}
+	return casted, nil
+}
+
+func (id *Identity) GetInMemoryModule(factory common.Factory) (InMemoryModule, error) {
+	module, err := factory.Get(queueModuleKey)
+	if err != nil {
+		return nil, err
+	}
+	casted, success := module.(InMemoryModule)
 	if !success {
 		return nil, fmt.Errorf("module with key %s is a %s", queueModuleKey, reflect.TypeOf(module))
 	}
@@ -18,6 +18,9 @@ package queue
 
 import (
 	"github.com/th2-net/th2-common-go/pkg/common"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
 	"github.com/th2-net/th2-common-go/pkg/queue"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
@@ -49,7 +52,19 @@ func newRabbitMq(
 	if configErr != nil {
 		return nil, configErr
 	}
-	return NewRabbitMq(boxConfig, connConfiguration, queueConfiguration)
+	options := rabbitmq.Options{Registerer: registererOf(provider)}
+	if health, ok := provider.(prometheus.Health); ok {
+		options.StateListener = newConnectionHealth(health)
+	}
+	impl, err := newRabbitMqImpl(boxConfig, connConfiguration, queueConfiguration, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := impl.watchConfig(provider, nil); err != nil {
+		_ = impl.Close()
+		return nil, err
+	}
+	return impl, nil
 }
 
 func NewRabbitMq(
@@ -57,13 +72,57 @@ func NewRabbitMq(
 	connConfiguration connection.Config,
 	queueConfiguration queue.RouterConfig,
 ) (Module, error) {
-	messageRouter, eventRouter, manager, err := rabbitmq.NewRouters(boxConfig, connConfiguration, &queueConfiguration)
+	impl, err := newRabbitMqImpl(boxConfig, connConfiguration, queueConfiguration, rabbitmq.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return impl, nil
+}
+
+func newRabbitMqImpl(
+	boxConfig common.BoxConfig,
+	connConfiguration connection.Config,
+	queueConfiguration queue.RouterConfig,
+	options rabbitmq.Options,
+) (*rabbitMqImpl, error) {
+	messageRouter, transportRouter, eventRouter, manager, err := rabbitmq.NewRoutersWithOptions(boxConfig, connConfiguration, &queueConfiguration, options)
 	if err != nil {
 		return nil, err
 	}
 
 	return &rabbitMqImpl{
 		closer:   manager,
-		baseImpl: baseImpl{messageRouter: messageRouter, eventRouter: eventRouter},
+		baseImpl: baseImpl{messageRouter: messageRouter, transportRouter: transportRouter, eventRouter: eventRouter},
 	}, nil
 }
+
+// newConnectionHealth registers the probe monitors for the publisher and consumer connections.
+// The readiness is lost while the connection is blocked or reconnecting.
+// The liveness is lost when the reconnection attempts are exhausted
+func newConnectionHealth(health prometheus.Health) connection.StateListener {
+	monitors := map[string]*metrics.HealthMetrics{
+		connection.PublisherConnection: metrics.NewNamedHealthMetrics("rabbitmq_publisher",
+			health.GetLivenessArbiter(), health.GetReadinessArbiter()),
+		connection.ConsumerConnection: metrics.NewNamedHealthMetrics("rabbitmq_consumer",
+			health.GetLivenessArbiter(), health.GetReadinessArbiter()),
+	}
+	logger := log.ForComponent("rabbitmq_health")
+	return func(connectionName string, state connection.State) {
+		monitor, exists := monitors[connectionName]
+		if !exists {
+			return
+		}
+		logger.Debug().
+			Str("connection", connectionName).
+			Stringer("state", state).
+			Msg("connection state changed")
+		switch state {
+		case connection.StateConnected:
+			monitor.Enable()
+		case connection.StateBlocked, connection.StateReconnecting:
+			monitor.ReadinessMonitor.Disable()
+		case connection.StateGaveUp:
+			monitor.Disable()
+		}
+	}
+}
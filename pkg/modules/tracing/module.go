@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/th2-net/th2-common-go/pkg/common"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	moduleKey      = "tracing"
+	configFileName = "tracing"
+)
+
+var tracingModuleKey = common.ModuleKey(moduleKey)
+
+// Module sets the global OpenTelemetry propagator, so the trace context is passed through MQ and gRPC.
+// The spans are exported only if the tracing is enabled in the configuration
+type Module interface {
+	common.Module
+	IsEnabled() bool
+}
+
+type module struct {
+	provider *sdktrace.TracerProvider
+}
+
+func (m *module) GetKey() common.ModuleKey {
+	return tracingModuleKey
+}
+
+func (m *module) IsEnabled() bool {
+	return m.provider != nil
+}
+
+func (m *module) Close() error {
+	if m.provider == nil {
+		return nil
+	}
+	return m.provider.Shutdown(context.Background())
+}
+
+func NewModule(provider common.ConfigProvider) (common.Module, error) {
+	config := tracing.Config{}
+	if err := provider.GetConfig(configFileName, &config); err != nil {
+		logger := log.ForComponent("tracing")
+		logger.Warn().
+			Err(err).
+			Msg("cannot read config. spans will not be exported")
+	}
+	serviceName := provider.GetBoxConfig().Name
+	if serviceName == "" {
+		serviceName = "th2-box"
+	}
+	return New(config, serviceName)
+}
+
+func New(config tracing.Config, serviceName string) (Module, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if !config.Enabled {
+		return &module{}, nil
+	}
+	provider, err := tracing.NewTracerProvider(context.Background(), config, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create tracer provider: %w", err)
+	}
+	otel.SetTracerProvider(provider)
+	return &module{provider: provider}, nil
+}
+
+type Identity struct{}
+
+func (id *Identity) GetModule(factory common.Factory) (Module, error) {
+	module, err := factory.Get(tracingModuleKey)
+	if err != nil {
+		return nil, err
+	}
+	casted, success := module.(Module)
+	if !success {
+		return nil, fmt.Errorf("module with key %s is a %s", tracingModuleKey, reflect.TypeOf(module))
+	}
+	return casted, nil
+}
+
+var ModuleID = &Identity{}
@@ -24,14 +24,24 @@ const (
 	SubscribeAttribute = "subscribe"
 	SendAttribute      = "publish"
 	EventAttribute     = "event"
+	// TransportGroupAttribute marks pins that use the th2 transport protocol instead of protobuf
+	TransportGroupAttribute = "transport-group"
 )
 
 func FindSendQueuesByAttr(mrc *queue.RouterConfig, attrs []string) map[string]queue.DestinationConfig {
-	return findQueueByAttr(mrc, attrs, SendAttribute)
+	return excludeByAttr(findQueueByAttr(mrc, attrs, SendAttribute), TransportGroupAttribute)
 }
 
 func FindSubscribeQueuesByAttr(mrc *queue.RouterConfig, attrs []string) map[string]queue.DestinationConfig {
-	return findQueueByAttr(mrc, attrs, SubscribeAttribute)
+	return excludeByAttr(findQueueByAttr(mrc, attrs, SubscribeAttribute), TransportGroupAttribute)
+}
+
+func FindSendTransportQueuesByAttr(mrc *queue.RouterConfig, attrs []string) map[string]queue.DestinationConfig {
+	return findQueueByAttr(mrc, attrs, SendAttribute, TransportGroupAttribute)
+}
+
+func FindSubscribeTransportQueuesByAttr(mrc *queue.RouterConfig, attrs []string) map[string]queue.DestinationConfig {
+	return findQueueByAttr(mrc, attrs, SubscribeAttribute, TransportGroupAttribute)
 }
 
 func FindSendEventQueuesByAttr(mrc *queue.RouterConfig, attrs []string) map[string]queue.DestinationConfig {
@@ -68,6 +78,15 @@ func findQueueByAttr(mrc *queue.RouterConfig, attrs []string, extraAttr ...strin
 	return result
 }
 
+func excludeByAttr(queues map[string]queue.DestinationConfig, attr string) map[string]queue.DestinationConfig {
+	for pin, config := range queues {
+		if contains(config.Attributes, attr) {
+			delete(queues, pin)
+		}
+	}
+	return queues
+}
+
 func contains(s []string, str string) bool {
 	for _, v := range s {
 		if v == str {
@@ -16,12 +16,34 @@
 
 package queue
 
+import "context"
+
 type Monitor interface {
+	// Unsubscribe cancels the subscription. It doesn't wait for the deliveries which are being handled,
+	// so it can be called from the listener. The listener is closed after it handles them
 	Unsubscribe() error
 }
 
+// CompletionWaiter is implemented by the monitors returned by the routers
+type CompletionWaiter interface {
+	// Wait blocks until the subscription is cancelled, the deliveries received before are handled
+	// and the listener is closed. It returns the error of closing the listener
+	Wait(ctx context.Context) error
+}
+
+// Delivery describes how the batch passed to the listener was delivered
 type Delivery struct {
 	Redelivered bool
+	// Context holds the trace context extracted from the delivery headers.
+	// Spans started by the listener from this context continue the trace of the publisher
+	Context context.Context
+	// Properties holds the headers and the properties the batch was published with
+	Properties
+	Exchange    string
+	RoutingKey  string
+	DeliveryTag uint64
+	// Pin is the name of the pin the batch was received by
+	Pin string
 }
 
 type Confirmation interface {
@@ -17,6 +17,7 @@
 package event
 
 import (
+	"context"
 	"io"
 
 	"github.com/th2-net/th2-common-go/pkg/queue"
@@ -27,5 +28,12 @@ type Router interface {
 	SendAll(batch *p_buff.EventBatch, attributes ...string) error
 	SubscribeAll(listener Listener, attributes ...string) (queue.Monitor, error)
 	SubscribeAllWithManualAck(listener ConformationListener, attributes ...string) (queue.Monitor, error)
+
+	// SendAllCtx is the same as SendAll but stops waiting for the connection or the publication when ctx is done
+	SendAllCtx(ctx context.Context, batch *p_buff.EventBatch, attributes ...string) error
+	// SubscribeAllCtx is the same as SubscribeAll but uses ctx for starting the subscription only
+	SubscribeAllCtx(ctx context.Context, listener Listener, attributes ...string) (queue.Monitor, error)
+	// SubscribeAllWithManualAckCtx is the same as SubscribeAllWithManualAck but uses ctx for starting the subscription only
+	SubscribeAllWithManualAckCtx(ctx context.Context, listener ConformationListener, attributes ...string) (queue.Monitor, error)
 	io.Closer
 }
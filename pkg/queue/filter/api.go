@@ -18,9 +18,14 @@ package filter
 
 import (
 	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 )
 
 type Strategy interface {
 	Verify(messages *p_buff.MessageGroupBatch, filters []mqFilter.FilterConfiguration) bool
 }
+
+type TransportStrategy interface {
+	Verify(batch *transport.GroupBatch, filters []mqFilter.FilterConfiguration) bool
+}
@@ -48,6 +48,7 @@ func (dfs defaultFilterStrategy) Verify(messages *p_buff.MessageGroupBatch, filt
 				if e := dfs.logger.Debug(); e.Enabled() {
 					e.Int("filter N", n+1).
 						Interface("Metadata", flt.Metadata.Filters).
+						Interface("Message", flt.Message.Filters).
 						Interface("MessageID", FirstIDFromMsgGroup(msgGroup)).
 						Msg("First message ID of MessageGroupBatch that didn't match filter")
 				}
@@ -59,6 +60,7 @@ func (dfs defaultFilterStrategy) Verify(messages *p_buff.MessageGroupBatch, filt
 			if e := dfs.logger.Debug(); e.Enabled() {
 				e.Int("filter N", n+1).
 					Interface("Metadata", flt.Metadata.Filters).
+					Interface("Message", flt.Message.Filters).
 					Msg("Filter fields to which matched MessageGroupBatch")
 			}
 			return res
@@ -67,28 +69,79 @@ func (dfs defaultFilterStrategy) Verify(messages *p_buff.MessageGroupBatch, filt
 	return false
 }
 func (dfs defaultFilterStrategy) CheckValues(msgGroup *p_buff.MessageGroup, filter mqFilter.FilterConfiguration) bool {
-	// return true if all messages match all simple filters (metadata in this case)
+	// return true if all messages match all simple filters (metadata and message fields)
 	// return false if at least one message doesn't match any simple filter
 	for _, anyMessage := range msgGroup.Messages {
-		for _, filterFields := range filter.Metadata.Filters {
-			if !checkValue(dfs.extractFields.GetFieldValue(anyMessage, filterFields.FieldName), filterFields) {
-				if e := dfs.logger.Debug(); e.Enabled() {
-					e.Interface("Filter", filterFields).
-						Interface("MessageID", IDFromAnyMsg(anyMessage)).
-						Msg("Message didn't match filter")
-				}
-				return false
+		if !checkSpec(filter.Metadata, func(fieldName string) string {
+			return dfs.extractFields.GetFieldValue(anyMessage, fieldName)
+		}) {
+			dfs.logMismatch(anyMessage, filter.Metadata)
+			return false
+		}
+		// raw messages have no fields, so the message conditions are checked against absent values
+		parsed := anyMessage.GetMessage()
+		if !checkSpec(filter.Message, func(fieldName string) string {
+			if parsed == nil {
+				return ""
 			}
+			return MessageFieldValue(parsed, fieldName)
+		}) {
+			dfs.logMismatch(anyMessage, filter.Message)
+			return false
 		}
 	}
-	// if there was not any mismatching in metadata, therefore ALL messages matches ALL filters and returning true
+	// if there was not any mismatching, therefore ALL messages matches ALL filters and returning true
 	return true
 }
 
-func checkValue(value string, filter mqFilter.FilterFieldsConfig) bool {
-	if value == "" {
+func (dfs defaultFilterStrategy) logMismatch(anyMessage *p_buff.AnyMessage, spec mqFilter.FilterSpec) {
+	if e := dfs.logger.Debug(); e.Enabled() {
+		e.Interface("Filter", spec).
+			Interface("MessageID", IDFromAnyMsg(anyMessage)).
+			Msg("Message didn't match filter")
+	}
+}
+
+// checkSpec returns true if the field values match all the conditions and all the groups of the spec
+func checkSpec(spec mqFilter.FilterSpec, fieldValue func(fieldName string) string) bool {
+	for _, filterFields := range spec.Filters {
+		if !checkValue(fieldValue(filterFields.FieldName), filterFields) {
+			return false
+		}
+	}
+	for _, group := range spec.Groups {
+		if !checkGroup(group, fieldValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkGroup(group mqFilter.FilterGroup, fieldValue func(fieldName string) string) bool {
+	switch group.Operator {
+	case mqFilter.And:
+		for _, spec := range group.Specs {
+			if !checkSpec(spec, fieldValue) {
+				return false
+			}
+		}
+		return true
+	case mqFilter.Or:
+		for _, spec := range group.Specs {
+			if checkSpec(spec, fieldValue) {
+				return true
+			}
+		}
+		return false
+	case mqFilter.Not:
+		return len(group.Specs) == 1 && !checkSpec(group.Specs[0], fieldValue)
+	default:
 		return false
 	}
+}
+
+// checkValue treats an absent field as the empty value
+func checkValue(value string, filter mqFilter.FilterFieldsConfig) bool {
 	switch filter.Operation {
 	case mqFilter.Equal:
 		return value == filter.ExpectedValue
@@ -100,6 +153,18 @@ func checkValue(value string, filter mqFilter.FilterFieldsConfig) bool {
 		return len(value) != 0
 	case mqFilter.Wildcard:
 		return wildcard.Match(filter.ExpectedValue, value)
+	case mqFilter.Regex:
+		return filter.MatchPattern(value)
+	case mqFilter.Less:
+		result, ok := filter.Compare(value)
+		return ok && result < 0
+	case mqFilter.More:
+		result, ok := filter.Compare(value)
+		return ok && result > 0
+	case mqFilter.In:
+		return filter.ContainsValue(value)
+	case mqFilter.NotIn:
+		return !filter.ContainsValue(value)
 	default:
 		return false
 	}
@@ -0,0 +1,300 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+)
+
+func simple(value string) *p_buff.Value {
+	return &p_buff.Value{Kind: &p_buff.Value_SimpleValue{SimpleValue: value}}
+}
+
+func parsedMessage() *p_buff.AnyMessage {
+	return &p_buff.AnyMessage{Kind: &p_buff.AnyMessage_Message{Message: &p_buff.Message{
+		Metadata: &p_buff.MessageMetadata{
+			Id: &p_buff.MessageID{
+				ConnectionId: &p_buff.ConnectionID{SessionAlias: "alias"},
+			},
+			MessageType: "NewOrderSingle",
+			Properties:  map[string]string{"venue": "XLON"},
+		},
+		Fields: map[string]*p_buff.Value{
+			"ClOrdID":      simple("order-1"),
+			"OrderQty":     simple("100"),
+			"TransactTime": simple("2026-01-02T10:00:00.5Z"),
+			"dotted.key":   simple("dotted"),
+			"Instrument": {Kind: &p_buff.Value_MessageValue{MessageValue: &p_buff.Message{
+				Fields: map[string]*p_buff.Value{"Symbol": simple("EUR/USD")},
+			}}},
+			"Legs": {Kind: &p_buff.Value_ListValue{ListValue: &p_buff.ListValue{
+				Values: []*p_buff.Value{
+					{Kind: &p_buff.Value_MessageValue{MessageValue: &p_buff.Message{
+						Fields: map[string]*p_buff.Value{"Side": simple("BUY")},
+					}}},
+					{Kind: &p_buff.Value_MessageValue{MessageValue: &p_buff.Message{
+						Fields: map[string]*p_buff.Value{"Side": simple("SELL")},
+					}}},
+				},
+			}}},
+		},
+	}}}
+}
+
+func rawMessage() *p_buff.AnyMessage {
+	return &p_buff.AnyMessage{Kind: &p_buff.AnyMessage_RawMessage{RawMessage: &p_buff.RawMessage{
+		Metadata: &p_buff.RawMessageMetadata{
+			Id: &p_buff.MessageID{
+				ConnectionId: &p_buff.ConnectionID{SessionAlias: "alias"},
+			},
+			Properties: map[string]string{"venue": "XLON"},
+		},
+	}}}
+}
+
+func parseFilter(t *testing.T, data string) mqFilter.FilterConfiguration {
+	var filter mqFilter.FilterConfiguration
+	if err := json.Unmarshal([]byte(data), &filter); err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+func TestMessageFieldValue(t *testing.T) {
+	msg := parsedMessage().GetMessage()
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "ClOrdID", want: "order-1"},
+		{path: "Instrument.Symbol", want: "EUR/USD"},
+		{path: "Legs.0.Side", want: "BUY"},
+		{path: "Legs.1.Side", want: "SELL"},
+		{path: "dotted.key", want: "dotted"},
+		{path: "Legs.2.Side", want: ""},
+		{path: "Legs.first.Side", want: ""},
+		{path: "Instrument", want: ""},
+		{path: "Unknown.Field", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := MessageFieldValue(msg, tt.path); got != tt.want {
+				t.Errorf("MessageFieldValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultStrategyAppliesMessageAndPropertiesFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *p_buff.AnyMessage
+		filter  string
+		want    bool
+	}{
+		{
+			name:    "nested fields match",
+			message: parsedMessage(),
+			filter: `{
+				"metadata": {"message_type": {"value": "NewOrderSingle", "operation": "EQUAL"}},
+				"message": {
+					"Instrument.Symbol": {"value": "EUR/USD", "operation": "EQUAL"},
+					"Legs.1.Side": {"value": "SELL", "operation": "EQUAL"}
+				}
+			}`,
+			want: true,
+		},
+		{
+			name:    "field does not match",
+			message: parsedMessage(),
+			filter:  `{"message": {"Legs.0.Side": {"value": "SELL", "operation": "EQUAL"}}}`,
+			want:    false,
+		},
+		{
+			name:    "missing field does not match",
+			message: parsedMessage(),
+			filter:  `{"message": {"Price": {"value": "1", "operation": "EQUAL"}}}`,
+			want:    false,
+		},
+		{
+			name:    "metadata property matches",
+			message: parsedMessage(),
+			filter:  `{"metadata": {"venue": {"value": "XL*", "operation": "WILDCARD"}}}`,
+			want:    true,
+		},
+		{
+			name:    "raw metadata property matches",
+			message: rawMessage(),
+			filter:  `{"metadata": {"venue": {"value": "XLON", "operation": "EQUAL"}}}`,
+			want:    true,
+		},
+		{
+			name:    "raw message does not match message filter",
+			message: rawMessage(),
+			filter: `{
+				"metadata": {"session_alias": {"value": "alias", "operation": "EQUAL"}},
+				"message": {"ClOrdID": {"value": "order-1", "operation": "EQUAL"}}
+			}`,
+			want: false,
+		},
+		{
+			name:    "raw message matches metadata filter",
+			message: rawMessage(),
+			filter:  `{"metadata": {"session_alias": {"value": "alias", "operation": "EQUAL"}}}`,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := &p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{
+				{Messages: []*p_buff.AnyMessage{tt.message}},
+			}}
+			if got := Default.Verify(batch, []mqFilter.FilterConfiguration{parseFilter(t, tt.filter)}); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultStrategyAppliesExtendedOperations(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{
+			name:   "regex matches",
+			filter: `{"message": {"ClOrdID": {"value": "^order-[0-9]+$", "operation": "REGEX"}}}`,
+			want:   true,
+		},
+		{
+			name:   "regex does not match",
+			filter: `{"message": {"ClOrdID": {"value": "^cancel-", "operation": "REGEX"}}}`,
+			want:   false,
+		},
+		{
+			name:   "number is compared numerically",
+			filter: `{"message": {"OrderQty": {"value": "20", "operation": "MORE"}}}`,
+			want:   true,
+		},
+		{
+			name:   "number is not less",
+			filter: `{"message": {"OrderQty": {"value": "100", "operation": "LESS"}}}`,
+			want:   false,
+		},
+		{
+			name:   "timestamp is compared as time",
+			filter: `{"message": {"TransactTime": {"value": "2026-01-02T12:00:00+01:00", "operation": "LESS"}}}`,
+			want:   true,
+		},
+		{
+			name:   "not a number does not match",
+			filter: `{"message": {"ClOrdID": {"value": "1", "operation": "MORE"}}}`,
+			want:   false,
+		},
+		{
+			name:   "value is in list",
+			filter: `{"metadata": {"venue": {"values": ["XPAR", "XLON"], "operation": "IN"}}}`,
+			want:   true,
+		},
+		{
+			name:   "value is not in list",
+			filter: `{"metadata": {"venue": {"values": ["XPAR", "XLON"], "operation": "NOT_IN"}}}`,
+			want:   false,
+		},
+		{
+			name: "one of OR specs matches",
+			filter: `{"message": {"OR": [
+				{"ClOrdID": {"value": "order-2", "operation": "EQUAL"}},
+				{"Instrument.Symbol": {"value": "EUR/USD", "operation": "EQUAL"}}
+			]}}`,
+			want: true,
+		},
+		{
+			name: "none of OR specs matches",
+			filter: `{"message": {"OR": [
+				{"ClOrdID": {"value": "order-2", "operation": "EQUAL"}},
+				{"Instrument.Symbol": {"value": "USD/JPY", "operation": "EQUAL"}}
+			]}}`,
+			want: false,
+		},
+		{
+			name:   "absent field is empty",
+			filter: `{"message": {"Price": {"operation": "EMPTY"}}}`,
+			want:   true,
+		},
+		{
+			name:   "present field is not empty",
+			filter: `{"message": {"ClOrdID": {"operation": "EMPTY"}}}`,
+			want:   false,
+		},
+		{
+			name:   "absent field is not equal",
+			filter: `{"message": {"Price": {"value": "1", "operation": "NOT_EQUAL"}}}`,
+			want:   true,
+		},
+		{
+			name:   "absent field is not in list",
+			filter: `{"metadata": {"desk": {"values": ["A", "B"], "operation": "NOT_IN"}}}`,
+			want:   true,
+		},
+		{
+			name:   "NOT matches absent field",
+			filter: `{"message": {"NOT": {"Price": {"value": "1", "operation": "EQUAL"}}}}`,
+			want:   true,
+		},
+		{
+			name:   "absent field is not compared",
+			filter: `{"message": {"Price": {"value": "1", "operation": "LESS"}}}`,
+			want:   false,
+		},
+		{
+			name:   "absent field does not match regex",
+			filter: `{"message": {"Price": {"value": "^[0-9]+$", "operation": "REGEX"}}}`,
+			want:   false,
+		},
+		{
+			name:   "NOT inverts nested spec",
+			filter: `{"metadata": {"NOT": {"venue": {"value": "XLON", "operation": "EQUAL"}}}}`,
+			want:   false,
+		},
+		{
+			name: "nested groups are combined with fields",
+			filter: `{"message": {
+				"ClOrdID": {"value": "order-1", "operation": "EQUAL"},
+				"AND": [
+					{"OrderQty": {"value": "50", "operation": "MORE"}},
+					{"NOT": {"Legs.0.Side": {"value": "SELL", "operation": "EQUAL"}}}
+				]
+			}}`,
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := &p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{
+				{Messages: []*p_buff.AnyMessage{parsedMessage()}},
+			}}
+			if got := Default.Verify(batch, []mqFilter.FilterConfiguration{parseFilter(t, tt.filter)}); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+)
+
+const (
+	EventTypeKey = "event_type"
+	StatusKey    = "status"
+	NameKey      = "name"
+	ParentIDKey  = "parent_id"
+)
+
+type EventStrategy interface {
+	Verify(batch *p_buff.EventBatch, filters []mqFilter.FilterConfiguration) bool
+}
+
+type eventFilterStrategy struct {
+	logger zerolog.Logger
+}
+
+var DefaultEvent EventStrategy = eventFilterStrategy{logger: log.ForComponent("event_filter_strategy")}
+
+func (efs eventFilterStrategy) Verify(batch *p_buff.EventBatch, filters []mqFilter.FilterConfiguration) bool {
+	// the same rules as for MessageGroupBatch:
+	// all events must match all the metadata conditions of at least one filter or the list must be empty
+	if len(filters) == 0 {
+		return true
+	}
+	for n, flt := range filters {
+		if efs.checkValues(batch, flt) {
+			return true
+		}
+		if e := efs.logger.Debug(); e.Enabled() {
+			e.Int("filter N", n+1).
+				Interface("Metadata", flt.Metadata.Filters).
+				Msg("EventBatch didn't match filter")
+		}
+	}
+	return false
+}
+
+func (efs eventFilterStrategy) checkValues(batch *p_buff.EventBatch, filter mqFilter.FilterConfiguration) bool {
+	for _, evt := range batch.Events {
+		if !checkSpec(filter.Metadata, func(fieldName string) string {
+			return EventFieldValue(batch, evt, fieldName)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// EventFieldValue returns the value of the event field used in filters.
+// The parent ID of the batch is used for the events without their own parent ID
+func EventFieldValue(batch *p_buff.EventBatch, evt *p_buff.Event, fieldName string) string {
+	switch fieldName {
+	case EventTypeKey:
+		return evt.GetType()
+	case StatusKey:
+		return evt.GetStatus().String()
+	case NameKey:
+		return evt.GetName()
+	case ParentIDKey:
+		if parentID := evt.GetParentId(); parentID != nil {
+			return parentID.GetId()
+		}
+		return batch.GetParentEventId().GetId()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package filter
+
+import (
+	"testing"
+
+	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+)
+
+func eventBatch() *p_buff.EventBatch {
+	return &p_buff.EventBatch{
+		ParentEventId: &p_buff.EventID{Id: "root"},
+		Events: []*p_buff.Event{
+			{Id: &p_buff.EventID{Id: "1"}, Name: "order sent", Type: "Order", Status: p_buff.EventStatus_SUCCESS},
+			{Id: &p_buff.EventID{Id: "2"}, Name: "order rejected", Type: "Order", Status: p_buff.EventStatus_FAILED,
+				ParentId: &p_buff.EventID{Id: "1"}},
+		},
+	}
+}
+
+func TestEventStrategyVerifiesAllEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{
+			name:   "type matches",
+			filter: `{"metadata": {"event_type": {"value": "Order", "operation": "EQUAL"}}}`,
+			want:   true,
+		},
+		{
+			name:   "status does not match",
+			filter: `{"metadata": {"status": {"value": "SUCCESS", "operation": "EQUAL"}}}`,
+			want:   false,
+		},
+		{
+			name:   "name matches",
+			filter: `{"metadata": {"name": {"value": "order *", "operation": "WILDCARD"}}}`,
+			want:   true,
+		},
+		{
+			name:   "parent id of batch is used",
+			filter: `{"metadata": {"parent_id": {"value": "2", "operation": "NOT_EQUAL"}}}`,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultEvent.Verify(eventBatch(), []mqFilter.FilterConfiguration{parseFilter(t, tt.filter)}); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectEventsKeepsMatchingEvents(t *testing.T) {
+	batch := eventBatch()
+	filters := []mqFilter.FilterConfiguration{
+		parseFilter(t, `{"metadata": {"parent_id": {"value": "root", "operation": "EQUAL"}}}`),
+	}
+	selected := SelectEvents(DefaultEvent, batch, filters)
+	if len(selected.Events) != 1 || selected.Events[0].GetId().GetId() != "1" {
+		t.Fatalf("unexpected events %v", selected.Events)
+	}
+	if selected.GetParentEventId().GetId() != "root" {
+		t.Errorf("parent event ID of the batch is lost")
+	}
+	if SelectEvents(DefaultEvent, batch, nil) != batch {
+		t.Errorf("batch must be returned as is without filters")
+	}
+}
+
+func TestSelectGroupsKeepsMatchingGroups(t *testing.T) {
+	matching := &p_buff.MessageGroup{Messages: []*p_buff.AnyMessage{parsedMessage()}}
+	other := parsedMessage()
+	other.GetMessage().Metadata.MessageType = "Heartbeat"
+	batch := &p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{
+		matching,
+		{Messages: []*p_buff.AnyMessage{other}},
+	}}
+	filters := []mqFilter.FilterConfiguration{
+		parseFilter(t, `{"metadata": {"message_type": {"value": "NewOrderSingle", "operation": "EQUAL"}}}`),
+	}
+	selected := SelectGroups(Default, batch, filters)
+	if len(selected.Groups) != 1 || selected.Groups[0] != matching {
+		t.Fatalf("unexpected groups %v", selected.Groups)
+	}
+	if len(batch.Groups) != 2 {
+		t.Errorf("original batch must not be modified")
+	}
+}
@@ -17,6 +17,9 @@
 package filter
 
 import (
+	"strconv"
+	"strings"
+
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 )
 
@@ -51,7 +54,7 @@ func (mfe th2MsgFieldExtraction) msgFieldValue(msg *p_buff.Message, fieldName st
 	case ProtocolKey:
 		return msg.Metadata.Protocol
 	default:
-		return ""
+		return msg.Metadata.GetProperties()[fieldName]
 	}
 }
 
@@ -64,7 +67,40 @@ func (mfe th2MsgFieldExtraction) rawMsgFieldValue(msg *p_buff.RawMessage, fieldN
 	case ProtocolKey:
 		return msg.Metadata.Protocol
 	default:
-		return ""
+		return msg.Metadata.GetProperties()[fieldName]
+	}
+}
+
+// MessageFieldValue returns the simple value of the parsed message field by the dotted path.
+// The path steps into nested messages by the field name and into lists by the element index, e.g. legs.0.price.
+// A field with the dots in its name is found by the whole name first
+func MessageFieldValue(msg *p_buff.Message, path string) string {
+	if value, ok := msg.GetFields()[path]; ok {
+		return value.GetSimpleValue()
+	}
+	value := &p_buff.Value{Kind: &p_buff.Value_MessageValue{MessageValue: msg}}
+	for _, step := range strings.Split(path, ".") {
+		value = nestedValue(value, step)
+		if value == nil {
+			return ""
+		}
+	}
+	return value.GetSimpleValue()
+}
+
+func nestedValue(value *p_buff.Value, step string) *p_buff.Value {
+	switch kind := value.GetKind().(type) {
+	case *p_buff.Value_MessageValue:
+		return kind.MessageValue.GetFields()[step]
+	case *p_buff.Value_ListValue:
+		index, err := strconv.Atoi(step)
+		values := kind.ListValue.GetValues()
+		if err != nil || index < 0 || index >= len(values) {
+			return nil
+		}
+		return values[index]
+	default:
+		return nil
 	}
 }
 
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+)
+
+// SelectGroups returns the batch holding only the groups that match the filters.
+// The batch is returned as is if there are no filters or all its groups match
+func SelectGroups(strategy Strategy, batch *p_buff.MessageGroupBatch, filters []mqFilter.FilterConfiguration) *p_buff.MessageGroupBatch {
+	if len(filters) == 0 {
+		return batch
+	}
+	selected := make([]*p_buff.MessageGroup, 0, len(batch.Groups))
+	for _, group := range batch.Groups {
+		if strategy.Verify(&p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{group}}, filters) {
+			selected = append(selected, group)
+		}
+	}
+	if len(selected) == len(batch.Groups) {
+		return batch
+	}
+	return &p_buff.MessageGroupBatch{Groups: selected}
+}
+
+// SelectTransportGroups is the same as SelectGroups but for the transport batch
+func SelectTransportGroups(strategy TransportStrategy, batch *transport.GroupBatch, filters []mqFilter.FilterConfiguration) *transport.GroupBatch {
+	if len(filters) == 0 {
+		return batch
+	}
+	selected := make([]*transport.MessageGroup, 0, len(batch.Groups))
+	for _, group := range batch.Groups {
+		single := &transport.GroupBatch{Book: batch.Book, SessionGroup: batch.SessionGroup, Groups: []*transport.MessageGroup{group}}
+		if strategy.Verify(single, filters) {
+			selected = append(selected, group)
+		}
+	}
+	if len(selected) == len(batch.Groups) {
+		return batch
+	}
+	return &transport.GroupBatch{Book: batch.Book, SessionGroup: batch.SessionGroup, Groups: selected}
+}
+
+// SelectEvents returns the batch holding only the events that match the filters.
+// The batch is returned as is if there are no filters or all its events match
+func SelectEvents(strategy EventStrategy, batch *p_buff.EventBatch, filters []mqFilter.FilterConfiguration) *p_buff.EventBatch {
+	if len(filters) == 0 {
+		return batch
+	}
+	selected := make([]*p_buff.Event, 0, len(batch.Events))
+	for _, evt := range batch.Events {
+		single := &p_buff.EventBatch{ParentEventId: batch.ParentEventId, Events: []*p_buff.Event{evt}}
+		if strategy.Verify(single, filters) {
+			selected = append(selected, evt)
+		}
+	}
+	if len(selected) == len(batch.Events) {
+		return batch
+	}
+	return &p_buff.EventBatch{ParentEventId: batch.ParentEventId, Events: selected}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	mqFilter "github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+)
+
+type transportFilterStrategy struct {
+	logger zerolog.Logger
+}
+
+var DefaultTransport TransportStrategy = transportFilterStrategy{logger: log.ForComponent("transport_filter_strategy")}
+
+func (tfs transportFilterStrategy) Verify(batch *transport.GroupBatch, filters []mqFilter.FilterConfiguration) bool {
+	// the same rules as for MessageGroupBatch:
+	// the batch must entirely match at least one filter from the list or the list must be empty
+	if len(filters) == 0 {
+		return true
+	}
+	for n, flt := range filters {
+		res := true
+		for _, group := range batch.Groups {
+			if !tfs.checkValues(group, flt) {
+				res = false
+				if e := tfs.logger.Debug(); e.Enabled() {
+					e.Int("filter N", n+1).
+						Interface("Metadata", flt.Metadata.Filters).
+						Interface("MessageID", FirstIDFromTransportGroup(group)).
+						Msg("First message ID of GroupBatch that didn't match filter")
+				}
+				break
+			}
+		}
+		if res {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValues applies the metadata filters only because the body of transport messages is not decoded
+func (tfs transportFilterStrategy) checkValues(group *transport.MessageGroup, filter mqFilter.FilterConfiguration) bool {
+	for _, msg := range group.Messages {
+		if !checkSpec(filter.Metadata, func(fieldName string) string {
+			return TransportFieldValue(msg, fieldName)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// TransportFieldValue returns the value of the metadata field used in filters.
+// The direction is returned as FIRST or SECOND to be compatible with filters for protobuf pins.
+// Other names are looked up in the metadata properties of the message.
+func TransportFieldValue(msg transport.Message, fieldName string) string {
+	switch fieldName {
+	case SessionAliasKey:
+		return msg.GetID().SessionAlias
+	case MessageTypeKey:
+		if parsed, ok := msg.(*transport.ParsedMessage); ok {
+			return parsed.Type
+		}
+		return ""
+	case DirectionKey:
+		return msg.GetID().Direction.ProtoName()
+	case ProtocolKey:
+		return msg.GetProtocol()
+	default:
+		return msg.GetMetadata()[fieldName]
+	}
+}
+
+func FirstIDFromTransportGroup(group *transport.MessageGroup) *transport.MessageID {
+	if len(group.Messages) == 0 {
+		return nil
+	}
+	return group.Messages[0].GetID()
+}
+
+func FirstIDFromTransportBatch(batch *transport.GroupBatch) *transport.MessageID {
+	if len(batch.Groups) == 0 {
+		return nil
+	}
+	return FirstIDFromTransportGroup(batch.Groups[0])
+}
@@ -19,8 +19,14 @@ package queue
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -29,6 +35,25 @@ const (
 	Empty    FilterOperation = "EMPTY"
 	NotEmpty FilterOperation = "NOT_EMPTY"
 	Wildcard FilterOperation = "WILDCARD"
+	// Regex matches the value against the regular expression in the RE2 syntax
+	Regex FilterOperation = "REGEX"
+	// Less matches the value that is less than the expected number or RFC 3339 timestamp
+	Less FilterOperation = "LESS"
+	// More matches the value that is greater than the expected number or RFC 3339 timestamp
+	More FilterOperation = "MORE"
+	// In matches the value that is equal to one of the expected values
+	In FilterOperation = "IN"
+	// NotIn matches the value that is not equal to any of the expected values
+	NotIn FilterOperation = "NOT_IN"
+)
+
+const (
+	// And matches if all the nested specs match
+	And LogicalOperator = "AND"
+	// Or matches if at least one of the nested specs matches
+	Or LogicalOperator = "OR"
+	// Not matches if the nested spec doesn't match
+	Not LogicalOperator = "NOT"
 )
 
 var (
@@ -38,6 +63,16 @@ var (
 		string(Empty):    Empty,
 		string(NotEmpty): NotEmpty,
 		string(Wildcard): Wildcard,
+		string(Regex):    Regex,
+		string(Less):     Less,
+		string(More):     More,
+		string(In):       In,
+		string(NotIn):    NotIn,
+	}
+	logicalOperatorsMap = map[string]LogicalOperator{
+		string(And): And,
+		string(Or):  Or,
+		string(Not): Not,
 	}
 )
 
@@ -46,8 +81,20 @@ type FilterConfiguration struct {
 	Message  FilterSpec `json:"message"`
 }
 
+// FilterSpec matches if all the field conditions and all the groups match
 type FilterSpec struct {
 	Filters []FilterFieldsConfig
+	// Groups combine nested specs by the logical operators
+	Groups []FilterGroup
+}
+
+// LogicalOperator combines the nested specs of the group
+type LogicalOperator string
+
+// FilterGroup is a group of nested specs combined by the operator. The NOT group has a single spec
+type FilterGroup struct {
+	Operator LogicalOperator
+	Specs    []FilterSpec
 }
 
 type FilterOperation string
@@ -69,7 +116,98 @@ func (op *FilterOperation) UnmarshalJSON(data []byte) error {
 type FilterFieldsConfig struct {
 	FieldName     string
 	ExpectedValue string
-	Operation     FilterOperation
+	// ExpectedValues holds the values of IN and NOT_IN operations
+	ExpectedValues []string
+	Operation      FilterOperation
+
+	pattern *regexp.Regexp
+	bound   *filterBound
+}
+
+// filterBound is the parsed expected value of LESS and MORE operations
+type filterBound struct {
+	number    float64
+	timestamp time.Time
+	isTime    bool
+}
+
+func parseBound(value string) (*filterBound, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return &filterBound{number: number}, nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return &filterBound{timestamp: timestamp, isTime: true}, nil
+	}
+	return nil, fmt.Errorf("'%s' is neither a number nor an RFC 3339 timestamp", value)
+}
+
+// Validate checks the expected values of the operation and prepares them for matching
+func (fc *FilterFieldsConfig) Validate() error {
+	var err error
+	switch fc.Operation {
+	case Regex:
+		fc.pattern, err = regexp.Compile(fc.ExpectedValue)
+	case Less, More:
+		fc.bound, err = parseBound(fc.ExpectedValue)
+	case In, NotIn:
+		if len(fc.ExpectedValues) == 0 {
+			err = errors.New("no expected values")
+		}
+	case "":
+		err = errors.New("no operation")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s filter of field '%s': %w", fc.Operation, fc.FieldName, err)
+	}
+	return nil
+}
+
+// MatchPattern reports whether the value matches the regular expression of the REGEX operation
+func (fc FilterFieldsConfig) MatchPattern(value string) bool {
+	if fc.pattern != nil {
+		return fc.pattern.MatchString(value)
+	}
+	matched, err := regexp.MatchString(fc.ExpectedValue, value)
+	return err == nil && matched
+}
+
+// Compare compares the value with the expected value of LESS and MORE operations.
+// The values are compared as numbers if the expected value is a number and as RFC 3339 timestamps otherwise.
+// ok is false if the value cannot be parsed in the same way as the expected one
+func (fc FilterFieldsConfig) Compare(value string) (result int, ok bool) {
+	bound := fc.bound
+	if bound == nil {
+		var err error
+		if bound, err = parseBound(fc.ExpectedValue); err != nil {
+			return 0, false
+		}
+	}
+	value = strings.TrimSpace(value)
+	if bound.isTime {
+		timestamp, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return 0, false
+		}
+		return timestamp.Compare(bound.timestamp), true
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case number < bound.number:
+		return -1, true
+	case number > bound.number:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+// ContainsValue reports whether the value is one of the expected values of IN and NOT_IN operations
+func (fc FilterFieldsConfig) ContainsValue(value string) bool {
+	return slices.Contains(fc.ExpectedValues, value)
 }
 
 func (fc *FilterSpec) UnmarshalJSON(data []byte) error {
@@ -81,30 +219,83 @@ func (fc *FilterSpec) UnmarshalJSON(data []byte) error {
 	switch token {
 	case json.Delim('['):
 		var filterFields []struct {
-			FieldName     string          `json:"fieldName"`
-			ExpectedValue string          `json:"expectedValue"`
-			Operation     FilterOperation `json:"operation"`
+			FieldName      string          `json:"fieldName"`
+			ExpectedValue  string          `json:"expectedValue"`
+			ExpectedValues []string        `json:"expectedValues"`
+			Operation      FilterOperation `json:"operation"`
 		}
 		if err := json.Unmarshal(data, &filterFields); err != nil {
 			return err
 		}
 		for _, filter := range filterFields {
-			fc.Filters = append(fc.Filters, FilterFieldsConfig{FieldName: filter.FieldName, ExpectedValue: filter.ExpectedValue, Operation: filter.Operation})
+			if err := fc.addFilter(FilterFieldsConfig{FieldName: filter.FieldName, ExpectedValue: filter.ExpectedValue,
+				ExpectedValues: filter.ExpectedValues, Operation: filter.Operation}); err != nil {
+				return err
+			}
 		}
 	case json.Delim('{'):
 		type mapFilt struct {
-			ExpectedValue string          `json:"value"`
-			Operation     FilterOperation `json:"operation"`
+			ExpectedValue  string          `json:"value"`
+			ExpectedValues []string        `json:"values"`
+			Operation      FilterOperation `json:"operation"`
 		}
-		res := map[string]mapFilt{}
+		res := map[string]json.RawMessage{}
 		if err := json.Unmarshal(data, &res); err != nil {
 			return err
 		}
-		for k, v := range res {
-			fc.Filters = append(fc.Filters, FilterFieldsConfig{FieldName: k, ExpectedValue: v.ExpectedValue, Operation: v.Operation})
+		keys := make([]string, 0, len(res))
+		for k := range res {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if operator, ok := logicalOperatorsMap[k]; ok {
+				group, err := unmarshalGroup(operator, res[k])
+				if err != nil {
+					return err
+				}
+				fc.Groups = append(fc.Groups, group)
+				continue
+			}
+			var v mapFilt
+			if err := json.Unmarshal(res[k], &v); err != nil {
+				return fmt.Errorf("cannot parse filter of field '%s': %w", k, err)
+			}
+			if err := fc.addFilter(FilterFieldsConfig{FieldName: k, ExpectedValue: v.ExpectedValue,
+				ExpectedValues: v.ExpectedValues, Operation: v.Operation}); err != nil {
+				return err
+			}
 		}
 	default:
 		return fmt.Errorf("expect either array or single object but had %v", token)
 	}
 	return nil
 }
+
+func (fc *FilterSpec) addFilter(filter FilterFieldsConfig) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	fc.Filters = append(fc.Filters, filter)
+	return nil
+}
+
+// unmarshalGroup parses the list of nested specs for AND and OR groups and the single spec for the NOT group
+func unmarshalGroup(operator LogicalOperator, data []byte) (FilterGroup, error) {
+	group := FilterGroup{Operator: operator}
+	if operator == Not {
+		var spec FilterSpec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return group, fmt.Errorf("cannot parse %s group: %w", operator, err)
+		}
+		group.Specs = []FilterSpec{spec}
+		return group, nil
+	}
+	if err := json.Unmarshal(data, &group.Specs); err != nil {
+		return group, fmt.Errorf("cannot parse %s group: %w", operator, err)
+	}
+	if len(group.Specs) == 0 {
+		return group, fmt.Errorf("%s group has no specs", operator)
+	}
+	return group, nil
+}
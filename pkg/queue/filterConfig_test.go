@@ -150,3 +150,85 @@ func TestFilterOperation_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterSpec_UnmarshalJSONGroups(t *testing.T) {
+	var spec FilterSpec
+	err := json.Unmarshal([]byte(`
+	{
+		"session_alias": {"operation": "IN", "values": ["a", "b"]},
+		"OR": [
+			{"message_type": {"operation": "EQUAL", "value": "Order"}},
+			[{"fieldName": "direction", "operation": "EQUAL", "expectedValue": "FIRST"}]
+		],
+		"NOT": {"protocol": {"operation": "EQUAL", "value": "fix"}}
+	}`), &spec)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	want := FilterSpec{
+		Filters: []FilterFieldsConfig{
+			{FieldName: "session_alias", Operation: In, ExpectedValues: []string{"a", "b"}},
+		},
+		Groups: []FilterGroup{
+			{Operator: Not, Specs: []FilterSpec{
+				{Filters: []FilterFieldsConfig{{FieldName: "protocol", Operation: Equal, ExpectedValue: "fix"}}},
+			}},
+			{Operator: Or, Specs: []FilterSpec{
+				{Filters: []FilterFieldsConfig{{FieldName: "message_type", Operation: Equal, ExpectedValue: "Order"}}},
+				{Filters: []FilterFieldsConfig{{FieldName: "direction", Operation: Equal, ExpectedValue: "FIRST"}}},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("deserialized object %#v does not match expected", spec)
+	}
+}
+
+func TestFilterSpec_UnmarshalJSONValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid regex", data: `{"field": {"operation": "REGEX", "value": "("}}`},
+		{name: "invalid bound", data: `{"field": {"operation": "LESS", "value": "yesterday"}}`},
+		{name: "no values", data: `{"field": {"operation": "IN", "value": "a"}}`},
+		{name: "no operation", data: `{"field": {"value": "a"}}`},
+		{name: "unknown operation", data: `[{"fieldName": "field", "operation": "LIKE", "expectedValue": "a"}]`},
+		{name: "empty group", data: `{"AND": []}`},
+		{name: "invalid nested spec", data: `{"OR": [{"field": {"operation": "MORE", "value": "x"}}]}`},
+		{name: "NOT with list of specs", data: `{"NOT": [{"field": {"operation": "EQUAL", "value": "a"}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spec FilterSpec
+			if err := json.Unmarshal([]byte(tt.data), &spec); err == nil {
+				t.Errorf("UnmarshalJSON() must fail for %s", tt.data)
+			}
+		})
+	}
+}
+
+func TestFilterFieldsConfig_Compare(t *testing.T) {
+	tests := []struct {
+		expected string
+		value    string
+		want     int
+		wantOk   bool
+	}{
+		{expected: "10", value: "9.5", want: -1, wantOk: true},
+		{expected: "10", value: "1e2", want: 1, wantOk: true},
+		{expected: "10", value: "10.0", want: 0, wantOk: true},
+		{expected: "2026-01-01T00:00:00Z", value: "2025-12-31T23:59:59.999Z", want: -1, wantOk: true},
+		{expected: "2026-01-01T00:00:00Z", value: "10", wantOk: false},
+		{expected: "10", value: "ten", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected+" "+tt.value, func(t *testing.T) {
+			fc := FilterFieldsConfig{Operation: More, ExpectedValue: tt.expected}
+			got, ok := fc.Compare(tt.value)
+			if ok != tt.wantOk || (ok && got != tt.want) {
+				t.Errorf("Compare() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package dispatch runs delivery handlers in a fixed number of goroutines
+package dispatch
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Dispatcher runs submitted jobs in worker goroutines.
+// If it is ordered, jobs with the same key are run one by one in the order they were submitted.
+// Otherwise, a job is run by any free worker.
+type Dispatcher struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func New(workers int, ordered bool) *Dispatcher {
+	d := &Dispatcher{}
+	if ordered {
+		d.queues = make([]chan func(), workers)
+		for i := range d.queues {
+			d.queues[i] = make(chan func())
+		}
+	} else {
+		d.queues = []chan func(){make(chan func())}
+	}
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.run(d.queues[i%len(d.queues)])
+	}
+	return d
+}
+
+// Dispatch blocks until a worker accepts the job
+func (d *Dispatcher) Dispatch(key string, job func()) {
+	queue := d.queues[0]
+	if len(d.queues) > 1 {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key))
+		queue = d.queues[hash.Sum32()%uint32(len(d.queues))]
+	}
+	queue <- job
+}
+
+// Stop waits for completion of dispatched jobs. Dispatch must not be called after Stop
+func (d *Dispatcher) Stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run(queue <-chan func()) {
+	defer d.wg.Done()
+	for job := range queue {
+		job()
+	}
+}
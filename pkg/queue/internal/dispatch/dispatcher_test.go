@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dispatch
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedDispatcherKeepsOrderPerKey(t *testing.T) {
+	d := New(4, true)
+	var mutex sync.Mutex
+	handled := make(map[string][]int)
+	for i := 0; i < 100; i++ {
+		key := []string{"a", "b", "c"}[i%3]
+		value := i
+		d.Dispatch(key, func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			handled[key] = append(handled[key], value)
+		})
+	}
+	d.Stop()
+
+	for key, values := range handled {
+		assert.IsIncreasing(t, values, "values of key %s", key)
+	}
+	assert.Len(t, handled["a"], 34)
+}
+
+func TestDispatcherRunsJobsConcurrently(t *testing.T) {
+	d := New(3, false)
+	var running atomic.Int32
+	var maxRunning atomic.Int32
+	for i := 0; i < 9; i++ {
+		d.Dispatch("key", func() {
+			current := running.Add(1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	d.Stop()
+	assert.Equal(t, int32(3), maxRunning.Load())
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package properties converts queue.Properties and queue.Delivery from and to the AMQP types
+package properties
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
+)
+
+// Publishing creates the data published with the properties from ctx, the headers and the trace context
+func Publishing(ctx context.Context, body []byte, headers amqp.Table) amqp.Publishing {
+	properties, _ := queue.PropertiesFromContext(ctx)
+	if len(properties.Headers) > 0 {
+		merged := make(amqp.Table, len(properties.Headers)+len(headers))
+		for key, value := range properties.Headers {
+			merged[key] = value
+		}
+		for key, value := range headers {
+			merged[key] = value
+		}
+		headers = merged
+	}
+	return amqp.Publishing{
+		Headers:       tracing.InjectHeaders(ctx, headers),
+		MessageId:     properties.MessageID,
+		CorrelationId: properties.CorrelationID,
+		ContentType:   properties.ContentType,
+		Timestamp:     properties.Timestamp,
+		Priority:      properties.Priority,
+		Body:          body,
+	}
+}
+
+// FromDelivery returns the properties the delivery was published with
+func FromDelivery(delivery *amqp.Delivery) queue.Properties {
+	return queue.Properties{
+		Headers:       delivery.Headers,
+		MessageID:     delivery.MessageId,
+		CorrelationID: delivery.CorrelationId,
+		ContentType:   delivery.ContentType,
+		Timestamp:     delivery.Timestamp,
+		Priority:      delivery.Priority,
+	}
+}
+
+// FromPublishing returns the properties of the published data
+func FromPublishing(publishing *amqp.Publishing) queue.Properties {
+	return queue.Properties{
+		Headers:       publishing.Headers,
+		MessageID:     publishing.MessageId,
+		CorrelationID: publishing.CorrelationId,
+		ContentType:   publishing.ContentType,
+		Timestamp:     publishing.Timestamp,
+		Priority:      publishing.Priority,
+	}
+}
+
+// Delivery creates the delivery passed to the listeners of the pin
+func Delivery(ctx context.Context, delivery *amqp.Delivery, th2Pin string) queue.Delivery {
+	return queue.Delivery{
+		Redelivered: delivery.Redelivered,
+		Context:     ctx,
+		Properties:  FromDelivery(delivery),
+		Exchange:    delivery.Exchange,
+		RoutingKey:  delivery.RoutingKey,
+		DeliveryTag: delivery.DeliveryTag,
+		Pin:         th2Pin,
+	}
+}
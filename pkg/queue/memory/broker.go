@@ -0,0 +1,567 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package memory contains an in-process broker that emulates the subset of AMQP
+// used by the th2 routers. It allows testing boxes without a running RabbitMQ.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/dispatch"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrClosed             = errors.New("broker is closed")
+	ErrAlreadyConsuming   = errors.New("queue already has a consumer")
+	ErrUnknownDeliveryTag = errors.New("unknown delivery tag")
+)
+
+// Publication is a batch published via a pin
+type Publication struct {
+	Pin        string
+	Th2Type    string
+	Exchange   string
+	RoutingKey string
+	Headers    amqp.Table
+	// Properties holds the properties set by queue.WithProperties and the headers
+	Properties queue.Properties
+	Body       []byte
+}
+
+// QueueStats holds counters for deliveries handled by the queue
+type QueueStats struct {
+	Delivered    int
+	Acknowledged int
+	Rejected     int
+	Requeued     int
+	Pending      int
+	Unacked      int
+}
+
+type bindingKey struct {
+	exchange   string
+	routingKey string
+}
+
+type message struct {
+	exchange    string
+	routingKey  string
+	publishing  amqp.Publishing
+	redelivered bool
+	// consumer received the unacknowledged message
+	consumer *consumer
+}
+
+// consumer is cancelled by setting the stopped flag under the broker lock
+type consumer struct {
+	stopped bool
+	done    chan struct{}
+}
+
+type memoryQueue struct {
+	name     string
+	messages []message
+	unacked  map[uint64]message
+	// consumer is the active consumer of the queue. It is released on cancellation
+	// while the deliveries received by it are still being handled
+	consumer *consumer
+	stats    QueueStats
+	// deadLetter is the exchange and the routing key for messages rejected without requeue or expired
+	deadLetter *bindingKey
+	// messageTTL is the time the message stays in the queue before it is dead-lettered
+	messageTTL time.Duration
+}
+
+// Broker routes published data to the bound queues and delivers it to consumers in background goroutines.
+// Queues are created on the first binding, delivery or consumption.
+type Broker struct {
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	bindings    map[bindingKey][]string
+	queues      map[string]*memoryQueue
+	published   map[string][]Publication
+	deliveryTag uint64
+	closed      bool
+
+	logger zerolog.Logger
+}
+
+func NewBroker(logger zerolog.Logger) *Broker {
+	broker := &Broker{
+		bindings:  make(map[bindingKey][]string),
+		queues:    make(map[string]*memoryQueue),
+		published: make(map[string][]Publication),
+		logger:    logger,
+	}
+	broker.cond = sync.NewCond(&broker.mutex)
+	return broker
+}
+
+// Bind routes data published to the exchange with the routing key into the queue
+func (b *Broker) Bind(exchange string, key string, queueName string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	binding := newBindingKey(exchange, key)
+	for _, existing := range b.bindings[binding] {
+		if existing == queueName {
+			return
+		}
+	}
+	b.bindings[binding] = append(b.bindings[binding], queueName)
+	b.getQueue(queueName)
+	b.logger.Debug().
+		Str("exchange", exchange).
+		Str("routing", key).
+		Str("queue", queueName).
+		Msg("queue bound")
+}
+
+// SetDeadLetterExchange emulates the x-dead-letter-exchange and x-dead-letter-routing-key arguments of the queue.
+// Messages rejected without requeue are routed to the exchange with the routing key.
+// The routing key of the message is used if the key is empty
+func (b *Broker) SetDeadLetterExchange(queueName string, exchange string, routingKey string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	deadLetter := newBindingKey(exchange, routingKey)
+	b.getQueue(queueName).deadLetter = &deadLetter
+}
+
+// DeclareDelayQueue emulates the queue with the x-message-ttl argument.
+// Messages are routed to the target queue via the default exchange after the delay
+func (b *Broker) DeclareDelayQueue(_ context.Context, name string, delay time.Duration, targetQueue string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	q := b.getQueue(name)
+	q.messageTTL = delay
+	q.deadLetter = &bindingKey{routingKey: targetQueue}
+	return nil
+}
+
+func (b *Broker) Publish(ctx context.Context, body []byte, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return b.PublishWithHeaders(ctx, body, nil, routingKey, exchange, th2Pin, th2Type)
+}
+
+// PublishWithHeaders routes the data as Publish does.
+// As in AMQP, data published to the default (empty) exchange is put to the queue with the routing key name.
+// It is not put there again if the queue is also bound to the default exchange with the routing key
+func (b *Broker) PublishWithHeaders(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ctx, span := tracing.StartPublishSpan(ctx, exchange, routingKey)
+	defer span.End()
+	publishing := properties.Publishing(ctx, body, headers)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.published[th2Pin] = append(b.published[th2Pin], Publication{
+		Pin:        th2Pin,
+		Th2Type:    th2Type,
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+		Headers:    publishing.Headers,
+		Properties: properties.FromPublishing(&publishing),
+		Body:       body,
+	})
+	b.route(message{exchange: exchange, routingKey: routingKey, publishing: publishing})
+	b.logger.Trace().Int("size", len(body)).Str("pin", th2Pin).Msg("data published")
+	return nil
+}
+
+// PublishConfirmed is the same as PublishWithHeaders because the data is routed synchronously
+func (b *Broker) PublishConfirmed(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return b.PublishWithHeaders(ctx, body, headers, routingKey, exchange, th2Pin, th2Type)
+}
+
+// Deliver puts the data directly to the queue bypassing the bindings
+func (b *Broker) Deliver(queueName string, body []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.enqueue(queueName, message{publishing: amqp.Publishing{Body: body}})
+	return nil
+}
+
+func (b *Broker) Consume(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(delivery amqp.Delivery) error) (func(), <-chan struct{}, error) {
+	return b.consume(ctx, queueName, th2Pin, true, options, func(delivery amqp.Delivery, _ *prometheus.Timer) error {
+		return handler(delivery)
+	})
+}
+
+func (b *Broker) ConsumeWithManualAck(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
+	return b.consume(ctx, queueName, th2Pin, false, options, handler)
+}
+
+// Published returns all the data published via the pin
+func (b *Broker) Published(th2Pin string) []Publication {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]Publication(nil), b.published[th2Pin]...)
+}
+
+// PublishedMessages returns all the message batches published via the pin
+func (b *Broker) PublishedMessages(th2Pin string) ([]*p_buff.MessageGroupBatch, error) {
+	return unmarshalAll(b.Published(th2Pin), func() *p_buff.MessageGroupBatch { return &p_buff.MessageGroupBatch{} })
+}
+
+// PublishedEvents returns all the event batches published via the pin
+func (b *Broker) PublishedEvents(th2Pin string) ([]*p_buff.EventBatch, error) {
+	return unmarshalAll(b.Published(th2Pin), func() *p_buff.EventBatch { return &p_buff.EventBatch{} })
+}
+
+// Stats returns the delivery counters for the queue
+func (b *Broker) Stats(queueName string) QueueStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	q, exists := b.queues[queueName]
+	if !exists {
+		return QueueStats{}
+	}
+	stats := q.stats
+	stats.Pending = len(q.messages)
+	stats.Unacked = len(q.unacked)
+	return stats
+}
+
+// Reset removes all recorded publications
+func (b *Broker) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.published = make(map[string][]Publication)
+}
+
+func (b *Broker) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.cond.Broadcast()
+	b.logger.Info().Msg("broker closed")
+	return nil
+}
+
+func (b *Broker) consume(ctx context.Context, queueName string, th2Pin string, autoAck bool, options connection.ConsumeOptions,
+	handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+	q := b.getQueue(queueName)
+	c := &consumer{done: make(chan struct{})}
+	if q.consumer != nil {
+		if !options.WaitForExclusive {
+			return nil, nil, fmt.Errorf("%w: %s", ErrAlreadyConsuming, queueName)
+		}
+		go b.runStandbyConsumer(q, c, th2Pin, autoAck, options, handler)
+	} else {
+		q.consumer = c
+		go b.runConsumer(q, c, th2Pin, autoAck, options, handler)
+	}
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		c.stopped = true
+		if q.consumer == c {
+			q.consumer = nil
+		}
+		b.cond.Broadcast()
+	}, c.done, nil
+}
+
+// runStandbyConsumer waits until the queue has no consumer and starts consuming as WaitForExclusive does in RabbitMQ
+func (b *Broker) runStandbyConsumer(q *memoryQueue, c *consumer, th2Pin string, autoAck bool, options connection.ConsumeOptions,
+	handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) {
+	b.mutex.Lock()
+	for q.consumer != nil && !b.closed && !c.stopped {
+		b.cond.Wait()
+	}
+	if b.closed || c.stopped {
+		b.mutex.Unlock()
+		close(c.done)
+		return
+	}
+	q.consumer = c
+	b.mutex.Unlock()
+	b.logger.Debug().Str("queue", q.name).Str("pin", th2Pin).Msg("consumer waiting for exclusive access became active")
+	b.runConsumer(q, c, th2Pin, autoAck, options, handler)
+}
+
+// runConsumer delivers messages until the broker is closed or the consumer is cancelled.
+// On cancellation the unacknowledged messages are returned to the queue and the queue is released for other consumers
+func (b *Broker) runConsumer(q *memoryQueue, c *consumer, th2Pin string, autoAck bool, options connection.ConsumeOptions,
+	handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) {
+	defer close(c.done)
+	logger := b.logger.With().Str("queue", q.name).Str("pin", th2Pin).Logger()
+	logger.Debug().Msg("start handling messages")
+	acknowledger := &queueAcknowledger{broker: b, queue: q, consumer: c}
+	process := func(delivery amqp.Delivery) {
+		// the broker does not record metrics, the routers replace the timer with the one observing the process duration
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(float64) {}))
+		if err := handler(delivery, timer); err != nil {
+			logger.Error().
+				Err(err).
+				Int("bodySize", len(delivery.Body)).
+				Msg("Cannot handle delivery")
+		}
+	}
+	handleDelivery := process
+	var dispatcher *dispatch.Dispatcher
+	if options.Workers > 1 {
+		dispatcher = dispatch.New(options.Workers, options.OrderingKey != nil)
+		handleDelivery = func(delivery amqp.Delivery) {
+			var key string
+			if options.OrderingKey != nil {
+				key = options.OrderingKey(delivery)
+			}
+			dispatcher.Dispatch(key, func() { process(delivery) })
+		}
+	}
+	for {
+		b.mutex.Lock()
+		for len(q.messages) == 0 && !b.closed && !c.stopped {
+			b.cond.Wait()
+		}
+		if b.closed || c.stopped {
+			b.mutex.Unlock()
+			break
+		}
+		msg := q.messages[0]
+		q.messages = q.messages[1:]
+		b.deliveryTag++
+		tag := b.deliveryTag
+		if !autoAck {
+			unacked := msg
+			unacked.consumer = c
+			q.unacked[tag] = unacked
+		}
+		q.stats.Delivered++
+		b.mutex.Unlock()
+
+		delivery := amqp.Delivery{
+			Acknowledger:  acknowledger,
+			ConsumerTag:   th2Pin,
+			DeliveryTag:   tag,
+			Redelivered:   msg.redelivered,
+			Exchange:      msg.exchange,
+			RoutingKey:    msg.routingKey,
+			Headers:       msg.publishing.Headers,
+			MessageId:     msg.publishing.MessageId,
+			CorrelationId: msg.publishing.CorrelationId,
+			ContentType:   msg.publishing.ContentType,
+			Timestamp:     msg.publishing.Timestamp,
+			Priority:      msg.publishing.Priority,
+			Body:          msg.publishing.Body,
+		}
+		handleDelivery(delivery)
+	}
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+	b.mutex.Lock()
+	if c.stopped && !b.closed {
+		b.requeueUnacked(q, c)
+		b.cond.Broadcast()
+	}
+	b.mutex.Unlock()
+	logger.Debug().Msg("stop handling messages")
+}
+
+// requeueUnacked returns the messages received by the consumer to the queue. It must be called under the broker lock
+func (b *Broker) requeueUnacked(q *memoryQueue, c *consumer) {
+	var tags []uint64
+	for tag, msg := range q.unacked {
+		if msg.consumer == c {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	slices.Sort(tags)
+	requeued := make([]message, 0, len(tags))
+	for _, tag := range tags {
+		msg := q.unacked[tag]
+		delete(q.unacked, tag)
+		msg.redelivered = true
+		msg.consumer = nil
+		requeued = append(requeued, msg)
+	}
+	q.stats.Requeued += len(requeued)
+	q.messages = append(requeued, q.messages...)
+}
+
+// getQueue must be called under the broker lock
+func (b *Broker) getQueue(queueName string) *memoryQueue {
+	q, exists := b.queues[queueName]
+	if !exists {
+		q = &memoryQueue{name: queueName, unacked: make(map[uint64]message)}
+		b.queues[queueName] = q
+	}
+	return q
+}
+
+// route puts the message to each queue bound to its exchange and routing key once.
+// It must be called under the broker lock
+func (b *Broker) route(msg message) {
+	queues := b.bindings[newBindingKey(msg.exchange, msg.routingKey)]
+	if msg.exchange == "" && !slices.Contains(queues, msg.routingKey) {
+		b.enqueue(msg.routingKey, msg)
+	}
+	for _, queueName := range queues {
+		b.enqueue(queueName, msg)
+	}
+}
+
+// enqueue must be called under the broker lock
+func (b *Broker) enqueue(queueName string, msg message) {
+	q := b.getQueue(queueName)
+	q.messages = append(q.messages, msg)
+	if q.messageTTL > 0 {
+		time.AfterFunc(q.messageTTL, func() { b.expire(q) })
+	}
+	b.cond.Broadcast()
+}
+
+// expire dead-letters the oldest message of the queue. The messages expire in the order they were enqueued
+// because the queue has the same time-to-live for all of them
+func (b *Broker) expire(q *memoryQueue) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed || len(q.messages) == 0 {
+		return
+	}
+	msg := q.messages[0]
+	q.messages = q.messages[1:]
+	b.deadLetter(q, msg)
+}
+
+// deadLetter routes the message to the dead-letter exchange of the queue if it is set.
+// It must be called under the broker lock
+func (b *Broker) deadLetter(q *memoryQueue, msg message) {
+	if q.deadLetter == nil {
+		return
+	}
+	routingKey := q.deadLetter.routingKey
+	if routingKey == "" {
+		routingKey = msg.routingKey
+	}
+	b.route(message{exchange: q.deadLetter.exchange, routingKey: routingKey, publishing: msg.publishing})
+}
+
+// settle applies the acknowledgement to the messages received by the consumer.
+// As on the AMQP channel, multiple applies it to all the messages of the consumer up to the tag.
+// Nothing is applied if the tag is not an unacknowledged delivery of the consumer
+func (b *Broker) settle(q *memoryQueue, c *consumer, tag uint64, multiple bool, ack bool, requeue bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if msg, exists := q.unacked[tag]; !exists || msg.consumer != c {
+		return fmt.Errorf("%w: %d", ErrUnknownDeliveryTag, tag)
+	}
+	tags := []uint64{tag}
+	if multiple {
+		tags = tags[:0]
+		for unackedTag, msg := range q.unacked {
+			if unackedTag <= tag && msg.consumer == c {
+				tags = append(tags, unackedTag)
+			}
+		}
+		slices.Sort(tags)
+	}
+	var requeued []message
+	for _, t := range tags {
+		msg := q.unacked[t]
+		delete(q.unacked, t)
+		switch {
+		case ack:
+			q.stats.Acknowledged++
+		case requeue:
+			q.stats.Requeued++
+			msg.redelivered = true
+			msg.consumer = nil
+			requeued = append(requeued, msg)
+		default:
+			q.stats.Rejected++
+			b.deadLetter(q, msg)
+		}
+	}
+	if len(requeued) > 0 {
+		q.messages = append(requeued, q.messages...)
+		b.cond.Broadcast()
+	}
+	return nil
+}
+
+type queueAcknowledger struct {
+	broker   *Broker
+	queue    *memoryQueue
+	consumer *consumer
+}
+
+func (a *queueAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.broker.settle(a.queue, a.consumer, tag, multiple, true, false)
+}
+
+func (a *queueAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return a.broker.settle(a.queue, a.consumer, tag, multiple, false, requeue)
+}
+
+func (a *queueAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.broker.settle(a.queue, a.consumer, tag, false, false, requeue)
+}
+
+func newBindingKey(exchange string, key string) bindingKey {
+	return bindingKey{exchange: exchange, routingKey: key}
+}
+
+func unmarshalAll[T proto.Message](publications []Publication, newBatch func() T) ([]T, error) {
+	result := make([]T, 0, len(publications))
+	for _, publication := range publications {
+		batch := newBatch()
+		if err := proto.Unmarshal(publication.Body, batch); err != nil {
+			return nil, fmt.Errorf("cannot deserialize data published via pin %s: %w", publication.Pin, err)
+		}
+		result = append(result, batch)
+	}
+	return result, nil
+}
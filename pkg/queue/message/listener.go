@@ -35,3 +35,8 @@ type RawListener interface {
 	queue.CloseListener
 	Handle(delivery queue.Delivery, data []byte) error
 }
+
+type RawConformationListener interface {
+	queue.CloseListener
+	Handle(delivery queue.Delivery, data []byte, confirm queue.Confirmation) error
+}
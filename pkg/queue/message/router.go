@@ -17,6 +17,7 @@
 package message
 
 import (
+	"context"
 	"io"
 
 	"github.com/th2-net/th2-common-go/pkg/queue"
@@ -29,5 +30,19 @@ type Router interface {
 	SubscribeAll(listener Listener, attributes ...string) (queue.Monitor, error)
 	SubscribeRawAll(listener RawListener, attributes ...string) (queue.Monitor, error)
 	SubscribeAllWithManualAck(listener ConformationListener, attributes ...string) (queue.Monitor, error)
+	SubscribeRawAllWithManualAck(listener RawConformationListener, attributes ...string) (queue.Monitor, error)
+
+	// SendAllCtx is the same as SendAll but stops waiting for the connection or the publication when ctx is done
+	SendAllCtx(ctx context.Context, batch *p_buff.MessageGroupBatch, attributes ...string) error
+	// SendRawAllCtx is the same as SendRawAll but stops waiting for the connection or the publication when ctx is done
+	SendRawAllCtx(ctx context.Context, payload []byte, attributes ...string) error
+	// SubscribeAllCtx is the same as SubscribeAll but uses ctx for starting the subscription only
+	SubscribeAllCtx(ctx context.Context, listener Listener, attributes ...string) (queue.Monitor, error)
+	// SubscribeRawAllCtx is the same as SubscribeRawAll but uses ctx for starting the subscription only
+	SubscribeRawAllCtx(ctx context.Context, listener RawListener, attributes ...string) (queue.Monitor, error)
+	// SubscribeAllWithManualAckCtx is the same as SubscribeAllWithManualAck but uses ctx for starting the subscription only
+	SubscribeAllWithManualAckCtx(ctx context.Context, listener ConformationListener, attributes ...string) (queue.Monitor, error)
+	// SubscribeRawAllWithManualAckCtx is the same as SubscribeRawAllWithManualAck but uses ctx for starting the subscription only
+	SubscribeRawAllWithManualAckCtx(ctx context.Context, listener RawConformationListener, attributes ...string) (queue.Monitor, error)
 	io.Closer
 }
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package message
+
+import (
+	"context"
+	"io"
+
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+)
+
+type TransportListener interface {
+	queue.CloseListener
+	Handle(delivery queue.Delivery, batch *transport.GroupBatch) error
+}
+
+type TransportConformationListener interface {
+	queue.CloseListener
+	Handle(delivery queue.Delivery, batch *transport.GroupBatch, confirm queue.Confirmation) error
+}
+
+// TransportRouter sends and receives batches in the th2 transport protocol.
+// It uses only pins with the transport-group attribute; such pins are ignored by Router.
+type TransportRouter interface {
+	SendAll(batch *transport.GroupBatch, attributes ...string) error
+	SubscribeAll(listener TransportListener, attributes ...string) (queue.Monitor, error)
+	SubscribeAllWithManualAck(listener TransportConformationListener, attributes ...string) (queue.Monitor, error)
+
+	// SendAllCtx is the same as SendAll but stops waiting for the connection or the publication when ctx is done
+	SendAllCtx(ctx context.Context, batch *transport.GroupBatch, attributes ...string) error
+	// SubscribeAllCtx is the same as SubscribeAll but uses ctx for starting the subscription only
+	SubscribeAllCtx(ctx context.Context, listener TransportListener, attributes ...string) (queue.Monitor, error)
+	// SubscribeAllWithManualAckCtx is the same as SubscribeAllWithManualAck but uses ctx for starting the subscription only
+	SubscribeAllWithManualAckCtx(ctx context.Context, listener TransportConformationListener, attributes ...string) (queue.Monitor, error)
+	io.Closer
+}
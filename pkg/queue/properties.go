@@ -0,0 +1,46 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Properties are attached to the published data and exposed to the listeners via Delivery
+type Properties struct {
+	Headers       map[string]any
+	MessageID     string
+	CorrelationID string
+	ContentType   string
+	Timestamp     time.Time
+	Priority      uint8
+}
+
+type propertiesKey struct{}
+
+// WithProperties returns the context that makes the senders attach the properties to the data published with it.
+// Headers set by the library (e.g. the retry count or the trace context) take precedence over the passed ones
+func WithProperties(ctx context.Context, properties Properties) context.Context {
+	return context.WithValue(ctx, propertiesKey{}, properties)
+}
+
+// PropertiesFromContext returns the properties set by WithProperties
+func PropertiesFromContext(ctx context.Context) (Properties, bool) {
+	properties, ok := ctx.Value(propertiesKey{}).(Properties)
+	return properties, ok
+}
@@ -30,4 +30,23 @@ type Config struct {
 	MaxConnectionRecoveryTimeout int    `json:"maxConnectionRecoveryTimeout,omitempty"`
 	PrefetchCount                int    `json:"prefetchCount,omitempty"`
 	MessageRecursionLimit        int    `json:"messageRecursionLimit,omitempty"`
+	PublisherConfirmation        bool   `json:"publisherConfirmation,omitempty"`
+	PublisherConfirmationTimeout int    `json:"publisherConfirmationTimeout,omitempty"`
+	// AuthMechanism is the SASL mechanism: PLAIN (default) or EXTERNAL
+	AuthMechanism string     `json:"authMechanism,omitempty"`
+	TLS           *TLSConfig `json:"tls,omitempty"`
+}
+
+const (
+	PlainAuthMechanism    = "PLAIN"
+	ExternalAuthMechanism = "EXTERNAL"
+)
+
+// TLSConfig enables the amqps connection. Paths point to PEM-encoded files.
+type TLSConfig struct {
+	CACertificate      string `json:"caCertificate,omitempty"`
+	ClientCertificate  string `json:"clientCertificate,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
 }
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package connection
+
+import amqp "github.com/rabbitmq/amqp091-go"
+
+// ConsumeOptions holds settings of a single subscription
+type ConsumeOptions struct {
+	// Workers is the number of goroutines handling deliveries. Deliveries are handled one by one if it is less than 2
+	Workers int
+	// OrderingKey returns the key of the delivery. Deliveries with the same key are handled in the order they were received.
+	// Deliveries are handled in any order if it is nil
+	OrderingKey func(delivery amqp.Delivery) string
+	// Exclusive makes the broker refuse other consumers of the queue
+	Exclusive bool
+	// WaitForExclusive makes the subscription consume exclusively and retry in background
+	// while the broker refuses it because the queue has other consumers
+	WaitForExclusive bool
+	// OnFailure is called when the subscription cannot be started in background or recovered after a channel failure,
+	// e.g. the broker refuses the exclusive consumer. The handler is not called after that
+	OnFailure func(err error)
+}
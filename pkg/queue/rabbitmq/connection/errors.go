@@ -0,0 +1,45 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotConfirmed is returned when the broker responds with basic.nack to the publication
+	ErrNotConfirmed = errors.New("publication is not confirmed by broker")
+	// ErrConfirmationTimeout is returned when the broker does not confirm the publication in time
+	ErrConfirmationTimeout = errors.New("publication confirmation timeout")
+	// ErrRecoveryFailed is returned when the connection is not restored in maxRecoveryAttempts attempts
+	ErrRecoveryFailed = errors.New("connection recovery attempts are exhausted")
+)
+
+// UnroutableError is returned when the broker returns a mandatory publication
+// because no queue is bound to its routing key
+type UnroutableError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *UnroutableError) Error() string {
+	return fmt.Sprintf("publication to exchange '%s' with routing key '%s' is returned by broker: %d %s",
+		e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+// State is the state of a connection to RabbitMQ
+type State int
+
+const (
+	// StateConnected is reported when the connection is established, restored or unblocked
+	StateConnected State = iota
+	// StateBlocked is reported when the broker blocks publications because of a resource alarm
+	StateBlocked
+	// StateReconnecting is reported when the connection is lost and the reconnection starts
+	StateReconnecting
+	// StateGaveUp is reported when maxRecoveryAttempts reconnection attempts have failed.
+	// The connection is not restored anymore and its operations fail with ErrRecoveryFailed
+	StateGaveUp
+)
+
+const (
+	PublisherConnection = "publisher"
+	ConsumerConnection  = "consumer"
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateBlocked:
+		return "blocked"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateGaveUp:
+		return "gave up"
+	default:
+		return "unknown"
+	}
+}
+
+// StateListener is notified about the state changes of the connection with the specified name.
+// The name is either PublisherConnection or ConsumerConnection
+type StateListener func(connectionName string, state State)
@@ -17,17 +17,23 @@
 package rabbitmq
 
 import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/common"
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"github.com/th2-net/th2-common-go/pkg/queue"
 	"github.com/th2-net/th2-common-go/pkg/queue/event"
+	"github.com/th2-net/th2-common-go/pkg/queue/memory"
 	"github.com/th2-net/th2-common-go/pkg/queue/message"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	internalQueue "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
 	internal "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	eventImpl "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/event"
 	messageImpl "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/message"
 	"io"
+	"sync"
 )
 
 func NewRouters(
@@ -35,17 +41,149 @@ func NewRouters(
 	connection connection.Config,
 	config *queue.RouterConfig,
 ) (messageRouter message.Router, eventRouter event.Router, closer io.Closer, err error) {
-	manager, err := internal.NewConnectionManager(connection, boxConfig.Name, log.ForComponent("connection_manager"))
+	messageRouter, _, eventRouter, closer, err = NewRoutersWithTransport(boxConfig, connection, config)
+	return
+}
+
+// NewRoutersWithTransport is the same as NewRouters but also creates a router for pins with the transport protocol
+func NewRoutersWithTransport(
+	boxConfig common.BoxConfig,
+	connection connection.Config,
+	config *queue.RouterConfig,
+) (messageRouter message.Router, transportRouter message.TransportRouter, eventRouter event.Router, closer io.Closer, err error) {
+	return NewRoutersWithOptions(boxConfig, connection, config, Options{})
+}
+
+// Options holds the optional settings of the routers
+type Options struct {
+	// StateListener is notified about the state changes of RabbitMQ connections
+	StateListener connection.StateListener
+	// Registerer registers the metrics of the routers. The prometheus.DefaultRegisterer is used if it is not set
+	Registerer prometheus.Registerer
+}
+
+// NewRoutersWithOptions is the same as NewRoutersWithTransport but applies the options
+func NewRoutersWithOptions(
+	boxConfig common.BoxConfig,
+	connectionConfig connection.Config,
+	config *queue.RouterConfig,
+	options Options,
+) (messageRouter message.Router, transportRouter message.TransportRouter, eventRouter event.Router, closer io.Closer, err error) {
+	unregister, err := registerMetrics(options.Registerer)
 	if err != nil {
 		return
 	}
+	manager, err := internal.NewConnectionManagerWithListener(connectionConfig, boxConfig.Name, options.StateListener, log.ForComponent("connection_manager"))
+	if err != nil {
+		unregister()
+		return
+	}
 	go manager.ListenForBlockingNotifications()
 	messageRouter = newMessageRouter(&manager, config, log.ForComponent("message_router"))
+	transportRouter = newTransportRouter(&manager, config, log.ForComponent("transport_router"))
+	eventRouter = newEventRouter(&manager, config, log.ForComponent("event_router"))
+	closer = &metricsReleasingCloser{Closer: &manager, unregister: unregister}
+	return
+}
+
+// NewInMemoryRouters creates routers that use the in-process broker instead of RabbitMQ connections.
+// Each subscribe pin's queue is bound to the pin's exchange and routing key (the "name" field)
+// so that a publish pin with the same exchange and routing key delivers data into it.
+func NewInMemoryRouters(
+	broker *memory.Broker,
+	config *queue.RouterConfig,
+) (messageRouter message.Router, transportRouter message.TransportRouter, eventRouter event.Router, closer io.Closer) {
+	unregister, err := registerMetrics(nil)
+	if err != nil {
+		logger := log.ForComponent("in_memory_routers")
+		logger.Warn().Err(err).Msg("routers metrics are not exposed")
+	}
+	messageRouter, transportRouter, eventRouter, closer = newInMemoryRouters(broker, config)
+	closer = &metricsReleasingCloser{Closer: closer, unregister: unregister}
+	return
+}
+
+// NewInMemoryRoutersWithRegisterer is the same as NewInMemoryRouters but registers the metrics of the routers in the registerer.
+// The prometheus.DefaultRegisterer is used if the registerer is nil
+func NewInMemoryRoutersWithRegisterer(
+	broker *memory.Broker,
+	config *queue.RouterConfig,
+	registerer prometheus.Registerer,
+) (messageRouter message.Router, transportRouter message.TransportRouter, eventRouter event.Router, closer io.Closer, err error) {
+	unregister, err := registerMetrics(registerer)
+	if err != nil {
+		return
+	}
+	messageRouter, transportRouter, eventRouter, closer = newInMemoryRouters(broker, config)
+	closer = &metricsReleasingCloser{Closer: closer, unregister: unregister}
+	return
+}
+
+func newInMemoryRouters(
+	broker *memory.Broker,
+	config *queue.RouterConfig,
+) (messageRouter message.Router, transportRouter message.TransportRouter, eventRouter event.Router, closer io.Closer) {
+	BindInMemoryPins(broker, config)
+	manager := internal.NewInstrumentedManager(broker, broker, log.ForComponent("connection_manager"))
+	messageRouter = newMessageRouter(&manager, config, log.ForComponent("message_router"))
+	transportRouter = newTransportRouter(&manager, config, log.ForComponent("transport_router"))
 	eventRouter = newEventRouter(&manager, config, log.ForComponent("event_router"))
 	closer = &manager
 	return
 }
 
+// registerMetrics adds the metrics of all the router packages to the registerer.
+// The returned function releases them when the routers are closed
+func registerMetrics(registerer prometheus.Registerer) (func(), error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	var unregisters []func(prometheus.Registerer)
+	unregister := func() {
+		for _, unregister := range unregisters {
+			unregister(registerer)
+		}
+	}
+	for _, pkg := range []struct {
+		register   func(prometheus.Registerer) error
+		unregister func(prometheus.Registerer)
+	}{
+		{internal.RegisterMetrics, internal.UnregisterMetrics},
+		{internalQueue.RegisterMetrics, internalQueue.UnregisterMetrics},
+		{messageImpl.RegisterMetrics, messageImpl.UnregisterMetrics},
+		{eventImpl.RegisterMetrics, eventImpl.UnregisterMetrics},
+	} {
+		if err := pkg.register(registerer); err != nil {
+			unregister()
+			return func() {}, fmt.Errorf("cannot register metrics: %w", err)
+		}
+		unregisters = append(unregisters, pkg.unregister)
+	}
+	return unregister, nil
+}
+
+// metricsReleasingCloser releases the metrics of the routers once they are closed
+type metricsReleasingCloser struct {
+	io.Closer
+	unregister func()
+	once       sync.Once
+}
+
+func (c *metricsReleasingCloser) Close() error {
+	err := c.Closer.Close()
+	c.once.Do(c.unregister)
+	return err
+}
+
+// BindInMemoryPins binds each subscribe pin's queue to the pin's exchange and routing key
+func BindInMemoryPins(broker *memory.Broker, config *queue.RouterConfig) {
+	for _, pinConfig := range config.Queues {
+		if pinConfig.QueueName != "" && pinConfig.RoutingKey != "" {
+			broker.Bind(pinConfig.Exchange, pinConfig.RoutingKey, pinConfig.QueueName)
+		}
+	}
+}
+
 func newMessageRouter(
 	manager *internal.Manager,
 	config *queue.RouterConfig,
@@ -54,6 +192,14 @@ func newMessageRouter(
 	return messageImpl.NewRouter(manager, config, logger)
 }
 
+func newTransportRouter(
+	manager *internal.Manager,
+	config *queue.RouterConfig,
+	logger zerolog.Logger,
+) message.TransportRouter {
+	return messageImpl.NewTransportRouter(manager, config, logger)
+}
+
 func newEventRouter(
 	manager *internal.Manager,
 	config *queue.RouterConfig,
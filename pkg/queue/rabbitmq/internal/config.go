@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package internal
+
+import (
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+)
+
+// PinChanged returns true if the pin was removed from the new config or its routing was changed
+func PinChanged(oldConfig *queue.RouterConfig, newConfig *queue.RouterConfig, pin string) bool {
+	oldPin, oldExists := oldConfig.Queues[pin]
+	newPin, newExists := newConfig.Queues[pin]
+	if oldExists != newExists {
+		return true
+	}
+	return oldPin.Exchange != newPin.Exchange ||
+		oldPin.RoutingKey != newPin.RoutingKey ||
+		oldPin.QueueName != newPin.QueueName ||
+		!reflect.DeepEqual(oldPin.Batching, newPin.Batching)
+}
+
+// subscriptionChanged returns true if the subscribe configuration of the pin was changed
+func subscriptionChanged(oldConfig *queue.RouterConfig, newConfig *queue.RouterConfig, pin string) bool {
+	return !reflect.DeepEqual(oldConfig.Queues[pin], newConfig.Queues[pin])
+}
+
+// PinsUpdate holds the senders and subscribers affected by the configuration change.
+// They are closed or reconfigured by Apply after the router lock is released
+// because it involves network I/O and waits for the deliveries being handled
+type PinsUpdate struct {
+	senders map[string]io.Closer
+	removed map[string]Subscriber
+	changed map[string]Subscriber
+	config  *queue.RouterConfig
+	logger  *zerolog.Logger
+}
+
+// ForgetChangedPins removes senders of changed pins and subscribers of removed pins so that they are created again on the next use.
+// Must be called under the router lock, the returned update must be applied after the lock is released.
+func ForgetChangedPins[S any](
+	oldConfig *queue.RouterConfig,
+	newConfig *queue.RouterConfig,
+	senders map[string]S,
+	subscribers map[string]Subscriber,
+	logger *zerolog.Logger,
+) *PinsUpdate {
+	update := &PinsUpdate{
+		senders: make(map[string]io.Closer),
+		removed: make(map[string]Subscriber),
+		changed: make(map[string]Subscriber),
+		config:  newConfig,
+		logger:  logger,
+	}
+	for pin, sender := range senders {
+		if !PinChanged(oldConfig, newConfig, pin) {
+			continue
+		}
+		if closer, ok := any(sender).(io.Closer); ok {
+			update.senders[pin] = closer
+		}
+		delete(senders, pin)
+		logger.Info().Str("Pin", pin).Msg("sender removed due to configuration change")
+	}
+	for pin, subscriber := range subscribers {
+		if _, exists := newConfig.Queues[pin]; !exists {
+			delete(subscribers, pin)
+			update.removed[pin] = subscriber
+		} else if subscriptionChanged(oldConfig, newConfig, pin) {
+			update.changed[pin] = subscriber
+		}
+	}
+	return update
+}
+
+// Apply closes the senders of changed pins and the subscribers of removed pins
+// and reconfigures the subscribers of changed pins
+func (u *PinsUpdate) Apply() {
+	for pin, sender := range u.senders {
+		if err := sender.Close(); err != nil {
+			u.logger.Error().Err(err).Str("Pin", pin).Msg("cannot close sender of changed pin")
+		}
+	}
+	for pin, subscriber := range u.removed {
+		if err := subscriber.Close(); err != nil {
+			u.logger.Error().Err(err).Str("Pin", pin).Msg("cannot close subscriber of removed pin")
+			continue
+		}
+		u.logger.Info().Str("Pin", pin).Msg("subscriber closed due to configuration change")
+	}
+	for pin, subscriber := range u.changed {
+		config := u.config.Queues[pin]
+		if err := subscriber.Reconfigure(&config); err != nil {
+			if errors.Is(err, ClosedSubscriberError) {
+				continue
+			}
+			u.logger.Error().Err(err).Str("Pin", pin).Msg("cannot resubscribe pin with changed configuration")
+			continue
+		}
+		u.logger.Info().Str("Pin", pin).Msg("subscriber reconfigured due to configuration change")
+	}
+}
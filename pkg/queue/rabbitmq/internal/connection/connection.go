@@ -17,11 +17,13 @@
 package connection
 
 import (
+	"context"
 	"errors"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -45,6 +47,13 @@ type connectionHolder struct {
 	notifyRecovered       []chan struct{}
 	minRecoveryTimeout    time.Duration
 	maxRecoveryTimeout    time.Duration
+	// maxRecoveryAttempts is the number of failed reconnection attempts before the holder gives up
+	maxRecoveryAttempts int
+	onStateChange       func(state connection.State)
+	// gaveUp is set when the holder stops the reconnection, onGaveUp is called after that
+	gaveUp    atomic.Bool
+	onGaveUp  func()
+	closeOnce sync.Once
 }
 
 func newConnection(url string, name string, logger zerolog.Logger,
@@ -65,11 +74,20 @@ func newConnection(url string, name string, logger zerolog.Logger,
 	} else {
 		maxRecoveryTimeout = defaultMaxRecoveryTimeout
 	}
+	maxRecoveryAttempts := defaultMaxRecoveryAttempts
+	if configuration.MaxRecoveryAttempts > 0 {
+		maxRecoveryAttempts = configuration.MaxRecoveryAttempts
+	}
 	logger.Info().
 		Dur("minRecoveryTimeout", minRecoveryTimeout).
 		Dur("maxRecoveryTimeout", maxRecoveryTimeout).
+		Int("maxRecoveryAttempts", maxRecoveryAttempts).
 		Msg("recovery timeouts configured")
-	conn, err := dial(url, name)
+	amqpConfig, err := newAmqpConfig(name, configuration)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(url, amqpConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +97,7 @@ func newConnection(url string, name string, logger zerolog.Logger,
 		channels:  make(map[string]*amqp.Channel),
 		done:      make(chan struct{}),
 		reconnectToMq: func() (*amqp.Connection, error) {
-			return dial(url, name)
+			return dial(url, amqpConfig)
 		},
 		onConnectionRecovered: onConnectionRecovered,
 		onChannelRecovered:    onChannelRecovered,
@@ -88,9 +106,21 @@ func newConnection(url string, name string, logger zerolog.Logger,
 		notifyRecovered:       make([]chan struct{}, 0),
 		minRecoveryTimeout:    minRecoveryTimeout,
 		maxRecoveryTimeout:    maxRecoveryTimeout,
+		maxRecoveryAttempts:   maxRecoveryAttempts,
 	}, nil
 }
 
+// setStateListener must be called before the connection routine is started
+func (c *connectionHolder) setStateListener(listener func(state connection.State)) {
+	c.onStateChange = listener
+}
+
+func (c *connectionHolder) reportState(state connection.State) {
+	if c.onStateChange != nil {
+		c.onStateChange(state)
+	}
+}
+
 func (c *connectionHolder) runConnectionRoutine() {
 	run := true
 	connectionClosed := true
@@ -118,7 +148,12 @@ func (c *connectionHolder) runConnectionRoutine() {
 			c.logger.Error().
 				Err(connErr).
 				Msg("received connection error. reconnecting")
-			c.tryToReconnect()
+			if !c.tryToReconnect() {
+				c.logger.Info().
+					Msg("stopping connection routine during reconnect")
+				run = false
+				break
+			}
 			if c.onConnectionRecovered != nil {
 				c.onConnectionRecovered()
 			}
@@ -132,20 +167,39 @@ func (c *connectionHolder) runConnectionRoutine() {
 	}
 }
 
-func (c *connectionHolder) tryToReconnect() {
+// tryToReconnect returns false if the holder was closed before the connection was restored
+// or maxRecoveryAttempts attempts have failed. The holder gives up in the latter case
+func (c *connectionHolder) tryToReconnect() bool {
+	c.reportState(connection.StateReconnecting)
 	var delay = c.minRecoveryTimeout
+	attempts := 0
 	for {
 		err := c.reconnect()
 		if err == nil {
 			c.logger.Info().
+				Int("attempts", attempts).
 				Msg("connection to rabbitmq restored")
-			break
+			c.reportState(connection.StateConnected)
+			return true
 		}
+		attempts++
 		c.logger.Error().
 			Err(err).
+			Int("attempts", attempts).
 			Dur("timeout", delay).
 			Msg("reconnect failed. retrying after timeout")
-		time.Sleep(delay)
+		if attempts >= c.maxRecoveryAttempts {
+			c.logger.Error().
+				Int("attempts", attempts).
+				Msg("reconnect attempts are exhausted")
+			c.giveUp()
+			return false
+		}
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(delay):
+		}
 		delay *= 2
 		if delay > c.maxRecoveryTimeout {
 			delay = c.maxRecoveryTimeout
@@ -175,24 +229,42 @@ func (c *connectionHolder) registerBlockingListener(blocking chan amqp.Blocking)
 	return c.conn.NotifyBlocked(blocking)
 }
 
-func dial(url string, name string) (*amqp.Connection, error) {
-	properties := amqp.NewConnectionProperties()
-	properties.SetClientConnectionName(name)
-	conn, err := amqp.DialConfig(url, amqp.Config{
-		Heartbeat:  30 * time.Second,
-		Locale:     "en_US",
-		Properties: properties,
-	})
-	return conn, err
+func dial(url string, amqpConfig amqp.Config) (*amqp.Connection, error) {
+	return amqp.DialConfig(url, amqpConfig)
 }
 
 func (c *connectionHolder) Close() error {
-	close(c.done)
+	c.closeOnce.Do(func() { close(c.done) })
 	c.connMutex.RLock()
 	defer c.connMutex.RUnlock()
+	if c.conn == nil {
+		return nil
+	}
 	return c.conn.Close()
 }
 
+// giveUp stops the holder so that its operations fail with ErrRecoveryFailed
+func (c *connectionHolder) giveUp() {
+	if !c.gaveUp.CompareAndSwap(false, true) {
+		return
+	}
+	if err := c.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		c.logger.Warn().Err(err).Msg("cannot close connection")
+	}
+	c.reportState(connection.StateGaveUp)
+	if c.onGaveUp != nil {
+		c.onGaveUp()
+	}
+}
+
+// closedErr returns the error for the operations of the closed holder
+func (c *connectionHolder) closedErr() error {
+	if c.gaveUp.Load() {
+		return connection.ErrRecoveryFailed
+	}
+	return amqp.ErrClosed
+}
+
 func (c *connectionHolder) waitRecovered(ch chan struct{}) <-chan struct{} {
 	c.connMutex.RLock()
 	if !c.conn.IsClosed() {
@@ -208,11 +280,20 @@ func (c *connectionHolder) waitRecovered(ch chan struct{}) <-chan struct{} {
 	return ch
 }
 
-func (c *connectionHolder) getChannel(key string) (*amqp.Channel, error) {
+func (c *connectionHolder) getChannel(ctx context.Context, key string) (*amqp.Channel, error) {
 	var ch *amqp.Channel
 	var err error
 	var exists bool
-	<-c.waitRecovered(make(chan struct{}))
+	if c.gaveUp.Load() {
+		return nil, connection.ErrRecoveryFailed
+	}
+	select {
+	case <-c.waitRecovered(make(chan struct{})):
+	case <-c.done:
+		return nil, c.closedErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 	c.connMutex.RLock()
 	ch, exists = c.channels[key]
 	c.connMutex.RUnlock()
@@ -223,6 +304,23 @@ func (c *connectionHolder) getChannel(key string) (*amqp.Channel, error) {
 	return ch, err
 }
 
+// releaseChannel closes the channel and removes it from the cache
+func (c *connectionHolder) releaseChannel(key string) {
+	c.connMutex.Lock()
+	ch, exists := c.channels[key]
+	delete(c.channels, key)
+	c.connMutex.Unlock()
+	if !exists {
+		return
+	}
+	if err := ch.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		c.logger.Warn().
+			Err(err).
+			Str("channelKey", key).
+			Msg("cannot close channel")
+	}
+}
+
 func (c *connectionHolder) getOrCreateChannel(key string) (*amqp.Channel, error) {
 	c.connMutex.Lock()
 	defer c.connMutex.Unlock()
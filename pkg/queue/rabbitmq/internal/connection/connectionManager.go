@@ -17,28 +17,63 @@
 package connection
 
 import (
-	"fmt"
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
 )
 
+// MessagePublisher sends serialized batches to the exchange with the specified routing key.
+type MessagePublisher interface {
+	Publish(ctx context.Context, body []byte, routingKey string, exchange string, th2Pin string, th2Type string) error
+	// PublishWithHeaders is the same as Publish but adds the headers to the published data
+	PublishWithHeaders(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error
+	// PublishConfirmed is the same as PublishWithHeaders but waits for the confirmation of the broker
+	// even if the publisher confirmation is disabled. Unroutable data is reported as an error
+	PublishConfirmed(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error
+	// DeclareDelayQueue declares the durable queue keeping the data for the delay.
+	// The expired data is routed to the target queue via the default exchange
+	DeclareDelayQueue(ctx context.Context, name string, delay time.Duration, targetQueue string) error
+	io.Closer
+}
+
+// MessageConsumer delivers data from the queue to the handler.
+// The returned cancel function stops the subscription without waiting for the deliveries which are being handled,
+// so it can be called from the handler. The returned done channel is closed when the deliveries received
+// before the cancellation are handled or returned to the queue and the handler is not called anymore.
+type MessageConsumer interface {
+	Consume(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(delivery amqp.Delivery) error) (cancel func(), done <-chan struct{}, err error)
+	ConsumeWithManualAck(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) (cancel func(), done <-chan struct{}, err error)
+	io.Closer
+}
+
+type blockingListenerRegistrar interface {
+	registerBlockingListener(blocking chan amqp.Blocking) <-chan amqp.Blocking
+}
+
 type Manager struct {
-	Publisher *Publisher
-	Consumer  *Consumer
+	Publisher MessagePublisher
+	Consumer  MessageConsumer
 
-	Logger zerolog.Logger
-	closed chan struct{}
+	Logger   zerolog.Logger
+	listener connection.StateListener
+	closed   chan struct{}
 }
 
 func NewConnectionManager(connConfiguration connection.Config, componentName string, logger zerolog.Logger) (Manager, error) {
-	url := fmt.Sprintf("amqp://%s:%s@%s:%d/%s",
-		connConfiguration.Username,
-		connConfiguration.Password,
-		connConfiguration.Host,
-		connConfiguration.Port,
-		connConfiguration.VHost)
+	return NewConnectionManagerWithListener(connConfiguration, componentName, nil, logger)
+}
+
+// NewConnectionManagerWithListener is the same as NewConnectionManager but reports the state changes
+// of the publisher and consumer connections to the listener. The listener can be nil
+func NewConnectionManagerWithListener(connConfiguration connection.Config, componentName string,
+	listener connection.StateListener, logger zerolog.Logger) (Manager, error) {
+	url := buildURL(connConfiguration)
 	publisher, err := NewPublisher(url, connConfiguration, componentName, log.ForComponent("publisher"))
 	if err != nil {
 		return Manager{}, err
@@ -51,18 +86,38 @@ func NewConnectionManager(connConfiguration connection.Config, componentName str
 		}
 		return Manager{}, err
 	}
+	if listener != nil {
+		publisher.setStateListener(func(state connection.State) {
+			listener(connection.PublisherConnection, state)
+		})
+		consumer.setStateListener(func(state connection.State) {
+			listener(connection.ConsumerConnection, state)
+		})
+		listener(connection.PublisherConnection, connection.StateConnected)
+		listener(connection.ConsumerConnection, connection.StateConnected)
+	}
+	// the manager cannot work without any of the connections
+	publisher.onGaveUp = consumer.giveUp
+	consumer.onGaveUp = publisher.giveUp
 	go publisher.runConnectionRoutine()
 	go consumer.runConnectionRoutine()
 	return Manager{
 		Publisher: &publisher,
 		Consumer:  &consumer,
 		Logger:    logger,
+		listener:  listener,
 		// capacity is one to avoid blocking close call
 		closed: make(chan struct{}),
 	}, nil
 }
 
 func (manager *Manager) ListenForBlockingNotifications() {
+	publisher, isPublisherRegistrar := manager.Publisher.(blockingListenerRegistrar)
+	consumer, isConsumerRegistrar := manager.Consumer.(blockingListenerRegistrar)
+	if !isPublisherRegistrar || !isConsumerRegistrar {
+		manager.Logger.Debug().Msg("blocking notifications are not supported by the connection")
+		return
+	}
 	var run = true
 	var consumerClosed = true
 	var publisherClosed = true
@@ -75,11 +130,11 @@ func (manager *Manager) ListenForBlockingNotifications() {
 		// the connections for publisher and consumer will be recreated.
 		// Old channels will be closed and never receive a new value
 		if publisherClosed {
-			publisherNotifications = manager.Publisher.registerBlockingListener(make(chan amqp.Blocking, 1))
+			publisherNotifications = publisher.registerBlockingListener(make(chan amqp.Blocking, 1))
 			publisherClosed = false
 		}
 		if consumerClosed {
-			consumerNotifications = manager.Consumer.registerBlockingListener(make(chan amqp.Blocking, 1))
+			consumerNotifications = consumer.registerBlockingListener(make(chan amqp.Blocking, 1))
 			consumerClosed = false
 		}
 		select {
@@ -96,6 +151,7 @@ func (manager *Manager) ListenForBlockingNotifications() {
 				Str("reason", consumerBlocked.Reason).
 				Bool("active", consumerBlocked.Active).
 				Msg("received blocked notification for consumer")
+			manager.reportBlocking(connection.ConsumerConnection, consumerBlocked)
 		case publisherBlocked, ok := <-publisherNotifications:
 			if !ok {
 				publisherClosed = true
@@ -105,10 +161,32 @@ func (manager *Manager) ListenForBlockingNotifications() {
 				Str("reason", publisherBlocked.Reason).
 				Bool("active", publisherBlocked.Active).
 				Msg("received blocked notification for publisher")
+			manager.reportBlocking(connection.PublisherConnection, publisherBlocked)
 		}
 	}
 }
 
+func (manager *Manager) reportBlocking(connectionName string, blocking amqp.Blocking) {
+	if manager.listener == nil {
+		return
+	}
+	if blocking.Active {
+		manager.listener(connectionName, connection.StateBlocked)
+	} else {
+		manager.listener(connectionName, connection.StateConnected)
+	}
+}
+
+// NewManager creates a Manager on top of already established publisher and consumer.
+func NewManager(publisher MessagePublisher, consumer MessageConsumer, logger zerolog.Logger) Manager {
+	return Manager{
+		Publisher: publisher,
+		Consumer:  consumer,
+		Logger:    logger,
+		closed:    make(chan struct{}),
+	}
+}
+
 func (manager *Manager) Close() error {
 	close(manager.closed)
 
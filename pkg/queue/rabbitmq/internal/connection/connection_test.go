@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+	"errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"testing"
+	"time"
+)
+
+func newFailingHolder(failures int, maxAttempts int) *connectionHolder {
+	return &connectionHolder{
+		channels: make(map[string]*amqp.Channel),
+		done:     make(chan struct{}),
+		reconnectToMq: func() (*amqp.Connection, error) {
+			if failures > 0 {
+				failures--
+				return nil, errors.New("connection refused")
+			}
+			return &amqp.Connection{}, nil
+		},
+		logger:              zerolog.Nop(),
+		minRecoveryTimeout:  time.Millisecond,
+		maxRecoveryTimeout:  time.Millisecond,
+		maxRecoveryAttempts: maxAttempts,
+	}
+}
+
+func TestReconnectRestoresConnectionWithinAttempts(t *testing.T) {
+	holder := newFailingHolder(2, 3)
+	var states []connCfg.State
+	holder.setStateListener(func(state connCfg.State) {
+		states = append(states, state)
+	})
+
+	assert.True(t, holder.tryToReconnect())
+	assert.Equal(t, []connCfg.State{
+		connCfg.StateReconnecting,
+		connCfg.StateConnected,
+	}, states)
+}
+
+func TestReconnectGivesUpWhenAttemptsAreExhausted(t *testing.T) {
+	holder := newFailingHolder(3, 2)
+	var states []connCfg.State
+	holder.setStateListener(func(state connCfg.State) {
+		states = append(states, state)
+	})
+	peer := newFailingHolder(0, 2)
+	holder.onGaveUp = peer.giveUp
+	peer.onGaveUp = holder.giveUp
+
+	assert.False(t, holder.tryToReconnect())
+	assert.Equal(t, []connCfg.State{
+		connCfg.StateReconnecting,
+		connCfg.StateGaveUp,
+	}, states)
+	_, err := holder.getChannel(context.Background(), "key")
+	assert.ErrorIs(t, err, connCfg.ErrRecoveryFailed)
+	_, err = peer.getChannel(context.Background(), "key")
+	assert.ErrorIs(t, err, connCfg.ErrRecoveryFailed, "the peer connection must be stopped too")
+	select {
+	case <-peer.done:
+	default:
+		t.Fatal("the peer connection routine is not stopped")
+	}
+}
+
+func TestReconnectStopsWhenHolderIsClosed(t *testing.T) {
+	holder := &connectionHolder{
+		channels: make(map[string]*amqp.Channel),
+		done:     make(chan struct{}),
+		reconnectToMq: func() (*amqp.Connection, error) {
+			return nil, errors.New("connection refused")
+		},
+		logger:              zerolog.Nop(),
+		minRecoveryTimeout:  time.Millisecond,
+		maxRecoveryTimeout:  time.Millisecond,
+		maxRecoveryAttempts: 5,
+	}
+	close(holder.done)
+	assert.False(t, holder.tryToReconnect())
+}
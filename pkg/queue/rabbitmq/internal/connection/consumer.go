@@ -17,18 +17,20 @@
 package connection
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/dispatch"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"sync/atomic"
 	"time"
 )
 
-var th2RabbitmqMessageSizeSubscribeBytes = promauto.NewCounterVec(
+var th2RabbitmqMessageSizeSubscribeBytes = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_rabbitmq_message_size_subscribe_bytes",
 		Help: "Amount of bytes received",
@@ -36,7 +38,7 @@ var th2RabbitmqMessageSizeSubscribeBytes = promauto.NewCounterVec(
 	metrics.SubscriberLabels,
 )
 
-var th2RabbitmqMessageProcessDurationSeconds = promauto.NewHistogramVec(
+var th2RabbitmqMessageProcessDurationSeconds = prometheus.NewHistogramVec(
 	prometheus.HistogramOpts{
 		Name:    "th2_rabbitmq_message_process_duration_seconds",
 		Help:    "Subscriber's handling process duration",
@@ -45,10 +47,15 @@ var th2RabbitmqMessageProcessDurationSeconds = promauto.NewHistogramVec(
 	metrics.SubscriberLabels,
 )
 
+const defaultPrefetchCount = 10
+
 type Consumer struct {
 	*connectionHolder
 	Logger                          zerolog.Logger
 	maxMissingQueueRecoveryAttempts int
+	prefetchCount                   int
+	componentName                   string
+	tagCounter                      *atomic.Uint64
 }
 
 func NewConsumer(url string, configuration connection.Config, componentName string, logger zerolog.Logger) (Consumer, error) {
@@ -59,9 +66,19 @@ func NewConsumer(url string, configuration connection.Config, componentName stri
 	if configuration.MaxRecoveryAttempts > 0 {
 		maxMissingQueueRecoveryAttempts = configuration.MaxRecoveryAttempts
 	}
+	prefetchCount := configuration.PrefetchCount
+	if prefetchCount == 0 {
+		prefetchCount = defaultPrefetchCount
+	} else if prefetchCount < 0 {
+		// zero prefetch count means no limit for the broker
+		prefetchCount = 0
+	}
 	consumer := Consumer{
 		Logger:                          logger,
 		maxMissingQueueRecoveryAttempts: maxMissingQueueRecoveryAttempts,
+		prefetchCount:                   prefetchCount,
+		componentName:                   componentName,
+		tagCounter:                      &atomic.Uint64{},
 	}
 	conn, err := newConnection(url, fmt.Sprintf("%s_consumer", componentName),
 		logger, configuration, nil, nil)
@@ -73,9 +90,9 @@ func NewConsumer(url string, configuration connection.Config, componentName stri
 	return consumer, nil
 }
 
-func (cns *Consumer) Consume(queueName string, th2Pin string, th2Type string, handler func(delivery amqp.Delivery) error) error {
+func (cns *Consumer) Consume(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(delivery amqp.Delivery) error) (func(), <-chan struct{}, error) {
 	return cns.consume(
-		queueName, th2Pin, th2Type, cns.consumeWithAutoAck, "consume",
+		ctx, queueName, th2Pin, th2Type, options, true, "consume",
 		func(delivery amqp.Delivery, timer *prometheus.Timer) error {
 			defer timer.ObserveDuration()
 			return handler(delivery)
@@ -83,121 +100,270 @@ func (cns *Consumer) Consume(queueName string, th2Pin string, th2Type string, ha
 	)
 }
 
-func (cns *Consumer) ConsumeWithManualAck(queueName string, th2Pin string, th2Type string, handler func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) error {
+func (cns *Consumer) ConsumeWithManualAck(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
 	return cns.consume(
-		queueName, th2Pin, th2Type, cns.consumeWithManualAck, "consumeWithManualAck",
+		ctx, queueName, th2Pin, th2Type, options, false, "consumeWithManualAck",
 		func(delivery amqp.Delivery, timer *prometheus.Timer) error {
 			return handler(delivery, timer)
 		},
 	)
 }
 
-func (cns *Consumer) consume(queueName string, th2Pin string, th2Type string,
-	subscriptionProducer func(queueName string, methodName string) (*amqp.Channel, <-chan amqp.Delivery, error),
-	methodName string, handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) error {
-	ch, msgs, err := subscriptionProducer(queueName, methodName)
+// subscription holds the state of a single consumer of the queue
+type subscription struct {
+	queueName  string
+	th2Pin     string
+	th2Type    string
+	methodName string
+	tag        string
+	autoAck    bool
+	options    connection.ConsumeOptions
+	handler    func(delivery amqp.Delivery, timer *prometheus.Timer) error
+	// ctx is done when the subscription is cancelled
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// consume uses the context only for starting the subscription.
+// The subscription is recovered in background without the context after channel failures.
+// If options have more than one worker, deliveries are handled by the dispatcher and
+// the process duration is measured in the worker.
+// The subscription waiting for the exclusive consumption is started in background.
+// The returned function cancels the subscription, the returned channel is closed when the deliveries are drained.
+func (cns *Consumer) consume(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions,
+	autoAck bool, methodName string, handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
+	subCtx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{
+		queueName:  queueName,
+		th2Pin:     th2Pin,
+		th2Type:    th2Type,
+		methodName: methodName,
+		tag:        cns.consumerTag(th2Pin),
+		autoAck:    autoAck,
+		options:    options,
+		handler:    handler,
+		ctx:        subCtx,
+		cancel:     cancel,
+		stopped:    make(chan struct{}),
+	}
+	if options.WaitForExclusive {
+		go func() {
+			ch, msgs, err := cns.consumeFromQueue(sub.ctx, sub)
+			if err != nil {
+				cns.releaseChannel(sub.tag)
+				if !errors.Is(err, context.Canceled) {
+					cns.Logger.Error().
+						Err(err).
+						Str("method", methodName).
+						Str("queue", queueName).
+						Msg("cannot start exclusive consumer")
+					sub.fail(err)
+				}
+				close(sub.stopped)
+				return
+			}
+			cns.handleDeliveries(sub, ch, msgs)
+		}()
+		return sub.cancel, sub.stopped, nil
+	}
+	startCtx, stopStart := context.WithCancel(ctx)
+	defer stopStart()
+	// the start is interrupted by both the caller context and the cancellation of the subscription
+	context.AfterFunc(sub.ctx, stopStart)
+	ch, msgs, err := cns.consumeFromQueue(startCtx, sub)
 	if err != nil {
-		return err
-	}
-
-	go func() {
-		cns.Logger.Debug().
-			Str("method", methodName).
-			Str("queue", queueName).
-			Msg("start handling messages")
-		running := true
-		durationObserver := th2RabbitmqMessageProcessDurationSeconds.WithLabelValues(th2Pin, th2Type, queueName)
-		messageSizeObserver := th2RabbitmqMessageSizeSubscribeBytes.WithLabelValues(th2Pin, th2Type, queueName)
-		handleDelivery := func(d amqp.Delivery) {
-			timer := prometheus.NewTimer(durationObserver)
-			cns.Logger.Trace().
+		cancel()
+		cns.releaseChannel(sub.tag)
+		return nil, nil, err
+	}
+	go cns.handleDeliveries(sub, ch, msgs)
+	return sub.cancel, sub.stopped, nil
+}
+
+func (cns *Consumer) handleDeliveries(sub *subscription, ch *amqp.Channel, msgs <-chan amqp.Delivery) {
+	defer close(sub.stopped)
+	queueName := sub.queueName
+	methodName := sub.methodName
+	cns.Logger.Debug().
+		Str("method", methodName).
+		Str("queue", queueName).
+		Str("consumerTag", sub.tag).
+		Msg("start handling messages")
+	running := true
+	durationObserver := th2RabbitmqMessageProcessDurationSeconds.WithLabelValues(sub.th2Pin, sub.th2Type, queueName)
+	messageSizeObserver := th2RabbitmqMessageSizeSubscribeBytes.WithLabelValues(sub.th2Pin, sub.th2Type, queueName)
+	process := func(d amqp.Delivery) {
+		timer := prometheus.NewTimer(durationObserver)
+		cns.Logger.Trace().
+			Str("exchange", d.Exchange).
+			Str("routing", d.RoutingKey).
+			Int("bodySize", len(d.Body)).
+			Msg("receive delivery")
+		if err := sub.handler(d, timer); err != nil {
+			cns.Logger.Error().
+				Err(err).
 				Str("exchange", d.Exchange).
 				Str("routing", d.RoutingKey).
 				Int("bodySize", len(d.Body)).
-				Msg("receive delivery")
-			if err := handler(d, timer); err != nil {
-				cns.Logger.Error().
-					Err(err).
-					Str("exchange", d.Exchange).
-					Str("routing", d.RoutingKey).
-					Int("bodySize", len(d.Body)).
-					Msg("Cannot handle delivery")
+				Msg("Cannot handle delivery")
+		}
+		messageSizeObserver.Add(float64(len(d.Body)))
+	}
+	handleDelivery := process
+	var dispatcher *dispatch.Dispatcher
+	if options := sub.options; options.Workers > 1 {
+		dispatcher = dispatch.New(options.Workers, options.OrderingKey != nil)
+		handleDelivery = func(d amqp.Delivery) {
+			var key string
+			if options.OrderingKey != nil {
+				key = options.OrderingKey(d)
 			}
-			messageSizeObserver.Add(float64(len(d.Body)))
+			dispatcher.Dispatch(key, func() { process(d) })
 		}
-		deliveries := msgs
-		chErrors := ch.NotifyClose(make(chan *amqp.Error))
-		for running {
-			select {
-			case _, ok := <-cns.done:
-				if !ok {
-					running = false
-					// drain messages
-					for d := range deliveries {
-						handleDelivery(d)
-					}
-				}
-			case chErr, ok := <-chErrors:
-				if !ok {
-					break
+	}
+	deliveries := msgs
+	// failure is the error of the recovery, the subscription is stopped
+	var failure error
+	chErrors := ch.NotifyClose(make(chan *amqp.Error))
+	for running {
+		select {
+		case _, ok := <-cns.done:
+			if !ok {
+				running = false
+				if cns.gaveUp.Load() {
+					failure = connection.ErrRecoveryFailed
 				}
-				cns.Logger.Error().
-					Err(chErr).
-					Str("queue", queueName).
-					Msg("consumer error")
 				// drain messages
 				for d := range deliveries {
 					handleDelivery(d)
 				}
-				ch, deliveries, err = subscriptionProducer(queueName, methodName)
-				if err != nil {
-					if errors.Is(err, amqp.ErrClosed) {
-						break
-					}
-					// TODO: decide what to do in this case
-					panic(fmt.Sprintf("failure during consumer %s recovery: %v", methodName, err))
-				}
-				chErrors = ch.NotifyClose(make(chan *amqp.Error))
-				cns.Logger.Info().
-					Str("queue", queueName).
-					Msg("consumer channel recovered")
-			case d, ok := <-deliveries:
-				if !ok {
+			}
+		case <-sub.ctx.Done():
+			running = false
+			cns.cancelConsumer(sub, ch, deliveries, handleDelivery)
+		case chErr, ok := <-chErrors:
+			if !ok {
+				break
+			}
+			cns.Logger.Error().
+				Err(chErr).
+				Str("queue", queueName).
+				Msg("consumer error")
+			// drain messages
+			for d := range deliveries {
+				handleDelivery(d)
+			}
+			var err error
+			ch, deliveries, err = cns.consumeFromQueue(sub.ctx, sub)
+			if err != nil {
+				running = false
+				if errors.Is(err, amqp.ErrClosed) || errors.Is(err, context.Canceled) {
 					break
 				}
-				handleDelivery(d)
+				cns.Logger.Error().
+					Err(err).
+					Str("method", methodName).
+					Str("queue", queueName).
+					Msg("cannot recover consumer")
+				failure = err
+				break
+			}
+			chErrors = ch.NotifyClose(make(chan *amqp.Error))
+			cns.Logger.Info().
+				Str("queue", queueName).
+				Msg("consumer channel recovered")
+		case d, ok := <-deliveries:
+			if !ok {
+				break
 			}
+			handleDelivery(d)
 		}
-		cns.Logger.Debug().
-			Str("method", methodName).
-			Str("queue", queueName).
-			Msg("stop handling messages")
-	}()
-
-	return nil
+	}
+	if dispatcher != nil {
+		dispatcher.Stop()
+	}
+	cns.releaseChannel(sub.tag)
+	if failure != nil {
+		sub.fail(failure)
+	}
+	cns.Logger.Debug().
+		Str("method", methodName).
+		Str("queue", queueName).
+		Str("consumerTag", sub.tag).
+		Msg("stop handling messages")
 }
 
-func (cns *Consumer) consumeWithManualAck(queueName string, methodName string) (*amqp.Channel, <-chan amqp.Delivery, error) {
-	return cns.consumeFromQueue(queueName, methodName, false)
+// fail reports the failure of the subscription, the handler is not called after it
+func (sub *subscription) fail(err error) {
+	if sub.options.OnFailure != nil {
+		sub.options.OnFailure(fmt.Errorf("consumer of queue %s failed: %w", sub.queueName, err))
+	}
 }
 
-func (cns *Consumer) consumeWithAutoAck(queueName string, methodName string) (*amqp.Channel, <-chan amqp.Delivery, error) {
-	return cns.consumeFromQueue(queueName, methodName, true)
+// cancelConsumer stops the deliveries from the broker.
+// Deliveries received before the cancellation are handled in auto-ack mode and returned to the queue in manual mode
+func (cns *Consumer) cancelConsumer(sub *subscription, ch *amqp.Channel, deliveries <-chan amqp.Delivery, handleDelivery func(d amqp.Delivery)) {
+	if err := ch.Cancel(sub.tag, false); err != nil {
+		cns.Logger.Warn().
+			Err(err).
+			Str("queue", sub.queueName).
+			Str("consumerTag", sub.tag).
+			Msg("cannot cancel consumer")
+	}
+	// the channel is closed by the library after the broker confirms the cancellation
+	requeued := 0
+	for d := range deliveries {
+		if sub.autoAck {
+			handleDelivery(d)
+			continue
+		}
+		if err := d.Nack(false, true); err != nil {
+			cns.Logger.Error().
+				Err(err).
+				Str("queue", sub.queueName).
+				Msg("cannot return delivery to the queue")
+		}
+		requeued++
+	}
+	cns.Logger.Info().
+		Str("queue", sub.queueName).
+		Str("consumerTag", sub.tag).
+		Int("requeued", requeued).
+		Msg("consumer cancelled")
 }
 
-func (cns *Consumer) consumeFromQueue(queueName string, methodName string, autoAck bool) (*amqp.Channel, <-chan amqp.Delivery, error) {
+func (cns *Consumer) consumeFromQueue(ctx context.Context, sub *subscription) (*amqp.Channel, <-chan amqp.Delivery, error) {
+	queueName := sub.queueName
+	methodName := sub.methodName
 	attempts := 0
 	timeout := cns.minRecoveryTimeout
 	for {
-		ch, err := cns.getChannel(queueName)
+		// each subscription uses own channel to release it on cancellation
+		ch, err := cns.getChannel(ctx, sub.tag)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		msgs, err := cns.startConsuming(ch, queueName, autoAck)
+		msgs, err := cns.startConsuming(ch, sub)
 		if err != nil {
 			var amqpErr *amqp.Error
 			isAmqpErr := errors.As(err, &amqpErr)
+			if isAmqpErr && amqpErr.Code == amqp.AccessRefused && sub.options.WaitForExclusive {
+				cns.Logger.Info().
+					Str("method", methodName).
+					Str("queue", queueName).
+					Dur("timeout", cns.minRecoveryTimeout).
+					Msg("queue is consumed by another consumer. Retry after timeout")
+				select {
+				case <-ctx.Done():
+					return nil, nil, ctx.Err()
+				case <-cns.done:
+					return nil, nil, amqp.ErrClosed
+				case <-time.After(cns.minRecoveryTimeout):
+				}
+				continue
+			}
 			if !isAmqpErr || amqpErr.Code != amqp.NotFound {
 				cns.Logger.Error().
 					Err(err).
@@ -215,7 +381,11 @@ func (cns *Consumer) consumeFromQueue(queueName string, methodName string, autoA
 				Int("attempts", attempts).
 				Dur("timeout", timeout).
 				Msg("queue is not found. Retry after timeout")
-			time.Sleep(timeout)
+			select {
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			case <-time.After(timeout):
+			}
 			timeout *= 2
 			if timeout > cns.maxRecoveryTimeout {
 				timeout = cns.maxRecoveryTimeout
@@ -227,19 +397,30 @@ func (cns *Consumer) consumeFromQueue(queueName string, methodName string, autoA
 	}
 }
 
-func (cns *Consumer) startConsuming(ch *amqp.Channel, queueName string, autoAck bool) (<-chan amqp.Delivery, error) {
+func (cns *Consumer) startConsuming(ch *amqp.Channel, sub *subscription) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(cns.prefetchCount, 0, false); err != nil {
+		return nil, err
+	}
 	msgs, err := ch.Consume(
-		queueName, // queue
-		// TODO: we need to provide a name that will help to identify the component
-		"",      // consumer
-		autoAck, // auto-ack
-		false,   // exclusive
-		false,   // no-local
-		false,   // no-wait
-		nil,     // args
+		sub.queueName, // queue
+		sub.tag,       // consumer
+		sub.autoAck,   // auto-ack
+		sub.options.Exclusive || sub.options.WaitForExclusive, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
 	)
 	if err != nil {
 		return nil, err
 	}
 	return msgs, nil
 }
+
+// consumerTag identifies the subscription in the RabbitMQ management UI
+func (cns *Consumer) consumerTag(th2Pin string) string {
+	id := cns.tagCounter.Add(1)
+	if cns.componentName == "" {
+		return fmt.Sprintf("%s.%d", th2Pin, id)
+	}
+	return fmt.Sprintf("%s.%s.%d", cns.componentName, th2Pin, id)
+}
@@ -17,11 +17,14 @@
 package connection
 
 import (
+	"context"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
 	"github.com/th2-net/th2-common-go/test/modules/rabbitmq"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -51,7 +54,7 @@ func TestConsumer_Consume(t *testing.T) {
 	conn.BindQueue(config, queue, routingKey)
 
 	deliveries := make(chan []byte, 1)
-	err = manager.Consumer.Consume(queue.Name, "pin", "test", func(delivery amqp.Delivery) error {
+	_, _, err = manager.Consumer.Consume(context.Background(), queue.Name, "pin", "test", connCfg.ConsumeOptions{}, func(delivery amqp.Delivery) error {
 		deliveries <- delivery.Body
 		close(deliveries)
 		return nil
@@ -69,3 +72,77 @@ func TestConsumer_Consume(t *testing.T) {
 		t.Fatal("didn't receive the data withing 1 second")
 	}
 }
+
+func TestConsumer_ExclusiveConsumerWithTag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("do not run containers in short run")
+		return
+	}
+	config := rabbitmq.StartMq(t, "test")
+
+	manager, err := NewConnectionManager(config, "box", consumerLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	conn, err := rabbitmq.RawAmqp(t, config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	queue := conn.CreateQueue("test")
+	routingKey := "test-publish"
+	conn.BindQueue(config, queue, routingKey)
+
+	tags := make(chan string, 1)
+	cancel, done, err := manager.Consumer.Consume(context.Background(), queue.Name, "pin", "test", connCfg.ConsumeOptions{Exclusive: true},
+		func(delivery amqp.Delivery) error {
+			tags <- delivery.ConsumerTag
+			return nil
+		})
+	if err != nil {
+		t.Fatal("cannot start consuming", err)
+	}
+	otherConn, err := rabbitmq.RawAmqp(t, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherConn.Close()
+	_, err = otherConn.TryConsume(queue)
+	var amqpErr *amqp.Error
+	if assert.ErrorAs(t, err, &amqpErr) {
+		assert.Equal(t, amqp.AccessRefused, amqpErr.Code)
+	}
+
+	conn.Publish(config, routingKey, []byte("hello"))
+	select {
+	case tag := <-tags:
+		assert.Equal(t, "box.pin.1", tag)
+	case <-time.After(1 * time.Second):
+		t.Fatal("didn't receive the data withing 1 second")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer is not cancelled")
+	}
+	// the channel of the refused consumer is closed by the broker
+	freeConn, err := rabbitmq.RawAmqp(t, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer freeConn.Close()
+	_, err = freeConn.TryConsume(queue)
+	assert.NoError(t, err, "queue must be free after cancellation")
+}
+
+func TestConsumerTagIncludesComponentAndPin(t *testing.T) {
+	consumer := Consumer{componentName: "box", tagCounter: &atomic.Uint64{}}
+	assert.Equal(t, "box.pin.1", consumer.consumerTag("pin"))
+	assert.Equal(t, "box.pin.2", consumer.consumerTag("pin"))
+
+	consumer.componentName = ""
+	assert.Equal(t, "pin.3", consumer.consumerTag("pin"))
+}
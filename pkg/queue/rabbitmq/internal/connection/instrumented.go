@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+)
+
+// NewInstrumentedManager is the same as NewManager but records the metrics of the publications and the deliveries
+// as the RabbitMQ publisher and consumer do. It is used for the brokers which do not record them, e.g. the in-memory one
+func NewInstrumentedManager(publisher MessagePublisher, consumer MessageConsumer, logger zerolog.Logger) Manager {
+	return NewManager(&instrumentedPublisher{MessagePublisher: publisher}, &instrumentedConsumer{MessageConsumer: consumer}, logger)
+}
+
+type instrumentedPublisher struct {
+	MessagePublisher
+}
+
+func (p *instrumentedPublisher) Publish(ctx context.Context, body []byte, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return p.PublishWithHeaders(ctx, body, nil, routingKey, exchange, th2Pin, th2Type)
+}
+
+func (p *instrumentedPublisher) PublishWithHeaders(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	if err := p.MessagePublisher.PublishWithHeaders(ctx, body, headers, routingKey, exchange, th2Pin, th2Type); err != nil {
+		return err
+	}
+	recordPublication(body, routingKey, exchange, th2Pin, th2Type)
+	return nil
+}
+
+func (p *instrumentedPublisher) PublishConfirmed(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	if err := p.MessagePublisher.PublishConfirmed(ctx, body, headers, routingKey, exchange, th2Pin, th2Type); err != nil {
+		return err
+	}
+	th2RabbitmqPublishConfirmedTotal.WithLabelValues(th2Pin, th2Type, exchange, routingKey).Inc()
+	recordPublication(body, routingKey, exchange, th2Pin, th2Type)
+	return nil
+}
+
+func recordPublication(body []byte, routingKey string, exchange string, th2Pin string, th2Type string) {
+	th2RabbitmqMessageSizePublishBytes.WithLabelValues(th2Pin, th2Type, exchange, routingKey).Add(float64(len(body)))
+	th2RabbitmqMessagePublishTotal.WithLabelValues(th2Pin, th2Type, exchange, routingKey).Inc()
+}
+
+// instrumentedConsumer replaces the timers passed by the consumer with the ones observing the process duration
+type instrumentedConsumer struct {
+	MessageConsumer
+}
+
+func (c *instrumentedConsumer) Consume(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(delivery amqp.Delivery) error) (func(), <-chan struct{}, error) {
+	durationObserver, messageSizeObserver := deliveryObservers(queueName, th2Pin, th2Type)
+	return c.MessageConsumer.Consume(ctx, queueName, th2Pin, th2Type, options, func(delivery amqp.Delivery) error {
+		defer messageSizeObserver.Add(float64(len(delivery.Body)))
+		defer prometheus.NewTimer(durationObserver).ObserveDuration()
+		return handler(delivery)
+	})
+}
+
+func (c *instrumentedConsumer) ConsumeWithManualAck(ctx context.Context, queueName string, th2Pin string, th2Type string, options connection.ConsumeOptions, handler func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
+	durationObserver, messageSizeObserver := deliveryObservers(queueName, th2Pin, th2Type)
+	return c.MessageConsumer.ConsumeWithManualAck(ctx, queueName, th2Pin, th2Type, options, func(delivery amqp.Delivery, _ *prometheus.Timer) error {
+		defer messageSizeObserver.Add(float64(len(delivery.Body)))
+		return handler(delivery, prometheus.NewTimer(durationObserver))
+	})
+}
+
+func deliveryObservers(queueName string, th2Pin string, th2Type string) (prometheus.Observer, prometheus.Counter) {
+	return th2RabbitmqMessageProcessDurationSeconds.WithLabelValues(th2Pin, th2Type, queueName),
+		th2RabbitmqMessageSizeSubscribeBytes.WithLabelValues(th2Pin, th2Type, queueName)
+}
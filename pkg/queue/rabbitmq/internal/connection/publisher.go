@@ -20,15 +20,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
 	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
 )
 
-var th2RabbitmqMessageSizePublishBytes = promauto.NewCounterVec(
+var th2RabbitmqMessageSizePublishBytes = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_rabbitmq_message_size_publish_bytes",
 		Help: "Amount of bytes sent",
@@ -36,7 +39,7 @@ var th2RabbitmqMessageSizePublishBytes = promauto.NewCounterVec(
 	metrics.SenderLabels,
 )
 
-var th2RabbitmqMessagePublishTotal = promauto.NewCounterVec(
+var th2RabbitmqMessagePublishTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_rabbitmq_message_publish_total",
 		Help: "Amount of batches sent",
@@ -44,9 +47,23 @@ var th2RabbitmqMessagePublishTotal = promauto.NewCounterVec(
 	metrics.SenderLabels,
 )
 
+const (
+	// confirmedChannelPrefix separates the channels of confirmed publications
+	// from the channels of other publications if the publisher confirmation is disabled
+	confirmedChannelPrefix = "confirmed:"
+	// declareChannelKey is the key of the channel declaring queues, it is closed by the broker on failure
+	declareChannelKey = "declare"
+
+	deadLetterExchangeArg   = "x-dead-letter-exchange"
+	deadLetterRoutingKeyArg = "x-dead-letter-routing-key"
+)
+
 type Publisher struct {
 	*connectionHolder
-	Logger zerolog.Logger
+	Logger    zerolog.Logger
+	confirmer *publishConfirmer
+	// confirmAll is true if all publications wait for the confirmation of the broker
+	confirmAll bool
 }
 
 func NewPublisher(url string, configuration connCfg.Config, componentName string, logger zerolog.Logger) (Publisher, error) {
@@ -54,7 +71,9 @@ func NewPublisher(url string, configuration connCfg.Config, componentName string
 		return Publisher{}, errors.New("url is not set")
 	}
 	publisher := Publisher{
-		Logger: logger,
+		Logger:     logger,
+		confirmer:  newPublishConfirmer(configuration, logger),
+		confirmAll: configuration.PublisherConfirmation,
 	}
 	c, err := newConnection(url, fmt.Sprintf("%s_publisher", componentName),
 		logger, configuration, nil, nil)
@@ -66,19 +85,61 @@ func NewPublisher(url string, configuration connCfg.Config, componentName string
 	return publisher, nil
 }
 
-func (pb *Publisher) Publish(body []byte, routingKey string, exchange string, th2Pin string, th2Type string) error {
+func (pb *Publisher) Publish(ctx context.Context, body []byte, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return pb.PublishWithHeaders(ctx, body, nil, routingKey, exchange, th2Pin, th2Type)
+}
+
+func (pb *Publisher) PublishWithHeaders(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return pb.publish(ctx, body, headers, routingKey, exchange, th2Pin, th2Type, pb.confirmAll)
+}
+
+func (pb *Publisher) PublishConfirmed(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return pb.publish(ctx, body, headers, routingKey, exchange, th2Pin, th2Type, true)
+}
+
+func (pb *Publisher) DeclareDelayQueue(ctx context.Context, name string, delay time.Duration, targetQueue string) error {
+	ch, err := pb.getChannel(ctx, declareChannelKey)
+	if err != nil {
+		return err
+	}
+	_, err = ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+		amqp.QueueMessageTTLArg: delay.Milliseconds(),
+		deadLetterExchangeArg:   "",
+		deadLetterRoutingKeyArg: targetQueue,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot declare delay queue %s: %w", name, err)
+	}
+	return nil
+}
 
-	ch, err := pb.getChannel(routingKey)
+func (pb *Publisher) publish(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string, confirm bool) error {
+	channelKey := routingKey
+	if confirm && !pb.confirmAll {
+		channelKey = confirmedChannelPrefix + routingKey
+	}
+	ch, err := pb.getChannel(ctx, channelKey)
 	if err != nil {
 		return err
 	}
 
-	// Ideally, the context should be passed from outside
-	// but this is breaking API change and we cannot do that
-	publError := ch.PublishWithContext(context.Background(), exchange, routingKey, false, false, amqp.Publishing{Body: body})
+	ctx, span := tracing.StartPublishSpan(ctx, exchange, routingKey)
+	publishing := properties.Publishing(ctx, body, headers)
+	var publError error
+	if confirm {
+		publError = pb.confirmer.publish(ctx, ch, exchange, routingKey, publishing, prometheus.Labels{
+			metrics.DefaultTh2PinLabelName:     th2Pin,
+			metrics.DefaultTh2TypeLabelName:    th2Type,
+			metrics.DefaultExchangeLabelName:   exchange,
+			metrics.DefaultRoutingKeyLabelName: routingKey,
+		})
+	} else {
+		publError = ch.PublishWithContext(ctx, exchange, routingKey, false, false, publishing)
+	}
+	tracing.EndSpan(span, publError)
 	if publError != nil {
 		pb.Logger.Error().Err(publError).Send()
-		return err
+		return publError
 	}
 	bodySize := len(body)
 	pb.Logger.Trace().Int("size", bodySize).Msg("data published")
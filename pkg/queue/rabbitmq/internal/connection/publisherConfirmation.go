@@ -0,0 +1,210 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+)
+
+const (
+	defaultConfirmationTimeout = 10 * time.Second
+	// returnsBufferSize must be big enough to never block the connection reader
+	// with the returns left after timed out publications
+	returnsBufferSize = 100
+	// publicationHeader holds the number of the confirmed publication on its channel
+	// to correlate basic.return with the publication
+	publicationHeader = "x-th2-publication"
+)
+
+var th2RabbitmqPublishConfirmedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_rabbitmq_publish_confirmed_total",
+		Help: "Amount of batches confirmed by broker",
+	},
+	metrics.SenderLabels,
+)
+
+var th2RabbitmqPublishNackedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_rabbitmq_publish_nacked_total",
+		Help: "Amount of batches rejected by broker or not confirmed in time",
+	},
+	metrics.SenderLabels,
+)
+
+var th2RabbitmqPublishReturnedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_rabbitmq_publish_returned_total",
+		Help: "Amount of batches returned by broker as unroutable",
+	},
+	metrics.SenderLabels,
+)
+
+// confirmedChannel holds the state of a channel in confirm mode.
+// Publications on the channel are serialized and numbered. The number is sent in the publicationHeader,
+// so that basic.return of a publication that was not confirmed in time is not attributed to the next one.
+type confirmedChannel struct {
+	mutex    sync.Mutex
+	returns  chan amqp.Return
+	sequence int64
+}
+
+type publishConfirmer struct {
+	timeout  time.Duration
+	mutex    sync.Mutex
+	channels map[*amqp.Channel]*confirmedChannel
+	logger   zerolog.Logger
+}
+
+// newPublishConfirmer creates the confirmer for all publications if the publisher confirmation is enabled,
+// otherwise it is used only for the publications which must be confirmed
+func newPublishConfirmer(configuration connCfg.Config, logger zerolog.Logger) *publishConfirmer {
+	timeout := defaultConfirmationTimeout
+	if configuration.PublisherConfirmationTimeout > 0 {
+		timeout = time.Duration(configuration.PublisherConfirmationTimeout) * time.Millisecond
+	}
+	if configuration.PublisherConfirmation {
+		logger.Info().
+			Dur("timeout", timeout).
+			Msg("publisher confirmation enabled")
+	}
+	return &publishConfirmer{
+		timeout:  timeout,
+		channels: make(map[*amqp.Channel]*confirmedChannel),
+		logger:   logger,
+	}
+}
+
+func (pc *publishConfirmer) publish(ctx context.Context, ch *amqp.Channel, exchange string, routingKey string,
+	publishing amqp.Publishing, labels prometheus.Labels) error {
+	state, err := pc.getConfirmedChannel(ch)
+	if err != nil {
+		return err
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.dropStaleReturns()
+	state.sequence++
+	publication := state.sequence
+	publishing.Headers = withPublication(publishing.Headers, publication)
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, publishing)
+	if err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, pc.timeout)
+	defer cancel()
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		th2RabbitmqPublishNackedTotal.With(labels).Inc()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("%w: exchange '%s', routing key '%s' after %v",
+				connCfg.ErrConfirmationTimeout, exchange, routingKey, pc.timeout)
+		}
+		return err
+	}
+	if !acked {
+		th2RabbitmqPublishNackedTotal.With(labels).Inc()
+		return fmt.Errorf("%w: exchange '%s', routing key '%s'", connCfg.ErrNotConfirmed, exchange, routingKey)
+	}
+	if returned, found := state.takeReturn(publication); found {
+		th2RabbitmqPublishReturnedTotal.With(labels).Inc()
+		return &connCfg.UnroutableError{
+			Exchange:   returned.Exchange,
+			RoutingKey: returned.RoutingKey,
+			ReplyCode:  returned.ReplyCode,
+			ReplyText:  returned.ReplyText,
+		}
+	}
+	th2RabbitmqPublishConfirmedTotal.With(labels).Inc()
+	return nil
+}
+
+func (pc *publishConfirmer) getConfirmedChannel(ch *amqp.Channel) (*confirmedChannel, error) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	if state, exists := pc.channels[ch]; exists {
+		return state, nil
+	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	state := &confirmedChannel{
+		returns: ch.NotifyReturn(make(chan amqp.Return, returnsBufferSize)),
+	}
+	pc.channels[ch] = state
+	go func() {
+		<-ch.NotifyClose(make(chan *amqp.Error, 1))
+		pc.mutex.Lock()
+		delete(pc.channels, ch)
+		pc.mutex.Unlock()
+		pc.logger.Trace().Msg("confirmed channel removed")
+	}()
+	return state, nil
+}
+
+func (cc *confirmedChannel) dropStaleReturns() {
+	for {
+		select {
+		case _, ok := <-cc.returns:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// takeReturn looks for basic.return of the publication among the received ones.
+// basic.return is always sent by broker before the basic.ack for the same publication,
+// the returns of the previous publications received late are dropped
+func (cc *confirmedChannel) takeReturn(publication int64) (amqp.Return, bool) {
+	for {
+		select {
+		case returned, ok := <-cc.returns:
+			if !ok {
+				return amqp.Return{}, false
+			}
+			if returnedPublication, _ := returned.Headers[publicationHeader].(int64); returnedPublication == publication {
+				return returned, true
+			}
+		default:
+			return amqp.Return{}, false
+		}
+	}
+}
+
+// withPublication copies the headers with the number of the publication
+func withPublication(headers amqp.Table, publication int64) amqp.Table {
+	marked := make(amqp.Table, len(headers)+1)
+	for key, value := range headers {
+		marked[key] = value
+	}
+	marked[publicationHeader] = publication
+	return marked
+}
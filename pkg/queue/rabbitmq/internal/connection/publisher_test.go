@@ -17,8 +17,11 @@
 package connection
 
 import (
+	"context"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
 	"github.com/th2-net/th2-common-go/test/modules/rabbitmq"
 	"os"
 	"testing"
@@ -49,7 +52,7 @@ func TestPublisher(t *testing.T) {
 	routingKey := "test-publish"
 	conn.BindQueue(config, queue, routingKey)
 
-	err = manager.Publisher.Publish([]byte("hello"), routingKey, config.ExchangeName, "test", "msg")
+	err = manager.Publisher.Publish(context.Background(), []byte("hello"), routingKey, config.ExchangeName, "test", "msg")
 	if err != nil {
 		t.Fatal("cannot publish message")
 	}
@@ -61,3 +64,55 @@ func TestPublisher(t *testing.T) {
 		t.Fatal("didn't receive any delivery during 1 second")
 	}
 }
+
+func TestPublisherWithConfirmationReportsUnroutable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("do not run containers in short run")
+		return
+	}
+	config := rabbitmq.StartMq(t, "test")
+	config.PublisherConfirmation = true
+	config.PublisherConfirmationTimeout = 1000
+
+	manager, err := NewConnectionManager(config, "test", publisherLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go manager.ListenForBlockingNotifications()
+	defer manager.Close()
+	conn, err := rabbitmq.RawAmqp(t, config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	queue := conn.CreateQueue("test")
+	routingKey := "test-publish"
+	conn.BindQueue(config, queue, routingKey)
+
+	err = manager.Publisher.Publish(context.Background(), []byte("hello"), routingKey, config.ExchangeName, "test", "msg")
+	assert.NoError(t, err, "routable publication must be confirmed")
+
+	err = manager.Publisher.Publish(context.Background(), []byte("hello"), "unknown", config.ExchangeName, "test", "msg")
+	var unroutable *connCfg.UnroutableError
+	if assert.ErrorAs(t, err, &unroutable) {
+		assert.Equal(t, "unknown", unroutable.RoutingKey)
+	}
+}
+
+func TestConfirmedChannelIgnoresLateReturnOfPreviousPublication(t *testing.T) {
+	state := &confirmedChannel{returns: make(chan amqp.Return, returnsBufferSize)}
+	state.returns <- amqp.Return{
+		RoutingKey: "timed-out",
+		Headers:    withPublication(nil, 1),
+	}
+
+	_, found := state.takeReturn(2)
+	assert.False(t, found, "return of the previous publication must not be attributed to the current one")
+
+	state.returns <- amqp.Return{RoutingKey: "late", Headers: withPublication(nil, 2)}
+	state.returns <- amqp.Return{RoutingKey: "current", Headers: withPublication(amqp.Table{"key": "value"}, 3)}
+	returned, found := state.takeReturn(3)
+	assert.True(t, found)
+	assert.Equal(t, "current", returned.RoutingKey)
+	assert.Equal(t, "value", returned.Headers["key"])
+}
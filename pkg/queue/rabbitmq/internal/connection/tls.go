@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package connection
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+)
+
+// buildURL creates the connection URL with escaped credentials and virtual host
+func buildURL(configuration connection.Config) string {
+	scheme := "amqp"
+	if configuration.TLS != nil {
+		scheme = "amqps"
+	}
+	result := url.URL{
+		Scheme:  scheme,
+		Host:    net.JoinHostPort(configuration.Host, strconv.Itoa(configuration.Port)),
+		Path:    "/" + configuration.VHost,
+		RawPath: "/" + url.PathEscape(configuration.VHost),
+	}
+	if configuration.Username != "" || configuration.Password != "" {
+		result.User = url.UserPassword(configuration.Username, configuration.Password)
+	}
+	return result.String()
+}
+
+func newAmqpConfig(name string, configuration connection.Config) (amqp.Config, error) {
+	properties := amqp.NewConnectionProperties()
+	properties.SetClientConnectionName(name)
+	amqpConfig := amqp.Config{
+		Heartbeat:  30 * time.Second,
+		Locale:     "en_US",
+		Properties: properties,
+	}
+	switch strings.ToUpper(configuration.AuthMechanism) {
+	case "", connection.PlainAuthMechanism:
+	case connection.ExternalAuthMechanism:
+		if configuration.TLS == nil || configuration.TLS.ClientCertificate == "" {
+			return amqp.Config{}, errors.New("EXTERNAL auth mechanism requires TLS with client certificate")
+		}
+		amqpConfig.SASL = []amqp.Authentication{&amqp.ExternalAuth{}}
+	default:
+		return amqp.Config{}, fmt.Errorf("unsupported auth mechanism '%s'", configuration.AuthMechanism)
+	}
+	if configuration.TLS != nil {
+		tlsConfig, err := newTLSConfig(configuration.Host, configuration.TLS)
+		if err != nil {
+			return amqp.Config{}, err
+		}
+		amqpConfig.TLSClientConfig = tlsConfig
+	}
+	return amqpConfig, nil
+}
+
+func newTLSConfig(host string, configuration *connection.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         configuration.ServerName,
+		InsecureSkipVerify: configuration.InsecureSkipVerify,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	if configuration.CACertificate != "" {
+		caData, err := os.ReadFile(configuration.CACertificate)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in %s", configuration.CACertificate)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (configuration.ClientCertificate == "") != (configuration.ClientKey == "") {
+		return nil, errors.New("both client certificate and client key must be set")
+	}
+	if configuration.ClientCertificate != "" {
+		certificate, err := tls.LoadX509KeyPair(configuration.ClientCertificate, configuration.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
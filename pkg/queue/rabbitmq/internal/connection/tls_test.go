@@ -0,0 +1,190 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package connection
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+)
+
+func TestBuildURLEscapesCredentialsAndVHost(t *testing.T) {
+	config := connCfg.Config{
+		Host:     "localhost",
+		Port:     5672,
+		Username: "us@r",
+		Password: "p:ss/w%rd",
+		VHost:    "/",
+	}
+	uri, err := amqp.ParseURI(buildURL(config))
+	require.NoError(t, err)
+	assert.Equal(t, "amqp", uri.Scheme)
+	assert.Equal(t, "us@r", uri.Username)
+	assert.Equal(t, "p:ss/w%rd", uri.Password)
+	assert.Equal(t, "/", uri.Vhost)
+
+	config.VHost = "th2 vhost"
+	config.TLS = &connCfg.TLSConfig{}
+	uri, err = amqp.ParseURI(buildURL(config))
+	require.NoError(t, err)
+	assert.Equal(t, "amqps", uri.Scheme)
+	assert.Equal(t, "th2 vhost", uri.Vhost)
+}
+
+func TestExternalAuthRequiresClientCertificate(t *testing.T) {
+	_, err := newAmqpConfig("test", connCfg.Config{AuthMechanism: "EXTERNAL"})
+	assert.Error(t, err)
+	_, err = newAmqpConfig("test", connCfg.Config{AuthMechanism: "UNKNOWN"})
+	assert.Error(t, err)
+}
+
+type testCertificates struct {
+	caFile     string
+	caPool     *x509.CertPool
+	serverCert tls.Certificate
+	clientCert string
+	clientKey  string
+}
+
+func TestDialPresentsClientCertificateOverTLS(t *testing.T) {
+	certs := createCertificates(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certs.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certs.caPool,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type handshake struct {
+		header     []byte
+		clientName string
+	}
+	received := make(chan handshake, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 8)
+		// the stand-in only checks the TLS handshake and the AMQP protocol header
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		state := conn.(*tls.Conn).ConnectionState()
+		received <- handshake{header: header, clientName: state.PeerCertificates[0].Subject.CommonName}
+	}()
+
+	config := connCfg.Config{
+		Host:          "localhost",
+		Port:          listener.Addr().(*net.TCPAddr).Port,
+		VHost:         "th2",
+		AuthMechanism: connCfg.ExternalAuthMechanism,
+		TLS: &connCfg.TLSConfig{
+			CACertificate:     certs.caFile,
+			ClientCertificate: certs.clientCert,
+			ClientKey:         certs.clientKey,
+		},
+	}
+	amqpConfig, err := newAmqpConfig("test", config)
+	require.NoError(t, err)
+	_, err = dial(buildURL(config), amqpConfig)
+	assert.Error(t, err, "stand-in does not implement AMQP")
+
+	select {
+	case h := <-received:
+		assert.Equal(t, []byte("AMQP\x00\x00\x09\x01"), h.header)
+		assert.Equal(t, "th2-client", h.clientName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("TLS handshake was not completed")
+	}
+}
+
+func createCertificates(t *testing.T) testCertificates {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "th2-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+	writePEM := func(name string, blockType string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0o600))
+		return path
+	}
+
+	serverDER, serverKey := issue(2, "localhost", x509.ExtKeyUsageServerAuth)
+	clientDER, clientKey := issue(3, "th2-client", x509.ExtKeyUsageClientAuth)
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	return testCertificates{
+		caFile: writePEM("ca.pem", "CERTIFICATE", caDER),
+		caPool: caPool,
+		serverCert: tls.Certificate{
+			Certificate: [][]byte{serverDER},
+			PrivateKey:  serverKey,
+		},
+		clientCert: writePEM("client.pem", "CERTIFICATE", clientDER),
+		clientKey:  writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER),
+	}
+}
@@ -17,6 +17,7 @@
 package event
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -26,18 +27,20 @@ import (
 	"github.com/th2-net/th2-common-go/pkg/queue"
 	"github.com/th2-net/th2-common-go/pkg/queue/common"
 	"github.com/th2-net/th2-common-go/pkg/queue/event"
+	"github.com/th2-net/th2-common-go/pkg/queue/filter"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 )
 
 type CommonEventRouter struct {
-	connManager *connection.Manager
-	subscribers map[string]internal.Subscriber
-	senders     map[string]*CommonEventSender
-	config      *queue.RouterConfig
-	Logger      zerolog.Logger
-	mutex       *sync.RWMutex
+	connManager    *connection.Manager
+	subscribers    map[string]internal.Subscriber
+	senders        map[string]*CommonEventSender
+	filterStrategy filter.EventStrategy
+	config         *queue.RouterConfig
+	Logger         zerolog.Logger
+	mutex          *sync.RWMutex
 }
 
 func NewRouter(
@@ -46,12 +49,13 @@ func NewRouter(
 	logger zerolog.Logger,
 ) *CommonEventRouter {
 	return &CommonEventRouter{
-		connManager: manager,
-		subscribers: make(map[string]internal.Subscriber),
-		senders:     make(map[string]*CommonEventSender),
-		config:      config,
-		Logger:      logger,
-		mutex:       &sync.RWMutex{},
+		connManager:    manager,
+		subscribers:    make(map[string]internal.Subscriber),
+		senders:        make(map[string]*CommonEventSender),
+		filterStrategy: filter.DefaultEvent,
+		config:         config,
+		Logger:         logger,
+		mutex:          &sync.RWMutex{},
 	}
 }
 
@@ -60,16 +64,26 @@ func (cer *CommonEventRouter) Close() error {
 }
 
 func (cer *CommonEventRouter) SendAll(EventBatch *p_buff.EventBatch, attributes ...string) error {
-	pinsFoundByAttrs := common.FindSendEventQueuesByAttr(cer.config, attributes)
+	return cer.SendAllCtx(context.Background(), EventBatch, attributes...)
+}
+
+func (cer *CommonEventRouter) SendAllCtx(ctx context.Context, EventBatch *p_buff.EventBatch, attributes ...string) error {
+	pinsFoundByAttrs := common.FindSendEventQueuesByAttr(cer.getConfig(), attributes)
 	if len(pinsFoundByAttrs) == 0 {
 		cer.Logger.Error().
 			Any("attributes", attributes).
 			Msg("No such queue to send message")
 		return fmt.Errorf("no pin found for specified attributes: %v", attributes)
 	}
-	for pin, _ := range pinsFoundByAttrs {
+	for pin, config := range pinsFoundByAttrs {
+		if !cer.filterStrategy.Verify(EventBatch, config.Filters) {
+			cer.Logger.Debug().
+				Str("Pin", pin).
+				Msg("Event batch didn't match filter")
+			continue
+		}
 		sender := cer.getSender(pin)
-		err := sender.Send(EventBatch)
+		err := sender.Send(ctx, EventBatch)
 		if err != nil {
 			cer.Logger.Error().Err(err).Send()
 			return err
@@ -80,7 +94,11 @@ func (cer *CommonEventRouter) SendAll(EventBatch *p_buff.EventBatch, attributes
 }
 
 func (cer *CommonEventRouter) SubscribeAll(listener event.Listener, attributes ...string) (queue.Monitor, error) {
-	pinsFoundByAttrs := common.FindSubscribeEventQueuesByAttr(cer.config, attributes)
+	return cer.SubscribeAllCtx(context.Background(), listener, attributes...)
+}
+
+func (cer *CommonEventRouter) SubscribeAllCtx(ctx context.Context, listener event.Listener, attributes ...string) (queue.Monitor, error) {
+	pinsFoundByAttrs := common.FindSubscribeEventQueuesByAttr(cer.getConfig(), attributes)
 	if len(pinsFoundByAttrs) == 0 {
 		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
 	}
@@ -93,7 +111,7 @@ func (cer *CommonEventRouter) SubscribeAll(listener event.Listener, attributes .
 	if len(subscribers) == 0 {
 		return nil, errors.New("no such subscriber")
 	}
-	err = internal.StartAll(subscribers, &cer.Logger)
+	err = internal.StartAll(ctx, subscribers, &cer.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +119,11 @@ func (cer *CommonEventRouter) SubscribeAll(listener event.Listener, attributes .
 }
 
 func (cer *CommonEventRouter) SubscribeAllWithManualAck(listener event.ConformationListener, attributes ...string) (queue.Monitor, error) {
-	pinFoundByAttrs := common.FindSubscribeEventQueuesByAttr(cer.config, attributes)
+	return cer.SubscribeAllWithManualAckCtx(context.Background(), listener, attributes...)
+}
+
+func (cer *CommonEventRouter) SubscribeAllWithManualAckCtx(ctx context.Context, listener event.ConformationListener, attributes ...string) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeEventQueuesByAttr(cer.getConfig(), attributes)
 	if len(pinFoundByAttrs) == 0 {
 		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
 	}
@@ -114,7 +136,7 @@ func (cer *CommonEventRouter) SubscribeAllWithManualAck(listener event.Conformat
 	if len(subscribers) == 0 {
 		return nil, errors.New("no such subscriber")
 	}
-	err = internal.StartAll(subscribers, &cer.Logger)
+	err = internal.StartAll(ctx, subscribers, &cer.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +183,7 @@ func (cer *CommonEventRouter) subByPinWithAck(listener event.ConformationListene
 }
 
 func (cer *CommonEventRouter) getSubscriber(pin string, subscriberType internal.SubscriberType) (internal.Subscriber, error) {
-	queueConfig := cer.config.Queues[pin] // get queue by pin
+	queueConfig := cer.getConfig().Queues[pin] // get queue by pin
 	var result internal.Subscriber
 	result = cer.findSubscriber(pin)
 	if result != nil {
@@ -171,7 +193,8 @@ func (cer *CommonEventRouter) getSubscriber(pin string, subscriberType internal.
 	defer cer.mutex.Unlock()
 
 	// check if someone already created the subscriber in a different goroutine
-	if existing, ok := cer.subscribers[pin]; ok {
+	// the closed subscriber is replaced to allow subscribing to the pin again
+	if existing, ok := cer.subscribers[pin]; ok && !existing.IsClosed() {
 		return existing, nil
 	}
 	result, err := newSubscriber(cer.connManager, &queueConfig, pin, subscriberType)
@@ -187,14 +210,14 @@ func (cer *CommonEventRouter) findSubscriber(pin string) internal.Subscriber {
 	cer.mutex.RLock()
 	defer cer.mutex.RUnlock()
 
-	if existing, ok := cer.subscribers[pin]; ok {
+	if existing, ok := cer.subscribers[pin]; ok && !existing.IsClosed() {
 		return existing
 	}
 	return nil
 }
 
 func (cer *CommonEventRouter) getSender(pin string) *CommonEventSender {
-	queueConfig := cer.config.Queues[pin] // get queue by pin
+	queueConfig := cer.getConfig().Queues[pin] // get queue by pin
 	var result *CommonEventSender
 	result = cer.findSender(pin)
 	if result != nil {
@@ -224,3 +247,20 @@ func (cer *CommonEventRouter) findSender(pin string) *CommonEventSender {
 	}
 	return nil
 }
+
+func (cer *CommonEventRouter) getConfig() *queue.RouterConfig {
+	cer.mutex.RLock()
+	defer cer.mutex.RUnlock()
+	return cer.config
+}
+
+// UpdateConfig applies the new pins configuration. Senders of removed or changed pins are created again on the next use.
+// Subscriptions of removed pins are stopped, subscriptions of changed pins are started again with the new configuration.
+func (cer *CommonEventRouter) UpdateConfig(config *queue.RouterConfig) {
+	cer.mutex.Lock()
+	update := internal.ForgetChangedPins(cer.config, config, cer.senders, cer.subscribers, &cer.Logger)
+	cer.config = config
+	cer.mutex.Unlock()
+	update.Apply()
+	cer.Logger.Info().Msg("configuration updated")
+}
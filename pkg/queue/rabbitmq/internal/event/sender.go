@@ -17,13 +17,13 @@
 package event
 
 import (
+	"context"
 	"errors"
 
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 
 	"github.com/th2-net/th2-common-go/pkg/metrics"
@@ -34,7 +34,7 @@ var (
 	errNullMsg = errors.New("null value for sending")
 )
 
-var th2EventPublishTotal = promauto.NewCounterVec(
+var th2EventPublishTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_event_publish_total",
 		Help: "Quantity of outgoing events",
@@ -51,7 +51,7 @@ type CommonEventSender struct {
 	Logger zerolog.Logger
 }
 
-func (sender *CommonEventSender) Send(batch *p_buff.EventBatch) error {
+func (sender *CommonEventSender) Send(ctx context.Context, batch *p_buff.EventBatch) error {
 
 	if batch == nil {
 		sender.Logger.Error().
@@ -70,7 +70,7 @@ func (sender *CommonEventSender) Send(batch *p_buff.EventBatch) error {
 		return err
 	}
 
-	fail := sender.ConnManager.Publisher.Publish(body, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.EventTh2Type)
+	fail := sender.ConnManager.Publisher.Publish(ctx, body, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.EventTh2Type)
 	if fail != nil {
 		return fail
 	}
@@ -21,17 +21,20 @@ import (
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/filter"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/th2-net/th2-common-go/pkg/metrics"
 	"github.com/th2-net/th2-common-go/pkg/queue/event"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -39,7 +42,7 @@ var (
 	errNoListener = errors.New("no listener to handle delivery")
 )
 
-var th2EventSubscribeTotal = promauto.NewCounterVec(
+var th2EventSubscribeTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_event_subscribe_total",
 		Help: "Amount of events received",
@@ -54,7 +57,7 @@ func newSubscriber(
 	subscriberType internal.SubscriberType,
 ) (internal.Subscriber, error) {
 	logger := log.ForComponent("rabbitmq_event_subscriber")
-	baseHandler := baseEventHandler{&logger, pinName}
+	baseHandler := baseEventHandler{logger: &logger, th2Pin: pinName, filters: config.Filters}
 	switch subscriberType {
 	case internal.AutoSubscriberType:
 		return internal.NewAutoSubscriber(
@@ -63,6 +66,7 @@ func newSubscriber(
 			pinName,
 			&autoEventHandler{baseEventHandler: baseHandler},
 			metrics.EventTh2Type,
+			parentEventKey,
 		), nil
 	case internal.ManualSubscriberType:
 		return internal.NewManualSubscriber(
@@ -71,14 +75,60 @@ func newSubscriber(
 			pinName,
 			&confirmationEventHandler{baseEventHandler: baseHandler},
 			metrics.EventTh2Type,
+			parentEventKey,
 		), nil
 	}
 	return nil, fmt.Errorf("unsupported subscriber type: %d", subscriberType)
 }
 
+var (
+	// batchParentIDPath is the path to EventBatch.parent_event_id.id
+	batchParentIDPath = [][]protowire.Number{{1}}
+	// eventParentIDPath is the path to the parent ID of the first event in EventBatch: events -> parent_id
+	eventParentIDPath = [][]protowire.Number{{2}, {2}}
+	// eventIDPath is the path to EventID.id
+	eventIDPath = [][]protowire.Number{{1}}
+)
+
+// parentEventKey returns the parent event ID of the batch or of its first event.
+// It reads only the fields on the path to the ID so the batch is decoded once by the handler
+func parentEventKey(delivery amqp.Delivery) string {
+	parentID, ok := internal.FirstEmbeddedField(delivery.Body, batchParentIDPath...)
+	if !ok {
+		if parentID, ok = internal.FirstEmbeddedField(delivery.Body, eventParentIDPath...); !ok {
+			return ""
+		}
+	}
+	id, _ := internal.FirstEmbeddedField(parentID, eventIDPath...)
+	return string(id)
+}
+
 type baseEventHandler struct {
-	logger *zerolog.Logger
-	th2Pin string
+	logger  *zerolog.Logger
+	th2Pin  string
+	filters []queue.FilterConfiguration
+}
+
+// SetFilters replaces the filters of the pin. It is called when the subscriber doesn't consume deliveries
+func (cs *baseEventHandler) SetFilters(filters []queue.FilterConfiguration) {
+	cs.filters = filters
+}
+
+// selectEvents removes the events that don't match the filters of the pin.
+// It returns false if no event matches, so the batch must not be passed to the listener
+func (cs *baseEventHandler) selectEvents(batch *p_buff.EventBatch) (*p_buff.EventBatch, bool) {
+	if len(cs.filters) == 0 || len(batch.Events) == 0 {
+		return batch, true
+	}
+	selected := filter.SelectEvents(filter.DefaultEvent, batch, cs.filters)
+	if len(selected.Events) == 0 {
+		cs.logger.Debug().
+			Str("Pin", cs.th2Pin).
+			Int("events", len(batch.Events)).
+			Msg("Event batch didn't match filters")
+		return nil, false
+	}
+	return selected, true
 }
 
 type autoEventHandler struct {
@@ -129,8 +179,14 @@ func (cs *autoEventHandler) Handle(msgDelivery amqp.Delivery) error {
 		return err
 	}
 	th2EventSubscribeTotal.WithLabelValues(cs.th2Pin).Add(float64(len(result.Events)))
-	delivery := queue.Delivery{Redelivered: msgDelivery.Redelivered}
+	result, matched := cs.selectEvents(result)
+	if !matched {
+		return nil
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
 	handleErr := listener.Handle(delivery, result)
+	tracing.EndSpan(span, handleErr)
 	if handleErr != nil {
 		cs.logger.Error().Err(handleErr).
 			Str("routingKey", msgDelivery.RoutingKey).
@@ -159,11 +215,18 @@ func (cs *confirmationEventHandler) Handle(msgDelivery amqp.Delivery, timer *pro
 		return err
 	}
 	th2EventSubscribeTotal.WithLabelValues(cs.th2Pin).Add(float64(len(result.Events)))
-	delivery := queue.Delivery{Redelivered: msgDelivery.Redelivered}
 	deliveryConfirm := internal.DeliveryConfirmation{Delivery: &msgDelivery, Logger: log.ForComponent("confirmation"), Timer: timer}
+	result, matched := cs.selectEvents(result)
+	if !matched {
+		// the listener does not receive the batch, so it cannot confirm it
+		return deliveryConfirm.Confirm()
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
 	var confirmation queue.Confirmation = &deliveryConfirm
 
 	handleErr := listener.Handle(delivery, result, confirmation)
+	tracing.EndSpan(span, handleErr)
 	if handleErr != nil {
 		cs.logger.Error().Err(handleErr).Msg("Can't Handle")
 		return handleErr
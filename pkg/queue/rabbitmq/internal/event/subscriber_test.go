@@ -0,0 +1,62 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParentEventKey(t *testing.T) {
+	eventID := func(id string) *p_buff.EventID {
+		return &p_buff.EventID{Id: id, BookName: "book", Scope: "scope"}
+	}
+
+	for name, tc := range map[string]struct {
+		batch    *p_buff.EventBatch
+		expected string
+	}{
+		"batch parent": {
+			batch: &p_buff.EventBatch{
+				ParentEventId: eventID("batch-parent"),
+				Events:        []*p_buff.Event{{Id: eventID("event"), ParentId: eventID("event-parent")}},
+			},
+			expected: "batch-parent",
+		},
+		"first event parent": {
+			batch: &p_buff.EventBatch{Events: []*p_buff.Event{
+				{Id: eventID("first"), Name: "first", ParentId: eventID("first-parent")},
+				{Id: eventID("second"), ParentId: eventID("second-parent")},
+			}},
+			expected: "first-parent",
+		},
+		"no parent": {
+			batch:    &p_buff.EventBatch{Events: []*p_buff.Event{{Id: eventID("event")}}},
+			expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, err := proto.Marshal(tc.batch)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, parentEventKey(amqp.Delivery{Body: body}))
+		})
+	}
+}
@@ -0,0 +1,407 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/filter"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultMaxBatchSize  = 1024 * 1024
+	defaultFlushInterval = 100 * time.Millisecond
+	// defaultMaxPendingBatches is the default max size of the accumulated groups in batches of the max size
+	defaultMaxPendingBatches = 16
+	// groupsFieldNumber is the number of the groups field in MessageGroupBatch
+	groupsFieldNumber = 1
+)
+
+var errSenderClosed = errors.New("sender is closed")
+
+var th2MessageBatchSizeBytes = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "th2_message_batch_size_bytes",
+		Help:    "Size in bytes of batches accumulated by batching senders",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+	},
+	[]string{metrics.DefaultTh2PinLabelName},
+)
+
+var th2MessageBatchGroups = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "th2_message_batch_groups",
+		Help:    "Quantity of groups in batches accumulated by batching senders",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	},
+	[]string{metrics.DefaultTh2PinLabelName},
+)
+
+// streamKey identifies groups that must be sent in the same order as they were added
+type streamKey struct {
+	book         string
+	sessionAlias string
+	direction    p_buff.Direction
+}
+
+type pendingBatch struct {
+	groups []*p_buff.MessageGroup
+	sizes  []int
+	size   int
+}
+
+// batchingSender accumulates groups with the same session alias and direction
+// and sends them when the size limits are reached or the flush interval expires.
+// Batches are published outside the lock one at a time, so groups can be added while a batch is being published.
+// The groups of a batch that cannot be published are kept in front of the accumulated ones and the failure is returned
+// by the following sends until all the kept groups are sent by the timer. Sends wait while the size of the accumulated groups
+// exceeds the limit.
+type batchingSender struct {
+	delegate        *CommonMessageSender
+	maxBatchSize    int
+	maxGroups       int
+	maxPendingBytes int
+
+	mutex sync.Mutex
+	// changed is signalled when a publication completes, accumulated groups are sent or the sender is closed
+	changed     *sync.Cond
+	publishing  bool
+	pending     map[streamKey]*pendingBatch
+	pendingSize int
+	failure     error
+	closed      bool
+	done        chan struct{}
+	stopped     chan struct{}
+
+	logger zerolog.Logger
+}
+
+func newBatchingSender(delegate *CommonMessageSender, config *queue.BatchingConfig) *batchingSender {
+	sender := &batchingSender{
+		delegate:        delegate,
+		maxBatchSize:    config.MaxBatchSize,
+		maxGroups:       config.MaxGroups,
+		maxPendingBytes: config.MaxPendingBytes,
+		pending:         make(map[streamKey]*pendingBatch),
+		done:            make(chan struct{}),
+		stopped:         make(chan struct{}),
+		logger:          delegate.Logger.With().Str("pin", delegate.th2Pin).Logger(),
+	}
+	sender.changed = sync.NewCond(&sender.mutex)
+	if sender.maxBatchSize <= 0 {
+		sender.maxBatchSize = defaultMaxBatchSize
+	}
+	if sender.maxPendingBytes <= 0 {
+		sender.maxPendingBytes = defaultMaxPendingBatches * sender.maxBatchSize
+	}
+	interval := time.Duration(config.FlushInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	go sender.flushPeriodically(interval)
+	return sender
+}
+
+// Send accumulates the groups of the batch. An error means that the groups are not accepted:
+// the sender is closed, the context is done while waiting for the space for the groups
+// or the groups accepted before cannot be sent
+func (sender *batchingSender) Send(ctx context.Context, batch *p_buff.MessageGroupBatch) error {
+	if batch == nil {
+		return NullValue
+	}
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	if err := sender.checkAccepting(ctx); err != nil {
+		return err
+	}
+	if _, ok := queue.PropertiesFromContext(ctx); ok {
+		return sender.sendDirectly(ctx, batch)
+	}
+	sizes := make([]int, len(batch.Groups))
+	batchSize := 0
+	for index, group := range batch.Groups {
+		sizes[index] = protowire.SizeTag(groupsFieldNumber) + protowire.SizeBytes(proto.Size(group))
+		batchSize += sizes[index]
+	}
+	// a batch greater than the limit is accepted when nothing is accumulated
+	err := sender.await(ctx, func() bool {
+		return sender.closed || sender.failure != nil ||
+			sender.pendingSize == 0 || sender.pendingSize+batchSize <= sender.maxPendingBytes
+	})
+	if err != nil {
+		return err
+	}
+	if err := sender.checkAccepting(ctx); err != nil {
+		return err
+	}
+
+	var ready []streamKey
+	for index, group := range batch.Groups {
+		key := keyOf(group)
+		pending := sender.pendingOf(key)
+		pending.groups = append(pending.groups, group)
+		pending.sizes = append(pending.sizes, sizes[index])
+		pending.size += sizes[index]
+		if sender.isFull(pending) && !slices.Contains(ready, key) {
+			ready = append(ready, key)
+		}
+	}
+	sender.pendingSize += batchSize
+	for _, key := range ready {
+		// the groups are accepted, so the failure is returned by the next send
+		// and the groups left after the context is done are sent by the timer
+		if err := sender.flush(ctx, key, false); err != nil {
+			if ctx.Err() == nil {
+				sender.logger.Error().Err(err).Msg("cannot send accumulated batch, the groups are kept to be sent later")
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// checkAccepting must be called under the sender lock
+func (sender *batchingSender) checkAccepting(ctx context.Context) error {
+	if sender.closed {
+		return errSenderClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if sender.failure != nil {
+		return fmt.Errorf("accumulated groups are not sent: %w", sender.failure)
+	}
+	return nil
+}
+
+func (sender *batchingSender) isFull(pending *pendingBatch) bool {
+	return pending.size >= sender.maxBatchSize || (sender.maxGroups > 0 && len(pending.groups) >= sender.maxGroups)
+}
+
+func (sender *batchingSender) pendingOf(key streamKey) *pendingBatch {
+	pending := sender.pending[key]
+	if pending == nil {
+		pending = &pendingBatch{}
+		sender.pending[key] = pending
+	}
+	return pending
+}
+
+// sendDirectly publishes the batch bypassing the accumulation, so its properties are not attached to the groups of other sends.
+// The groups accumulated for the streams of the batch are sent first to keep the order.
+// It must be called under the sender lock
+func (sender *batchingSender) sendDirectly(ctx context.Context, batch *p_buff.MessageGroupBatch) error {
+	for _, group := range batch.Groups {
+		if err := sender.flush(ctx, keyOf(group), true); err != nil {
+			return err
+		}
+	}
+	return sender.publish(ctx, func() error {
+		return sender.delegate.Send(ctx, batch)
+	})
+}
+
+// SendRaw publishes the data after all the accumulated groups, so it doesn't overtake them
+func (sender *batchingSender) SendRaw(ctx context.Context, data []byte) error {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	if err := sender.checkAccepting(ctx); err != nil {
+		return err
+	}
+	if err := sender.flushAll(ctx); err != nil {
+		return err
+	}
+	return sender.publish(ctx, func() error {
+		return sender.delegate.SendRaw(ctx, data)
+	})
+}
+
+// Close sends all accumulated groups
+func (sender *batchingSender) Close() error {
+	sender.mutex.Lock()
+	if sender.closed {
+		sender.mutex.Unlock()
+		return nil
+	}
+	sender.closed = true
+	close(sender.done)
+	sender.changed.Broadcast()
+	sender.mutex.Unlock()
+	<-sender.stopped
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	// a send started before Close can add groups while the lock is released during flush
+	for len(sender.pending) > 0 {
+		if err := sender.flushAll(context.Background()); err != nil {
+			dropped := 0
+			for _, pending := range sender.pending {
+				dropped += len(pending.groups)
+			}
+			clear(sender.pending)
+			sender.pendingSize = 0
+			return fmt.Errorf("%d accumulated groups are not sent: %w", dropped, err)
+		}
+	}
+	return nil
+}
+
+func (sender *batchingSender) flushPeriodically(interval time.Duration) {
+	defer close(sender.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sender.done:
+			return
+		case <-ticker.C:
+			sender.mutex.Lock()
+			err := sender.flushAll(context.Background())
+			sender.mutex.Unlock()
+			if err != nil {
+				sender.logger.Error().Err(err).Msg("cannot send accumulated batch, the groups are kept to be sent later")
+			}
+		}
+	}
+}
+
+// flushAll sends the groups accumulated for all streams and removes the streams without groups.
+// The failure of previous flushes is reset if all the groups are sent.
+// It must be called under the sender lock
+func (sender *batchingSender) flushAll(ctx context.Context) error {
+	for _, key := range slices.Collect(maps.Keys(sender.pending)) {
+		if err := sender.flush(ctx, key, true); err != nil {
+			return err
+		}
+	}
+	for key, pending := range sender.pending {
+		if len(pending.groups) == 0 {
+			delete(sender.pending, key)
+		}
+	}
+	if sender.failure != nil {
+		sender.failure = nil
+		sender.changed.Broadcast()
+	}
+	return nil
+}
+
+// flush sends the groups accumulated for the stream in batches within the size limits.
+// Unless all is set, only the full batches are sent. If the publication fails, the groups are returned
+// in front of the groups accumulated meanwhile and the failure is kept. It must be called under the sender lock
+func (sender *batchingSender) flush(ctx context.Context, key streamKey, all bool) error {
+	for {
+		// the groups are taken when no publication is in progress, so the batches of the stream are published in order
+		if err := sender.await(ctx, func() bool { return !sender.publishing }); err != nil {
+			return err
+		}
+		pending := sender.pending[key]
+		if pending == nil || len(pending.groups) == 0 || (!all && !sender.isFull(pending)) {
+			return nil
+		}
+		count, size := sender.nextBatch(pending)
+		groups, sizes := pending.groups[:count:count], pending.sizes[:count:count]
+		pending.groups, pending.sizes, pending.size = pending.groups[count:], pending.sizes[count:], pending.size-size
+		th2MessageBatchSizeBytes.WithLabelValues(sender.delegate.th2Pin).Observe(float64(size))
+		th2MessageBatchGroups.WithLabelValues(sender.delegate.th2Pin).Observe(float64(count))
+
+		// the groups are accumulated from different sends, so the context of none of them is used
+		err := sender.publish(context.Background(), func() error {
+			return sender.delegate.Send(context.Background(), &p_buff.MessageGroupBatch{Groups: groups})
+		})
+		if err != nil {
+			pending = sender.pendingOf(key)
+			pending.groups = append(groups, pending.groups...)
+			pending.sizes = append(sizes, pending.sizes...)
+			pending.size += size
+			sender.failure = err
+			return fmt.Errorf("cannot send %d groups: %w", count, err)
+		}
+		sender.pendingSize -= size
+	}
+}
+
+// nextBatch returns the number and the size of the first groups that fit the limits of the batch
+func (sender *batchingSender) nextBatch(pending *pendingBatch) (int, int) {
+	count, size := 1, pending.sizes[0]
+	for count < len(pending.groups) && size+pending.sizes[count] <= sender.maxBatchSize &&
+		(sender.maxGroups <= 0 || count < sender.maxGroups) {
+		size += pending.sizes[count]
+		count++
+	}
+	return count, size
+}
+
+// publish calls the send function with the sender lock released.
+// It must be called under the sender lock when no publication is in progress
+func (sender *batchingSender) publish(ctx context.Context, send func() error) error {
+	if err := sender.await(ctx, func() bool { return !sender.publishing }); err != nil {
+		return err
+	}
+	sender.publishing = true
+	sender.mutex.Unlock()
+	defer func() {
+		sender.mutex.Lock()
+		sender.publishing = false
+		sender.changed.Broadcast()
+	}()
+	return send()
+}
+
+// await waits until the condition is met or the context is done. It must be called under the sender lock
+func (sender *batchingSender) await(ctx context.Context, condition func() bool) error {
+	if condition() {
+		return nil
+	}
+	stop := context.AfterFunc(ctx, func() {
+		sender.mutex.Lock()
+		defer sender.mutex.Unlock()
+		sender.changed.Broadcast()
+	})
+	defer stop()
+	for !condition() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sender.changed.Wait()
+	}
+	return nil
+}
+
+func keyOf(group *p_buff.MessageGroup) streamKey {
+	if len(group.Messages) == 0 {
+		return streamKey{}
+	}
+	id := filter.IDFromAnyMsg(group.Messages[0])
+	return streamKey{
+		book:         id.GetBookName(),
+		sessionAlias: id.GetConnectionId().GetSessionAlias(),
+		direction:    id.GetDirection(),
+	}
+}
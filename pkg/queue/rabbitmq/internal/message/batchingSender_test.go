@@ -0,0 +1,239 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package message
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+type testPublisher struct {
+	mutex     sync.Mutex
+	err       error
+	block     chan struct{}
+	published []*p_buff.MessageGroupBatch
+}
+
+func (p *testPublisher) Publish(_ context.Context, body []byte, _ string, _ string, _ string, _ string) error {
+	if p.block != nil {
+		<-p.block
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	batch := &p_buff.MessageGroupBatch{}
+	if err := proto.Unmarshal(body, batch); err != nil {
+		return err
+	}
+	p.published = append(p.published, batch)
+	return nil
+}
+
+func (p *testPublisher) PublishWithHeaders(ctx context.Context, body []byte, _ amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return p.Publish(ctx, body, routingKey, exchange, th2Pin, th2Type)
+}
+
+func (p *testPublisher) PublishConfirmed(ctx context.Context, body []byte, headers amqp.Table, routingKey string, exchange string, th2Pin string, th2Type string) error {
+	return p.PublishWithHeaders(ctx, body, headers, routingKey, exchange, th2Pin, th2Type)
+}
+
+func (p *testPublisher) DeclareDelayQueue(context.Context, string, time.Duration, string) error {
+	return nil
+}
+
+func (p *testPublisher) Close() error {
+	return nil
+}
+
+func (p *testPublisher) setError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.err = err
+}
+
+func (p *testPublisher) batches() []*p_buff.MessageGroupBatch {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]*p_buff.MessageGroupBatch(nil), p.published...)
+}
+
+func newTestBatchingSender(publisher *testPublisher, config queue.BatchingConfig) *batchingSender {
+	config.FlushInterval = 60000
+	return newBatchingSender(&CommonMessageSender{
+		ConnManager: &connection.Manager{Publisher: publisher},
+		th2Pin:      "pin",
+		Logger:      zerolog.Nop(),
+	}, &config)
+}
+
+func groupBatch(sessionAlias string, sequences ...int64) *p_buff.MessageGroupBatch {
+	batch := &p_buff.MessageGroupBatch{}
+	for _, sequence := range sequences {
+		batch.Groups = append(batch.Groups, &p_buff.MessageGroup{
+			Messages: []*p_buff.AnyMessage{{Kind: &p_buff.AnyMessage_RawMessage{RawMessage: &p_buff.RawMessage{
+				Metadata: &p_buff.RawMessageMetadata{Id: &p_buff.MessageID{
+					ConnectionId: &p_buff.ConnectionID{SessionAlias: sessionAlias},
+					Sequence:     sequence,
+				}},
+			}}}},
+		})
+	}
+	return batch
+}
+
+// flushByTimer sends the accumulated groups as the flush timer does
+func flushByTimer(sender *batchingSender) error {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	return sender.flushAll(context.Background())
+}
+
+func sequences(batch *p_buff.MessageGroupBatch) []int64 {
+	var result []int64
+	for _, group := range batch.Groups {
+		result = append(result, group.Messages[0].GetRawMessage().GetMetadata().GetId().GetSequence())
+	}
+	return result
+}
+
+func TestBatchingSenderReportsFailedPublicationToNextSend(t *testing.T) {
+	failure := errors.New("publication failed")
+	publisher := &testPublisher{err: failure}
+	sender := newTestBatchingSender(publisher, queue.BatchingConfig{MaxGroups: 2})
+
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 1)))
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 2)), "accepted groups are sent later")
+	assert.ErrorIs(t, sender.Send(context.Background(), groupBatch("alias", 3)), failure)
+	assert.Empty(t, publisher.batches())
+
+	publisher.setError(nil)
+	assert.NoError(t, flushByTimer(sender))
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 3, 4)))
+	batches := publisher.batches()
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, []int64{1, 2}, sequences(batches[0]))
+		assert.Equal(t, []int64{3, 4}, sequences(batches[1]))
+	}
+
+	publisher.setError(failure)
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 5)))
+	assert.ErrorContains(t, sender.Close(), "1 accumulated groups are not sent")
+}
+
+func TestBatchingSenderSplitsGroupsByLimits(t *testing.T) {
+	publisher := &testPublisher{}
+	sender := newTestBatchingSender(publisher, queue.BatchingConfig{MaxGroups: 2})
+
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 1, 2, 3, 4, 5)))
+	assert.NoError(t, sender.Close())
+	batches := publisher.batches()
+	if assert.Len(t, batches, 3) {
+		assert.Equal(t, []int64{1, 2}, sequences(batches[0]))
+		assert.Equal(t, []int64{3, 4}, sequences(batches[1]))
+		assert.Equal(t, []int64{5}, sequences(batches[2]))
+	}
+}
+
+func TestBatchingSenderWaitsWhilePendingLimitIsExceeded(t *testing.T) {
+	publisher := &testPublisher{block: make(chan struct{})}
+	groupSize := proto.Size(groupBatch("alias", 1))
+	sender := newTestBatchingSender(publisher, queue.BatchingConfig{MaxGroups: 1, MaxPendingBytes: groupSize})
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sender.Send(context.Background(), groupBatch("alias", 1))
+	}()
+	assert.Eventually(t, func() bool {
+		sender.mutex.Lock()
+		defer sender.mutex.Unlock()
+		return sender.publishing
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sender.Send(ctx, groupBatch("alias", 2)), context.DeadlineExceeded,
+		"groups being sent must be counted in the limit")
+
+	close(publisher.block)
+	assert.NoError(t, <-flushed)
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 2)))
+	assert.NoError(t, sender.Close())
+	assert.Len(t, publisher.batches(), 2)
+}
+
+func TestBatchingSenderSendsAccumulatedGroupsBeforeRawData(t *testing.T) {
+	publisher := &testPublisher{}
+	sender := newTestBatchingSender(publisher, queue.BatchingConfig{})
+
+	assert.NoError(t, sender.Send(context.Background(), groupBatch("alias", 1)))
+	data, err := proto.Marshal(groupBatch("alias", 2))
+	assert.NoError(t, err)
+	assert.NoError(t, sender.SendRaw(context.Background(), data))
+	batches := publisher.batches()
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, []int64{1}, sequences(batches[0]))
+		assert.Equal(t, []int64{2}, sequences(batches[1]))
+	}
+	assert.NoError(t, sender.Close())
+}
+
+func TestBatchingSenderAcceptsGroupsDuringPublication(t *testing.T) {
+	publisher := &testPublisher{block: make(chan struct{})}
+	sender := newTestBatchingSender(publisher, queue.BatchingConfig{MaxGroups: 2})
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sender.Send(context.Background(), groupBatch("first", 1, 2))
+	}()
+	assert.Eventually(t, func() bool {
+		sender.mutex.Lock()
+		defer sender.mutex.Unlock()
+		return sender.publishing
+	}, time.Second, time.Millisecond)
+
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- sender.Send(context.Background(), groupBatch("second", 3))
+	}()
+	select {
+	case err := <-accepted:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("sender lock is held during publication")
+	}
+
+	close(publisher.block)
+	assert.NoError(t, <-flushed)
+	assert.NoError(t, sender.Close())
+	if batches := publisher.batches(); assert.Len(t, batches, 2) {
+		assert.Equal(t, []int64{1, 2}, sequences(batches[0]))
+		assert.Equal(t, []int64{3}, sequences(batches[1]))
+	}
+}
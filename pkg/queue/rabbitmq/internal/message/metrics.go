@@ -0,0 +1,48 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package message
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+)
+
+var th2MessageFilteredGroupsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_message_filtered_groups_total",
+		Help: "Quantity of outgoing message groups dropped by filters of publish pins",
+	},
+	[]string{metrics.DefaultTh2PinLabelName},
+)
+
+var collectors = []prometheus.Collector{
+	th2MessagePublishTotal,
+	th2MessageSubscribeTotal,
+	th2MessageBatchSizeBytes,
+	th2MessageBatchGroups,
+	th2MessageFilteredGroupsTotal,
+}
+
+// RegisterMetrics adds the metrics of the package to the registerer
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	return metrics.Register(registerer, collectors...)
+}
+
+// UnregisterMetrics releases the metrics of the package registered by RegisterMetrics
+func UnregisterMetrics(registerer prometheus.Registerer) {
+	metrics.Unregister(registerer, collectors...)
+}
@@ -17,6 +17,7 @@
 package message
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -30,13 +31,15 @@ import (
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
 )
 
 type CommonMessageRouter struct {
 	connManager    *connection.Manager
 	subscribers    map[string]internal.Subscriber
-	senders        map[string]*CommonMessageSender
+	senders        map[string]messageSender
 	filterStrategy filter.Strategy
+	filterMode     queue.FilterMode
 	config         *queue.RouterConfig
 	Logger         zerolog.Logger
 	mutex          *sync.RWMutex
@@ -50,7 +53,7 @@ func NewRouter(
 	return &CommonMessageRouter{
 		connManager:    manager,
 		subscribers:    make(map[string]internal.Subscriber),
-		senders:        make(map[string]*CommonMessageSender),
+		senders:        make(map[string]messageSender),
 		filterStrategy: filter.Default,
 		Logger:         logger,
 		config:         config,
@@ -58,12 +61,26 @@ func NewRouter(
 	}
 }
 
+// Close sends the groups accumulated by batching senders
 func (cmr *CommonMessageRouter) Close() error {
-	return nil
+	cmr.mutex.Lock()
+	defer cmr.mutex.Unlock()
+	var errs []error
+	for pin, sender := range cmr.senders {
+		if err := sender.Close(); err != nil {
+			cmr.Logger.Error().Err(err).Str("Pin", pin).Msg("cannot close sender")
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (cmr *CommonMessageRouter) SendAll(msgBatch *p_buff.MessageGroupBatch, attributes ...string) error {
-	pinsFoundByAttrs := common.FindSendQueuesByAttr(cmr.config, attributes)
+	return cmr.SendAllCtx(context.Background(), msgBatch, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SendAllCtx(ctx context.Context, msgBatch *p_buff.MessageGroupBatch, attributes ...string) error {
+	pinsFoundByAttrs := common.FindSendQueuesByAttr(cmr.getConfig(), attributes)
 	if len(pinsFoundByAttrs) == 0 {
 		cmr.Logger.Error().
 			Strs("attributes", attributes).
@@ -71,7 +88,8 @@ func (cmr *CommonMessageRouter) SendAll(msgBatch *p_buff.MessageGroupBatch, attr
 		return fmt.Errorf("no pin found for specified attributes: %v", attributes)
 	}
 	for pin, config := range pinsFoundByAttrs {
-		if !cmr.filterStrategy.Verify(msgBatch, config.Filters) {
+		selected := cmr.selectGroups(pin, msgBatch, config.Filters)
+		if selected == nil {
 			if e := cmr.Logger.Debug(); e.Enabled() {
 				e.Str("Pin", pin).
 					Interface("Metadata", filter.FirstIDFromMsgBatch(msgBatch)).
@@ -81,18 +99,18 @@ func (cmr *CommonMessageRouter) SendAll(msgBatch *p_buff.MessageGroupBatch, attr
 		}
 		if e := cmr.Logger.Debug(); e.Enabled() {
 			e.Str("Pin", pin).
-				Interface("Metadata", filter.FirstIDFromMsgBatch(msgBatch)).
+				Interface("Metadata", filter.FirstIDFromMsgBatch(selected)).
 				Msg("First ID of message batch matched filter")
 		}
 		sender := cmr.getSender(pin)
-		err := sender.Send(msgBatch)
+		err := sender.Send(ctx, selected)
 		if err != nil {
 			cmr.Logger.Error().Err(err).Send()
 			return err
 		}
 		if e := cmr.Logger.Debug(); e.Enabled() {
 			e.Str("sending to pin", pin).
-				Interface("Metadata", filter.FirstIDFromMsgBatch(msgBatch)).
+				Interface("Metadata", filter.FirstIDFromMsgBatch(selected)).
 				Msg("First ID of sent Message batch")
 		}
 	}
@@ -100,13 +118,43 @@ func (cmr *CommonMessageRouter) SendAll(msgBatch *p_buff.MessageGroupBatch, attr
 }
 
 func (cmr *CommonMessageRouter) SendRawAll(rawData []byte, attributes ...string) error {
-	pinsFoundByAttrs := common.FindSendQueuesByAttr(cmr.config, attributes)
+	return cmr.SendRawAllCtx(context.Background(), rawData, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SendRawAllCtx(ctx context.Context, rawData []byte, attributes ...string) error {
+	pinsFoundByAttrs := common.FindSendQueuesByAttr(cmr.getConfig(), attributes)
 	if len(pinsFoundByAttrs) == 0 {
 		return fmt.Errorf("no pin found for specified attributes: %v", attributes)
 	}
-	for pin, _ := range pinsFoundByAttrs {
+	var msgBatch *p_buff.MessageGroupBatch
+	for pin, config := range pinsFoundByAttrs {
+		data := rawData
+		if len(config.Filters) > 0 {
+			// the data is decoded only if it has to be filtered
+			if msgBatch == nil {
+				msgBatch = &p_buff.MessageGroupBatch{}
+				if err := proto.Unmarshal(rawData, msgBatch); err != nil {
+					return fmt.Errorf("cannot decode raw data to apply filters of pin %s: %w", pin, err)
+				}
+			}
+			selected := cmr.selectGroups(pin, msgBatch, config.Filters)
+			if selected == nil {
+				if e := cmr.Logger.Debug(); e.Enabled() {
+					e.Str("Pin", pin).
+						Interface("Metadata", filter.FirstIDFromMsgBatch(msgBatch)).
+						Msg("First ID of raw message batch didn't match filter")
+				}
+				continue
+			}
+			if selected != msgBatch {
+				var err error
+				if data, err = proto.Marshal(selected); err != nil {
+					return fmt.Errorf("cannot encode groups selected by filters of pin %s: %w", pin, err)
+				}
+			}
+		}
 		sender := cmr.getSender(pin)
-		err := sender.SendRaw(rawData)
+		err := sender.SendRaw(ctx, data)
 		if err != nil {
 			return err
 		}
@@ -116,7 +164,11 @@ func (cmr *CommonMessageRouter) SendRawAll(rawData []byte, attributes ...string)
 }
 
 func (cmr *CommonMessageRouter) SubscribeAllWithManualAck(listener message.ConformationListener, attributes ...string) (queue.Monitor, error) {
-	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.config, attributes)
+	return cmr.SubscribeAllWithManualAckCtx(context.Background(), listener, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SubscribeAllWithManualAckCtx(ctx context.Context, listener message.ConformationListener, attributes ...string) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.getConfig(), attributes)
 	if len(pinFoundByAttrs) == 0 {
 		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
 	}
@@ -131,7 +183,7 @@ func (cmr *CommonMessageRouter) SubscribeAllWithManualAck(listener message.Confo
 		return nil, errors.New("no such subscriber")
 	}
 
-	err = cmr.startAll(subscribers)
+	err = cmr.startAll(ctx, subscribers)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +192,11 @@ func (cmr *CommonMessageRouter) SubscribeAllWithManualAck(listener message.Confo
 }
 
 func (cmr *CommonMessageRouter) SubscribeAll(listener message.Listener, attributes ...string) (queue.Monitor, error) {
-	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.config, attributes)
+	return cmr.SubscribeAllCtx(context.Background(), listener, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SubscribeAllCtx(ctx context.Context, listener message.Listener, attributes ...string) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.getConfig(), attributes)
 	if len(pinFoundByAttrs) == 0 {
 		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
 	}
@@ -153,7 +209,7 @@ func (cmr *CommonMessageRouter) SubscribeAll(listener message.Listener, attribut
 	if len(subscribers) == 0 {
 		return nil, errors.New("no such subscriber")
 	}
-	err = cmr.startAll(subscribers)
+	err = cmr.startAll(ctx, subscribers)
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +217,11 @@ func (cmr *CommonMessageRouter) SubscribeAll(listener message.Listener, attribut
 }
 
 func (cmr *CommonMessageRouter) SubscribeRawAll(listener message.RawListener, attributes ...string) (queue.Monitor, error) {
-	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.config, attributes)
+	return cmr.SubscribeRawAllCtx(context.Background(), listener, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SubscribeRawAllCtx(ctx context.Context, listener message.RawListener, attributes ...string) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.getConfig(), attributes)
 	if len(pinFoundByAttrs) == 0 {
 		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
 	}
@@ -174,7 +234,32 @@ func (cmr *CommonMessageRouter) SubscribeRawAll(listener message.RawListener, at
 	if len(subscribers) == 0 {
 		return nil, errors.New("no such subscriber")
 	}
-	err = cmr.startAll(subscribers)
+	err = cmr.startAll(ctx, subscribers)
+	if err != nil {
+		return nil, err
+	}
+	return internal.MultiplySubscribeMonitor{SubscriberMonitors: subscribers}, nil
+}
+
+func (cmr *CommonMessageRouter) SubscribeRawAllWithManualAck(listener message.RawConformationListener, attributes ...string) (queue.Monitor, error) {
+	return cmr.SubscribeRawAllWithManualAckCtx(context.Background(), listener, attributes...)
+}
+
+func (cmr *CommonMessageRouter) SubscribeRawAllWithManualAckCtx(ctx context.Context, listener message.RawConformationListener, attributes ...string) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeQueuesByAttr(cmr.getConfig(), attributes)
+	if len(pinFoundByAttrs) == 0 {
+		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
+	}
+	subscribers, err := cmr.subscribeAll(pinFoundByAttrs, func(router *CommonMessageRouter, pinName string) (internal.SubscriberMonitor, error) {
+		return router.subByPinRawWithAck(listener, pinName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(subscribers) == 0 {
+		return nil, errors.New("no such subscriber")
+	}
+	err = cmr.startAll(ctx, subscribers)
 	if err != nil {
 		return nil, err
 	}
@@ -188,8 +273,8 @@ func (cmr *CommonMessageRouter) subscribeAll(
 	return internal.SubscribeAll(cmr, pinFoundByAttrs, &cmr.Logger, subscribeFunc)
 }
 
-func (cmr *CommonMessageRouter) startAll(subscribers []internal.SubscriberMonitor) error {
-	return internal.StartAll(subscribers, &cmr.Logger)
+func (cmr *CommonMessageRouter) startAll(ctx context.Context, subscribers []internal.SubscriberMonitor) error {
+	return internal.StartAll(ctx, subscribers, &cmr.Logger)
 }
 
 func (cmr *CommonMessageRouter) subByPin(listener message.Listener, pin string) (internal.SubscriberMonitor, error) {
@@ -249,9 +334,28 @@ func (cmr *CommonMessageRouter) subByPinWithAck(listener message.ConformationLis
 	return internal.MonitorFor(subscriber), nil
 }
 
+func (cmr *CommonMessageRouter) subByPinRawWithAck(listener message.RawConformationListener, pin string) (internal.SubscriberMonitor, error) {
+	subscriber, err := cmr.getSubscriber(pin, internal.ManualSubscriberType, rawContentType)
+	if err != nil {
+		return nil, err
+	}
+	manualSubscriber, err := internal.AsManualSubscriber(subscriber, pin)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := manualSubscriber.GetHandler().(*confirmationRawMessageHandler)
+	if !ok {
+		return nil, fmt.Errorf("handler with different type %T is subscribed to pin %s",
+			manualSubscriber.GetHandler(), pin)
+	}
+	handler.SetListener(listener)
+	cmr.Logger.Trace().Str("Pin", pin).Msg("Getting subscriber monitor")
+	return internal.MonitorFor(subscriber), nil
+}
+
 func (cmr *CommonMessageRouter) getSubscriber(pin string, subscriberType internal.SubscriberType, contentType contentType) (internal.Subscriber, error) {
 	// TODO: probably, we should use lock here to make subscriber creation atomic
-	queueConfig := cmr.config.Queues[pin] // get queue by pin
+	queueConfig := cmr.getConfig().Queues[pin] // get queue by pin
 	var result internal.Subscriber
 	result = cmr.findSubscriber(pin)
 	if result != nil {
@@ -261,7 +365,8 @@ func (cmr *CommonMessageRouter) getSubscriber(pin string, subscriberType interna
 	defer cmr.mutex.Unlock()
 
 	// check if someone already created the subscriber in a different goroutine
-	if existing, ok := cmr.subscribers[pin]; ok {
+	// the closed subscriber is replaced to allow subscribing to the pin again
+	if existing, ok := cmr.subscribers[pin]; ok && !existing.IsClosed() {
 		return existing, nil
 	}
 
@@ -279,15 +384,15 @@ func (cmr *CommonMessageRouter) findSubscriber(pin string) internal.Subscriber {
 	cmr.mutex.RLock()
 	defer cmr.mutex.RUnlock()
 
-	if existing, ok := cmr.subscribers[pin]; ok {
+	if existing, ok := cmr.subscribers[pin]; ok && !existing.IsClosed() {
 		return existing
 	}
 	return nil
 }
 
-func (cmr *CommonMessageRouter) getSender(pin string) *CommonMessageSender {
-	queueConfig := cmr.config.Queues[pin] // get queue by pin
-	var result *CommonMessageSender
+func (cmr *CommonMessageRouter) getSender(pin string) messageSender {
+	queueConfig := cmr.getConfig().Queues[pin] // get queue by pin
+	var result messageSender
 
 	result = cmr.findSender(pin)
 	if result != nil {
@@ -302,14 +407,18 @@ func (cmr *CommonMessageRouter) getSender(pin string) *CommonMessageSender {
 		return existing
 	}
 
-	result = &CommonMessageSender{ConnManager: cmr.connManager, exchangeName: queueConfig.Exchange,
+	sender := &CommonMessageSender{ConnManager: cmr.connManager, exchangeName: queueConfig.Exchange,
 		sendQueue: queueConfig.RoutingKey, th2Pin: pin, Logger: log.ForComponent("rabbitmq_message_sender")}
+	result = sender
+	if queueConfig.Batching != nil {
+		result = newBatchingSender(sender, queueConfig.Batching)
+	}
 	cmr.senders[pin] = result
 	cmr.Logger.Trace().Str("Pin", pin).Msg("Created sender")
 	return result
 }
 
-func (cmr *CommonMessageRouter) findSender(pin string) *CommonMessageSender {
+func (cmr *CommonMessageRouter) findSender(pin string) messageSender {
 	cmr.mutex.RLock()
 	defer cmr.mutex.RUnlock()
 
@@ -318,3 +427,56 @@ func (cmr *CommonMessageRouter) findSender(pin string) *CommonMessageSender {
 	}
 	return nil
 }
+
+// SetFilterMode changes the way filters of publish pins are applied to sent batches
+func (cmr *CommonMessageRouter) SetFilterMode(mode queue.FilterMode) {
+	cmr.mutex.Lock()
+	defer cmr.mutex.Unlock()
+	cmr.filterMode = mode
+}
+
+func (cmr *CommonMessageRouter) getFilterMode() queue.FilterMode {
+	cmr.mutex.RLock()
+	defer cmr.mutex.RUnlock()
+	return cmr.filterMode
+}
+
+// selectGroups returns the batch to send via the pin according to the filter mode
+// or nil if nothing has to be sent. The groups dropped by the filters are counted
+func (cmr *CommonMessageRouter) selectGroups(pin string, batch *p_buff.MessageGroupBatch, filters []queue.FilterConfiguration) *p_buff.MessageGroupBatch {
+	if len(filters) == 0 {
+		return batch
+	}
+	if cmr.getFilterMode() != queue.FilterPerGroup {
+		if !cmr.filterStrategy.Verify(batch, filters) {
+			th2MessageFilteredGroupsTotal.WithLabelValues(pin).Add(float64(len(batch.Groups)))
+			return nil
+		}
+		return batch
+	}
+	selected := filter.SelectGroups(cmr.filterStrategy, batch, filters)
+	if dropped := len(batch.Groups) - len(selected.Groups); dropped > 0 {
+		th2MessageFilteredGroupsTotal.WithLabelValues(pin).Add(float64(dropped))
+	}
+	if len(selected.Groups) == 0 {
+		return nil
+	}
+	return selected
+}
+
+func (cmr *CommonMessageRouter) getConfig() *queue.RouterConfig {
+	cmr.mutex.RLock()
+	defer cmr.mutex.RUnlock()
+	return cmr.config
+}
+
+// UpdateConfig applies the new pins configuration. Senders of removed or changed pins are created again on the next use.
+// Subscriptions of removed pins are stopped, subscriptions of changed pins are started again with the new configuration.
+func (cmr *CommonMessageRouter) UpdateConfig(config *queue.RouterConfig) {
+	cmr.mutex.Lock()
+	update := internal.ForgetChangedPins(cmr.config, config, cmr.senders, cmr.subscribers, &cmr.Logger)
+	cmr.config = config
+	cmr.mutex.Unlock()
+	update.Apply()
+	cmr.Logger.Info().Msg("configuration updated")
+}
@@ -17,13 +17,14 @@
 package message
 
 import (
+	"context"
 	"errors"
+	"io"
 
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 
 	"github.com/th2-net/th2-common-go/pkg/metrics"
@@ -34,7 +35,7 @@ var (
 	NullValue = errors.New("null value for sending")
 )
 
-var th2MessagePublishTotal = promauto.NewCounterVec(
+var th2MessagePublishTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_message_publish_total",
 		Help: "Quantity of outgoing messages",
@@ -47,6 +48,12 @@ var th2MessagePublishTotal = promauto.NewCounterVec(
 	},
 )
 
+type messageSender interface {
+	Send(ctx context.Context, batch *p_buff.MessageGroupBatch) error
+	SendRaw(ctx context.Context, data []byte) error
+	io.Closer
+}
+
 type CommonMessageSender struct {
 	ConnManager  *connection.Manager
 	exchangeName string
@@ -56,7 +63,7 @@ type CommonMessageSender struct {
 	Logger zerolog.Logger
 }
 
-func (sender *CommonMessageSender) Send(batch *p_buff.MessageGroupBatch) error {
+func (sender *CommonMessageSender) Send(ctx context.Context, batch *p_buff.MessageGroupBatch) error {
 
 	if batch == nil {
 		return NullValue
@@ -67,7 +74,7 @@ func (sender *CommonMessageSender) Send(batch *p_buff.MessageGroupBatch) error {
 		return err
 	}
 
-	fail := sender.ConnManager.Publisher.Publish(body, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.MessageGroupTh2Type)
+	fail := sender.ConnManager.Publisher.Publish(ctx, body, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.MessageGroupTh2Type)
 	if fail != nil {
 		return fail
 	}
@@ -76,9 +83,13 @@ func (sender *CommonMessageSender) Send(batch *p_buff.MessageGroupBatch) error {
 	return nil
 }
 
-func (sender *CommonMessageSender) SendRaw(data []byte) error {
+func (sender *CommonMessageSender) SendRaw(ctx context.Context, data []byte) error {
 	if data == nil {
 		return errors.New("nil raw data")
 	}
-	return sender.ConnManager.Publisher.Publish(data, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.MessageGroupTh2Type)
+	return sender.ConnManager.Publisher.Publish(ctx, data, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.MessageGroupTh2Type)
+}
+
+func (sender *CommonMessageSender) Close() error {
+	return nil
 }
@@ -21,22 +21,24 @@ import (
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/log"
 	"github.com/th2-net/th2-common-go/pkg/queue"
 	"github.com/th2-net/th2-common-go/pkg/queue/filter"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
 	p_buff "github.com/th2-net/th2-grpc-common-go"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/th2-net/th2-common-go/pkg/metrics"
 	"github.com/th2-net/th2-common-go/pkg/queue/message"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
-var th2MessageSubscribeTotal = promauto.NewCounterVec(
+var th2MessageSubscribeTotal = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "th2_message_subscribe_total",
 		Help: "Quantity of incoming messages",
@@ -54,6 +56,7 @@ type contentType = int
 const (
 	parsedContentType contentType = iota
 	rawContentType
+	transportContentType
 )
 
 func newSubscriber(
@@ -64,7 +67,13 @@ func newSubscriber(
 	contentType contentType,
 ) (internal.Subscriber, error) {
 	logger := log.ForComponent("rabbitmq_message_subscriber")
-	baseHandler := baseMessageHandler{&logger, pinName}
+	baseHandler := baseMessageHandler{logger: &logger, th2Pin: pinName, filters: config.Filters}
+	th2Type := metrics.MessageGroupTh2Type
+	orderingKey := sessionAliasKey
+	if contentType == transportContentType {
+		th2Type = metrics.TransportGroupTh2Type
+		orderingKey = transportSessionAliasKey
+	}
 	switch subscriberType {
 	case internal.AutoSubscriberType:
 		var handler internal.AutoHandler
@@ -77,10 +86,14 @@ func newSubscriber(
 			handler = &rawMessageHandler{
 				baseMessageHandler: baseHandler,
 			}
+		case transportContentType:
+			handler = &transportMessageHandler{
+				baseMessageHandler: baseHandler,
+			}
 		default:
 			return nil, fmt.Errorf("unknown content type: %d", contentType)
 		}
-		return internal.NewAutoSubscriber(manager, config, pinName, handler, metrics.MessageGroupTh2Type), nil
+		return internal.NewAutoSubscriber(manager, config, pinName, handler, th2Type, orderingKey), nil
 	case internal.ManualSubscriberType:
 		var handler internal.ConfirmationHandler
 		switch contentType {
@@ -88,20 +101,82 @@ func newSubscriber(
 			handler = &confirmationMessageHandler{
 				baseMessageHandler: baseHandler,
 			}
+		case transportContentType:
+			handler = &confirmationTransportMessageHandler{
+				baseMessageHandler: baseHandler,
+			}
 		case rawContentType:
-			return nil, errors.New("raw content is not supported for manual subscriber")
+			handler = &confirmationRawMessageHandler{
+				baseMessageHandler: baseHandler,
+			}
 		default:
 			return nil, fmt.Errorf("unknown content type: %d", contentType)
 		}
-		return internal.NewManualSubscriber(manager, config, pinName, handler, metrics.MessageGroupTh2Type), nil
+		return internal.NewManualSubscriber(manager, config, pinName, handler, th2Type, orderingKey), nil
 	default:
 		return nil, fmt.Errorf("unsupported subscriber type: %d", subscriberType)
 	}
 }
 
+// sessionAliasPath is the path to the session alias of the first message in MessageGroupBatch:
+// groups -> messages -> message | raw_message -> metadata -> id -> connection_id -> session_alias
+var sessionAliasPath = [][]protowire.Number{{1}, {1}, {1, 2}, {1}, {1}, {1}, {1}}
+
+// sessionAliasKey returns the session alias of the first message in the batch.
+// It reads only the fields on the path to the alias so the batch is decoded once by the handler
+func sessionAliasKey(delivery amqp.Delivery) string {
+	alias, _ := internal.FirstEmbeddedField(delivery.Body, sessionAliasPath...)
+	return string(alias)
+}
+
 type baseMessageHandler struct {
-	logger *zerolog.Logger
-	th2Pin string
+	logger  *zerolog.Logger
+	th2Pin  string
+	filters []queue.FilterConfiguration
+}
+
+// SetFilters replaces the filters of the pin. It is called when the subscriber doesn't consume deliveries
+func (cs *baseMessageHandler) SetFilters(filters []queue.FilterConfiguration) {
+	cs.filters = filters
+}
+
+// selectGroups removes the groups that don't match the filters of the pin.
+// It returns false if no group matches, so the batch must not be passed to the listener
+func (cs *baseMessageHandler) selectGroups(batch *p_buff.MessageGroupBatch) (*p_buff.MessageGroupBatch, bool) {
+	if len(cs.filters) == 0 || len(batch.Groups) == 0 {
+		return batch, true
+	}
+	selected := filter.SelectGroups(filter.Default, batch, cs.filters)
+	if len(selected.Groups) == 0 {
+		if e := cs.logger.Debug(); e.Enabled() {
+			e.Str("Pin", cs.th2Pin).
+				Interface("MessageID", filter.FirstIDFromMsgBatch(batch)).
+				Msg("First message ID of message batch that didn't match filters")
+		}
+		return nil, false
+	}
+	return selected, true
+}
+
+// selectRawGroups is the same as selectGroups but for the serialized batch.
+// The data is decoded only if the pin has filters and re-encoded only if some groups were removed
+func (cs *baseMessageHandler) selectRawGroups(data []byte) ([]byte, bool, error) {
+	if len(cs.filters) == 0 {
+		return data, true, nil
+	}
+	batch := &p_buff.MessageGroupBatch{}
+	if err := proto.Unmarshal(data, batch); err != nil {
+		return nil, false, fmt.Errorf("cannot decode batch to apply filters: %w", err)
+	}
+	selected, matched := cs.selectGroups(batch)
+	if !matched || len(selected.Groups) == len(batch.Groups) {
+		return data, matched, nil
+	}
+	data, err := proto.Marshal(selected)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot encode filtered batch: %w", err)
+	}
+	return data, true, nil
 }
 
 type rawMessageHandler struct {
@@ -132,6 +207,20 @@ func (cs *confirmationMessageHandler) Close() error {
 	return listener.OnClose()
 }
 
+type confirmationRawMessageHandler struct {
+	baseMessageHandler
+	listener message.RawConformationListener
+}
+
+func (cs *confirmationRawMessageHandler) Close() error {
+	listener := cs.listener
+	if listener == nil {
+		return nil
+	}
+	cs.listener = nil
+	return listener.OnClose()
+}
+
 type messageHandler struct {
 	baseMessageHandler
 	listener message.Listener
@@ -156,9 +245,15 @@ func (cs *messageHandler) Handle(msgDelivery amqp.Delivery) error {
 	if err != nil {
 		return err
 	}
-	delivery := queue.Delivery{Redelivered: msgDelivery.Redelivered}
 	metrics.UpdateMessageMetrics(result, th2MessageSubscribeTotal, cs.th2Pin)
+	result, matched := cs.selectGroups(result)
+	if !matched {
+		return nil
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
 	handleErr := listener.Handle(delivery, result)
+	tracing.EndSpan(span, handleErr)
 	if handleErr != nil {
 		cs.logger.Error().Err(handleErr).Str("Method", "Handler").Msg("Can't Handle")
 		return handleErr
@@ -176,15 +271,21 @@ func (cs *rawMessageHandler) Handle(msgDelivery amqp.Delivery) error {
 	if listener == nil {
 		return errors.New("no Listener to handle")
 	}
-	delivery := queue.Delivery{Redelivered: msgDelivery.Redelivered}
-	handleErr := listener.Handle(delivery, msgDelivery.Body)
+	data, matched, err := cs.selectRawGroups(msgDelivery.Body)
+	if err != nil || !matched {
+		return err
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
+	handleErr := listener.Handle(delivery, data)
+	tracing.EndSpan(span, handleErr)
 	if handleErr != nil {
 		cs.logger.Error().Err(handleErr).Str("Method", "HandlerRaw").Msg("Can't Handle")
 		return handleErr
 	}
 	if e := cs.logger.Debug(); e.Enabled() {
 		e.Str("Method", "HandlerRaw").
-			Bytes("Data", msgDelivery.Body).
+			Bytes("Data", data).
 			Msgf("Batch has been processed")
 	}
 	return nil
@@ -201,11 +302,18 @@ func (cs *confirmationMessageHandler) Handle(msgDelivery amqp.Delivery, timer *p
 		cs.logger.Error().Err(err).Str("Method", "ConfirmationHandler").Msg("Can't unmarshal proto")
 		return nil
 	}
-	delivery := queue.Delivery{Redelivered: msgDelivery.Redelivered}
 	deliveryConfirm := internal.DeliveryConfirmation{Delivery: &msgDelivery, Logger: log.ForComponent("confirmation"), Timer: timer}
-
 	metrics.UpdateMessageMetrics(result, th2MessageSubscribeTotal, cs.th2Pin)
+	result, matched := cs.selectGroups(result)
+	if !matched {
+		// the listener does not receive the batch, so it cannot confirm it
+		return deliveryConfirm.Confirm()
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
+
 	handleErr := listener.Handle(delivery, result, &deliveryConfirm)
+	tracing.EndSpan(span, handleErr)
 	if handleErr != nil {
 		cs.logger.Error().Err(handleErr).Str("Method", "ConfirmationHandler").Msg("Can't Handle")
 		return handleErr
@@ -218,6 +326,37 @@ func (cs *confirmationMessageHandler) Handle(msgDelivery amqp.Delivery, timer *p
 	return nil
 }
 
+func (cs *confirmationRawMessageHandler) Handle(msgDelivery amqp.Delivery, timer *prometheus.Timer) error {
+	listener := cs.listener
+	if listener == nil {
+		return errors.New("no Confirmation Listener to Handle")
+	}
+	deliveryConfirm := internal.DeliveryConfirmation{Delivery: &msgDelivery, Logger: log.ForComponent("confirmation"), Timer: timer}
+	data, matched, err := cs.selectRawGroups(msgDelivery.Body)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		// the listener does not receive the batch, so it cannot confirm it
+		return deliveryConfirm.Confirm()
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
+
+	handleErr := listener.Handle(delivery, data, &deliveryConfirm)
+	tracing.EndSpan(span, handleErr)
+	if handleErr != nil {
+		cs.logger.Error().Err(handleErr).Str("Method", "ConfirmationRawHandler").Msg("Can't Handle")
+		return handleErr
+	}
+	if e := cs.logger.Debug(); e.Enabled() {
+		e.Str("Method", "ConfirmationRawHandler").
+			Int("Size", len(data)).
+			Msg("Batch has been processed")
+	}
+	return nil
+}
+
 func (cs *messageHandler) SetListener(listener message.Listener) {
 	cs.listener = listener
 	cs.logger.Trace().Msg("set listener")
@@ -228,6 +367,11 @@ func (cs *rawMessageHandler) SetListener(listener message.RawListener) {
 	cs.logger.Trace().Msg("set raw listener")
 }
 
+func (cs *confirmationRawMessageHandler) SetListener(listener message.RawConformationListener) {
+	cs.listener = listener
+	cs.logger.Trace().Msg("set raw confirmation listener")
+}
+
 func (cs *confirmationMessageHandler) SetListener(listener message.ConformationListener) {
 	cs.listener = listener
 	cs.logger.Trace().Msg("Added confirmation listener")
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package message
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	p_buff "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSessionAliasKey(t *testing.T) {
+	connectionID := func(alias string) *p_buff.MessageID {
+		return &p_buff.MessageID{ConnectionId: &p_buff.ConnectionID{SessionAlias: alias}, Sequence: 1}
+	}
+	raw := &p_buff.AnyMessage{Kind: &p_buff.AnyMessage_RawMessage{RawMessage: &p_buff.RawMessage{
+		Metadata: &p_buff.RawMessageMetadata{Id: connectionID("raw-alias")},
+		Body:     []byte("body"),
+	}}}
+	parsed := &p_buff.AnyMessage{Kind: &p_buff.AnyMessage_Message{Message: &p_buff.Message{
+		Metadata: &p_buff.MessageMetadata{Id: connectionID("parsed-alias"), MessageType: "type"},
+	}}}
+
+	for name, tc := range map[string]struct {
+		batch    *p_buff.MessageGroupBatch
+		expected string
+	}{
+		"raw message": {
+			batch: &p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{
+				{Messages: []*p_buff.AnyMessage{raw, parsed}},
+			}},
+			expected: "raw-alias",
+		},
+		"parsed message": {
+			batch: &p_buff.MessageGroupBatch{Groups: []*p_buff.MessageGroup{
+				{Messages: []*p_buff.AnyMessage{parsed}},
+				{Messages: []*p_buff.AnyMessage{raw}},
+			}},
+			expected: "parsed-alias",
+		},
+		"empty batch": {
+			batch:    &p_buff.MessageGroupBatch{},
+			expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, err := proto.Marshal(tc.batch)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, sessionAliasKey(amqp.Delivery{Body: body}))
+		})
+	}
+
+	assert.Equal(t, "", sessionAliasKey(amqp.Delivery{Body: []byte{0x0a, 0x05}}), "malformed batch")
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/common"
+	"github.com/th2-net/th2-common-go/pkg/queue/filter"
+	"github.com/th2-net/th2-common-go/pkg/queue/message"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+)
+
+type TransportRouter struct {
+	connManager    *connection.Manager
+	subscribers    map[string]internal.Subscriber
+	senders        map[string]*TransportMessageSender
+	filterStrategy filter.TransportStrategy
+	filterMode     queue.FilterMode
+	config         *queue.RouterConfig
+	Logger         zerolog.Logger
+	mutex          *sync.RWMutex
+}
+
+func NewTransportRouter(
+	manager *connection.Manager,
+	config *queue.RouterConfig,
+	logger zerolog.Logger,
+) *TransportRouter {
+	return &TransportRouter{
+		connManager:    manager,
+		subscribers:    make(map[string]internal.Subscriber),
+		senders:        make(map[string]*TransportMessageSender),
+		filterStrategy: filter.DefaultTransport,
+		Logger:         logger,
+		config:         config,
+		mutex:          &sync.RWMutex{},
+	}
+}
+
+func (tr *TransportRouter) Close() error {
+	return nil
+}
+
+func (tr *TransportRouter) SendAll(batch *transport.GroupBatch, attributes ...string) error {
+	return tr.SendAllCtx(context.Background(), batch, attributes...)
+}
+
+func (tr *TransportRouter) SendAllCtx(ctx context.Context, batch *transport.GroupBatch, attributes ...string) error {
+	pinsFoundByAttrs := common.FindSendTransportQueuesByAttr(tr.getConfig(), attributes)
+	if len(pinsFoundByAttrs) == 0 {
+		tr.Logger.Error().
+			Strs("attributes", attributes).
+			Msg("No such queue to send transport batch")
+		return fmt.Errorf("no pin found for specified attributes: %v", attributes)
+	}
+	for pin, config := range pinsFoundByAttrs {
+		selected := tr.selectGroups(pin, batch, config.Filters)
+		if selected == nil {
+			if e := tr.Logger.Debug(); e.Enabled() {
+				e.Str("Pin", pin).
+					Interface("Metadata", filter.FirstIDFromTransportBatch(batch)).
+					Msg("First ID of transport batch didn't match filter")
+			}
+			continue
+		}
+		sender := tr.getSender(pin)
+		err := sender.Send(ctx, selected)
+		if err != nil {
+			tr.Logger.Error().Err(err).Send()
+			return err
+		}
+		if e := tr.Logger.Debug(); e.Enabled() {
+			e.Str("sending to pin", pin).
+				Interface("Metadata", filter.FirstIDFromTransportBatch(selected)).
+				Msg("First ID of sent transport batch")
+		}
+	}
+	return nil
+}
+
+func (tr *TransportRouter) SubscribeAll(listener message.TransportListener, attributes ...string) (queue.Monitor, error) {
+	return tr.SubscribeAllCtx(context.Background(), listener, attributes...)
+}
+
+func (tr *TransportRouter) SubscribeAllCtx(ctx context.Context, listener message.TransportListener, attributes ...string) (queue.Monitor, error) {
+	return tr.subscribe(ctx, attributes, func(router *TransportRouter, pinName string) (internal.SubscriberMonitor, error) {
+		return router.subByPin(listener, pinName)
+	})
+}
+
+func (tr *TransportRouter) SubscribeAllWithManualAck(listener message.TransportConformationListener, attributes ...string) (queue.Monitor, error) {
+	return tr.SubscribeAllWithManualAckCtx(context.Background(), listener, attributes...)
+}
+
+func (tr *TransportRouter) SubscribeAllWithManualAckCtx(ctx context.Context, listener message.TransportConformationListener, attributes ...string) (queue.Monitor, error) {
+	return tr.subscribe(ctx, attributes, func(router *TransportRouter, pinName string) (internal.SubscriberMonitor, error) {
+		return router.subByPinWithAck(listener, pinName)
+	})
+}
+
+func (tr *TransportRouter) subscribe(
+	ctx context.Context,
+	attributes []string,
+	subscribeFunc func(router *TransportRouter, pinName string) (internal.SubscriberMonitor, error),
+) (queue.Monitor, error) {
+	pinFoundByAttrs := common.FindSubscribeTransportQueuesByAttr(tr.getConfig(), attributes)
+	if len(pinFoundByAttrs) == 0 {
+		return nil, fmt.Errorf("no pin found for attributes %v", attributes)
+	}
+	subscribers, err := internal.SubscribeAll(tr, pinFoundByAttrs, &tr.Logger, subscribeFunc)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscribers) == 0 {
+		return nil, errors.New("no such subscriber")
+	}
+	err = internal.StartAll(ctx, subscribers, &tr.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return internal.MultiplySubscribeMonitor{SubscriberMonitors: subscribers}, nil
+}
+
+func (tr *TransportRouter) subByPin(listener message.TransportListener, pin string) (internal.SubscriberMonitor, error) {
+	subscriber, err := tr.getSubscriber(pin, internal.AutoSubscriberType)
+	if err != nil {
+		return nil, err
+	}
+	autoSubscriber, err := internal.AsAutoSubscriber(subscriber, pin)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := autoSubscriber.GetHandler().(*transportMessageHandler)
+	if !ok {
+		return nil, fmt.Errorf("handler with different type %T is subscribed to pin %s",
+			autoSubscriber.GetHandler(), pin)
+	}
+	handler.SetListener(listener)
+	tr.Logger.Trace().Str("Pin", pin).Msg("Getting subscriber monitor")
+	return internal.MonitorFor(subscriber), nil
+}
+
+func (tr *TransportRouter) subByPinWithAck(listener message.TransportConformationListener, pin string) (internal.SubscriberMonitor, error) {
+	subscriber, err := tr.getSubscriber(pin, internal.ManualSubscriberType)
+	if err != nil {
+		return nil, err
+	}
+	manualSubscriber, err := internal.AsManualSubscriber(subscriber, pin)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := manualSubscriber.GetHandler().(*confirmationTransportMessageHandler)
+	if !ok {
+		return nil, fmt.Errorf("handler with different type %T is subscribed to pin %s",
+			manualSubscriber.GetHandler(), pin)
+	}
+	handler.SetListener(listener)
+	tr.Logger.Trace().Str("Pin", pin).Msg("Getting subscriber monitor")
+	return internal.MonitorFor(subscriber), nil
+}
+
+func (tr *TransportRouter) getSubscriber(pin string, subscriberType internal.SubscriberType) (internal.Subscriber, error) {
+	queueConfig := tr.getConfig().Queues[pin]
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	// the closed subscriber is replaced to allow subscribing to the pin again
+	if existing, ok := tr.subscribers[pin]; ok && !existing.IsClosed() {
+		return existing, nil
+	}
+	result, err := newSubscriber(tr.connManager, &queueConfig, pin, subscriberType, transportContentType)
+	if err != nil {
+		return nil, err
+	}
+	tr.subscribers[pin] = result
+	tr.Logger.Trace().Str("Pin", pin).Msg("Created transport subscriber")
+	return result, nil
+}
+
+func (tr *TransportRouter) getSender(pin string) *TransportMessageSender {
+	queueConfig := tr.getConfig().Queues[pin]
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	if existing, ok := tr.senders[pin]; ok {
+		return existing
+	}
+	result := &TransportMessageSender{ConnManager: tr.connManager, exchangeName: queueConfig.Exchange,
+		sendQueue: queueConfig.RoutingKey, th2Pin: pin, Logger: log.ForComponent("rabbitmq_transport_sender")}
+	tr.senders[pin] = result
+	tr.Logger.Trace().Str("Pin", pin).Msg("Created transport sender")
+	return result
+}
+
+// SetFilterMode changes the way filters of publish pins are applied to sent batches
+func (tr *TransportRouter) SetFilterMode(mode queue.FilterMode) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	tr.filterMode = mode
+}
+
+func (tr *TransportRouter) getFilterMode() queue.FilterMode {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+	return tr.filterMode
+}
+
+// selectGroups returns the batch to send via the pin according to the filter mode
+// or nil if nothing has to be sent. The groups dropped by the filters are counted
+func (tr *TransportRouter) selectGroups(pin string, batch *transport.GroupBatch, filters []queue.FilterConfiguration) *transport.GroupBatch {
+	if len(filters) == 0 {
+		return batch
+	}
+	if tr.getFilterMode() != queue.FilterPerGroup {
+		if !tr.filterStrategy.Verify(batch, filters) {
+			th2MessageFilteredGroupsTotal.WithLabelValues(pin).Add(float64(len(batch.Groups)))
+			return nil
+		}
+		return batch
+	}
+	selected := filter.SelectTransportGroups(tr.filterStrategy, batch, filters)
+	if dropped := len(batch.Groups) - len(selected.Groups); dropped > 0 {
+		th2MessageFilteredGroupsTotal.WithLabelValues(pin).Add(float64(dropped))
+	}
+	if len(selected.Groups) == 0 {
+		return nil
+	}
+	return selected
+}
+
+func (tr *TransportRouter) getConfig() *queue.RouterConfig {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+	return tr.config
+}
+
+// UpdateConfig applies the new pins configuration. Senders of removed or changed pins are created again on the next use.
+// Subscriptions of removed pins are stopped, subscriptions of changed pins are started again with the new configuration.
+func (tr *TransportRouter) UpdateConfig(config *queue.RouterConfig) {
+	tr.mutex.Lock()
+	update := internal.ForgetChangedPins(tr.config, config, tr.senders, tr.subscribers, &tr.Logger)
+	tr.config = config
+	tr.mutex.Unlock()
+	update.Apply()
+	tr.Logger.Info().Msg("configuration updated")
+}
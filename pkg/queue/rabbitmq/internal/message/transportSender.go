@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package message
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+)
+
+type TransportMessageSender struct {
+	ConnManager  *connection.Manager
+	exchangeName string
+	sendQueue    string
+	th2Pin       string
+
+	Logger zerolog.Logger
+}
+
+func (sender *TransportMessageSender) Send(ctx context.Context, batch *transport.GroupBatch) error {
+	if batch == nil {
+		return NullValue
+	}
+	body, err := transport.Encode(batch)
+	if err != nil {
+		sender.Logger.Error().Err(err).Msg("Error during encoding transport batch")
+		return err
+	}
+	fail := sender.ConnManager.Publisher.Publish(ctx, body, sender.sendQueue, sender.exchangeName, sender.th2Pin, metrics.TransportGroupTh2Type)
+	if fail != nil {
+		return fail
+	}
+	metrics.UpdateTransportMessageMetrics(batch, th2MessagePublishTotal, sender.th2Pin)
+	return nil
+}
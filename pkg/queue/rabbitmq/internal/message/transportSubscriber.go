@@ -0,0 +1,159 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package message
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue/filter"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
+	"github.com/th2-net/th2-common-go/pkg/queue/message"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
+)
+
+// transportSessionAliasKey returns the session alias of the first message in the transport batch
+func transportSessionAliasKey(delivery amqp.Delivery) string {
+	alias, err := transport.FirstSessionAlias(delivery.Body)
+	if err != nil {
+		return ""
+	}
+	return alias
+}
+
+// selectTransportGroups is the same as selectGroups but for the transport batch
+func (cs *baseMessageHandler) selectTransportGroups(batch *transport.GroupBatch) (*transport.GroupBatch, bool) {
+	if len(cs.filters) == 0 || len(batch.Groups) == 0 {
+		return batch, true
+	}
+	selected := filter.SelectTransportGroups(filter.DefaultTransport, batch, cs.filters)
+	if len(selected.Groups) == 0 {
+		if e := cs.logger.Debug(); e.Enabled() {
+			e.Str("Pin", cs.th2Pin).
+				Interface("MessageID", filter.FirstIDFromTransportBatch(batch)).
+				Msg("First message ID of transport batch that didn't match filters")
+		}
+		return nil, false
+	}
+	return selected, true
+}
+
+type transportMessageHandler struct {
+	baseMessageHandler
+	listener message.TransportListener
+}
+
+func (cs *transportMessageHandler) Close() error {
+	listener := cs.listener
+	if listener == nil {
+		return nil
+	}
+	cs.listener = nil
+	return listener.OnClose()
+}
+
+func (cs *transportMessageHandler) Handle(msgDelivery amqp.Delivery) error {
+	listener := cs.listener
+	if listener == nil {
+		return errors.New("no Listener to handle")
+	}
+	result, err := transport.Decode(msgDelivery.Body)
+	if err != nil {
+		return err
+	}
+	metrics.UpdateTransportMessageMetrics(result, th2MessageSubscribeTotal, cs.th2Pin)
+	result, matched := cs.selectTransportGroups(result)
+	if !matched {
+		return nil
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
+	handleErr := listener.Handle(delivery, result)
+	tracing.EndSpan(span, handleErr)
+	if handleErr != nil {
+		cs.logger.Error().Err(handleErr).Str("Method", "TransportHandler").Msg("Can't Handle")
+		return handleErr
+	}
+	if e := cs.logger.Debug(); e.Enabled() {
+		e.Str("Method", "TransportHandler").
+			Interface("MessageID", filter.FirstIDFromTransportBatch(result)).
+			Msg("First message ID of transport batch that handled successfully")
+	}
+	return nil
+}
+
+func (cs *transportMessageHandler) SetListener(listener message.TransportListener) {
+	cs.listener = listener
+	cs.logger.Trace().Msg("set transport listener")
+}
+
+type confirmationTransportMessageHandler struct {
+	baseMessageHandler
+	listener message.TransportConformationListener
+}
+
+func (cs *confirmationTransportMessageHandler) Close() error {
+	listener := cs.listener
+	if listener == nil {
+		return nil
+	}
+	cs.listener = nil
+	return listener.OnClose()
+}
+
+func (cs *confirmationTransportMessageHandler) Handle(msgDelivery amqp.Delivery, timer *prometheus.Timer) error {
+	listener := cs.listener
+	if listener == nil {
+		return errors.New("no Confirmation Listener to Handle")
+	}
+	result, err := transport.Decode(msgDelivery.Body)
+	if err != nil {
+		cs.logger.Error().Err(err).Str("Method", "ConfirmationTransportHandler").Msg("Can't decode transport batch")
+		return nil
+	}
+	deliveryConfirm := internal.DeliveryConfirmation{Delivery: &msgDelivery, Logger: log.ForComponent("confirmation"), Timer: timer}
+	metrics.UpdateTransportMessageMetrics(result, th2MessageSubscribeTotal, cs.th2Pin)
+	result, matched := cs.selectTransportGroups(result)
+	if !matched {
+		// the listener does not receive the batch, so it cannot confirm it
+		return deliveryConfirm.Confirm()
+	}
+	ctx, span := tracing.StartConsumeSpan(msgDelivery.Headers, cs.th2Pin)
+	delivery := properties.Delivery(ctx, &msgDelivery, cs.th2Pin)
+
+	handleErr := listener.Handle(delivery, result, &deliveryConfirm)
+	tracing.EndSpan(span, handleErr)
+	if handleErr != nil {
+		cs.logger.Error().Err(handleErr).Str("Method", "ConfirmationTransportHandler").Msg("Can't Handle")
+		return handleErr
+	}
+	if e := cs.logger.Debug(); e.Enabled() {
+		e.Str("Method", "ConfirmationTransportHandler").
+			Interface("MessageID", filter.FirstIDFromTransportBatch(result)).
+			Msg("First message ID of transport batch that was handled successfully")
+	}
+	return nil
+}
+
+func (cs *confirmationTransportMessageHandler) SetListener(listener message.TransportConformationListener) {
+	cs.listener = listener
+	cs.logger.Trace().Msg("Added transport confirmation listener")
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/internal/properties"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+	"github.com/th2-net/th2-common-go/pkg/tracing"
+)
+
+const (
+	// RetryCountHeader holds the number of failed handling attempts of the delivery
+	RetryCountHeader = "x-th2-retry-count"
+	// ErrorHeader holds the error of the last attempt of the dead-lettered delivery
+	ErrorHeader = "x-th2-error"
+	// QueueHeader holds the queue the dead-lettered delivery was consumed from
+	QueueHeader = "x-th2-queue"
+
+	defaultMaxAttempts = 3
+	defaultBackoff     = time.Second
+)
+
+var (
+	errRejected = errors.New("rejected by listener")
+	// errNoDeadLetterExchange makes the delivery rejected without requeue,
+	// so the dead-letter exchange of the queue is applied by the broker
+	errNoDeadLetterExchange = errors.New("no dead-letter exchange is configured")
+)
+
+var th2RabbitmqMessageRetryTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_rabbitmq_message_retry_total",
+		Help: "Amount of deliveries published again to the queue after failed handling",
+	},
+	metrics.SubscriberLabels,
+)
+
+var th2RabbitmqMessageDeadLetterTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "th2_rabbitmq_message_dead_letter_total",
+		Help: "Amount of deliveries dead-lettered after the last failed attempt",
+	},
+	metrics.SubscriberLabels,
+)
+
+// retrier applies the retry policy of the pin to failed deliveries
+type retrier struct {
+	maxAttempts          int
+	backoff              time.Duration
+	maxBackoff           time.Duration
+	deadLetterExchange   string
+	deadLetterRoutingKey string
+
+	publisher connection.MessagePublisher
+	queueName string
+	th2Pin    string
+	th2Type   string
+	logger    zerolog.Logger
+
+	// declared holds the names of the delay queues declared by the retrier
+	declared map[string]bool
+	mutex    sync.Mutex
+}
+
+func newRetrier(policy *queue.RetryPolicy, publisher connection.MessagePublisher, queueName string, th2Pin string, th2Type string) *retrier {
+	r := &retrier{
+		declared:             make(map[string]bool),
+		maxAttempts:          policy.MaxAttempts,
+		backoff:              time.Duration(policy.Backoff) * time.Millisecond,
+		maxBackoff:           time.Duration(policy.MaxBackoff) * time.Millisecond,
+		deadLetterExchange:   policy.DeadLetterExchange,
+		deadLetterRoutingKey: policy.DeadLetterRoutingKey,
+		publisher:            publisher,
+		queueName:            queueName,
+		th2Pin:               th2Pin,
+		th2Type:              th2Type,
+		logger:               log.ForComponent("rabbitmq_retry").With().Str("pin", th2Pin).Logger(),
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultMaxAttempts
+	}
+	if r.backoff <= 0 {
+		r.backoff = defaultBackoff
+	}
+	if r.maxBackoff < r.backoff {
+		r.maxBackoff = r.backoff
+	}
+	return r
+}
+
+// wrapAuto acknowledges the delivery after it is handled, so the failed delivery is not lost
+// before it is retried or dead-lettered. The handler must be consumed with the manual acknowledgement
+func (r *retrier) wrapAuto(handler func(delivery amqp.Delivery) error) func(delivery amqp.Delivery, timer *prometheus.Timer) error {
+	return func(delivery amqp.Delivery, timer *prometheus.Timer) error {
+		defer timer.ObserveDuration()
+		if err := handler(delivery); err != nil {
+			return r.settle(delivery, delivery.Acknowledger, err)
+		}
+		return delivery.Ack(false)
+	}
+}
+
+// wrapManual makes rejections of the listener go through the retry policy.
+// A delivery which is not confirmed or rejected by the listener that returned an error is treated as rejected
+func (r *retrier) wrapManual(handler func(delivery amqp.Delivery, timer *prometheus.Timer) error) func(delivery amqp.Delivery, timer *prometheus.Timer) error {
+	return func(delivery amqp.Delivery, timer *prometheus.Timer) error {
+		acknowledger := &retryAcknowledger{retrier: r, delegate: delivery.Acknowledger, delivery: delivery}
+		delivery.Acknowledger = acknowledger
+		err := handler(delivery, timer)
+		if err != nil && acknowledger.settleOnce() {
+			if rejectErr := acknowledger.reject(err); rejectErr != nil {
+				return errors.Join(err, rejectErr)
+			}
+			// the failure is handled by the retry policy
+			return nil
+		}
+		return err
+	}
+}
+
+// settle applies the retry policy to the failed delivery and acknowledges or rejects it
+func (r *retrier) settle(delivery amqp.Delivery, acknowledger amqp.Acknowledger, cause error) error {
+	tag := delivery.DeliveryTag
+	err := r.fail(delivery, cause)
+	switch {
+	case err == nil:
+		return acknowledger.Ack(tag, false)
+	case errors.Is(err, errNoDeadLetterExchange):
+		return acknowledger.Reject(tag, false)
+	default:
+		// keep the delivery in the queue if it cannot be retried or dead-lettered
+		return errors.Join(err, acknowledger.Reject(tag, true))
+	}
+}
+
+// fail publishes the delivery to the delay queue for the next attempt or to the dead-letter exchange.
+// The publication is confirmed by the broker, so the original delivery must be acknowledged by the caller if nil is returned.
+// errNoDeadLetterExchange is returned if the delivery must be rejected without requeue
+func (r *retrier) fail(delivery amqp.Delivery, cause error) error {
+	// the published delivery keeps the properties and continues the trace of the failed one
+	ctx := queue.WithProperties(tracing.ExtractHeaders(context.Background(), delivery.Headers),
+		properties.FromDelivery(&delivery))
+	attempt := retryCount(delivery.Headers) + 1
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[RetryCountHeader] = int32(attempt)
+	if attempt < r.maxAttempts {
+		delay := r.delay(attempt)
+		r.logger.Warn().
+			Err(cause).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("delivery handling failed, it will be retried")
+		delayQueue, err := r.delayQueue(ctx, delay)
+		if err != nil {
+			return err
+		}
+		// the default exchange routes the data directly to the delay queue,
+		// the broker returns it to the queue of the pin when the delay expires
+		if err := r.publisher.PublishConfirmed(ctx, delivery.Body, headers, delayQueue, "", r.th2Pin, r.th2Type); err != nil {
+			return fmt.Errorf("cannot publish delivery for retry: %w", err)
+		}
+		th2RabbitmqMessageRetryTotal.WithLabelValues(r.th2Pin, r.th2Type, r.queueName).Inc()
+		return nil
+	}
+	th2RabbitmqMessageDeadLetterTotal.WithLabelValues(r.th2Pin, r.th2Type, r.queueName).Inc()
+	if r.deadLetterExchange == "" && r.deadLetterRoutingKey == "" {
+		r.logger.Error().
+			Err(cause).
+			Int("attempts", attempt).
+			Msg("delivery handling failed, it is rejected without requeue")
+		return errNoDeadLetterExchange
+	}
+	headers[ErrorHeader] = cause.Error()
+	headers[QueueHeader] = r.queueName
+	if err := r.publisher.PublishConfirmed(ctx, delivery.Body, headers, r.deadLetterRoutingKey, r.deadLetterExchange, r.th2Pin, r.th2Type); err != nil {
+		return fmt.Errorf("cannot publish delivery to dead-letter exchange: %w", err)
+	}
+	r.logger.Error().
+		Err(cause).
+		Int("attempts", attempt).
+		Str("exchange", r.deadLetterExchange).
+		Str("routing", r.deadLetterRoutingKey).
+		Msg("delivery handling failed, the delivery is dead-lettered")
+	return nil
+}
+
+// delayQueue returns the name of the queue keeping the deliveries for the delay and declares it once
+func (r *retrier) delayQueue(ctx context.Context, delay time.Duration) (string, error) {
+	name := fmt.Sprintf("%s.retry.%dms", r.queueName, delay.Milliseconds())
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.declared[name] {
+		return name, nil
+	}
+	if err := r.publisher.DeclareDelayQueue(ctx, name, delay, r.queueName); err != nil {
+		return "", err
+	}
+	r.declared[name] = true
+	return name, nil
+}
+
+func (r *retrier) delay(attempt int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempt && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.maxBackoff)
+}
+
+func retryCount(headers amqp.Table) int {
+	switch count := headers[RetryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	default:
+		return 0
+	}
+}
+
+// retryAcknowledger replaces the rejection of the delivery with the retry policy
+type retryAcknowledger struct {
+	retrier  *retrier
+	delegate amqp.Acknowledger
+	delivery amqp.Delivery
+
+	mutex   sync.Mutex
+	settled bool
+}
+
+func (ra *retryAcknowledger) settleOnce() bool {
+	ra.mutex.Lock()
+	defer ra.mutex.Unlock()
+	if ra.settled {
+		return false
+	}
+	ra.settled = true
+	return true
+}
+
+func (ra *retryAcknowledger) Ack(tag uint64, multiple bool) error {
+	ra.settleOnce()
+	return ra.delegate.Ack(tag, multiple)
+}
+
+func (ra *retryAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	ra.settleOnce()
+	if requeue || multiple {
+		return ra.delegate.Nack(tag, multiple, requeue)
+	}
+	return ra.reject(errRejected)
+}
+
+func (ra *retryAcknowledger) Reject(tag uint64, requeue bool) error {
+	ra.settleOnce()
+	if requeue {
+		return ra.delegate.Reject(tag, requeue)
+	}
+	return ra.reject(errRejected)
+}
+
+func (ra *retryAcknowledger) reject(cause error) error {
+	return ra.retrier.settle(ra.delivery, ra.delegate, cause)
+}
@@ -17,18 +17,23 @@
 package internal
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/queue"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
 	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
 	"io"
 	"sync"
 )
 
-var DoubleStartError = errors.New("the subscription already started")
+var (
+	DoubleStartError      = errors.New("the subscription already started")
+	ClosedSubscriberError = errors.New("the subscriber is closed")
+)
 
 type SubscriberType = int
 
@@ -43,6 +48,7 @@ func NewAutoSubscriber(
 	pinName string,
 	handler AutoHandler,
 	metric string,
+	orderingKey func(delivery amqp.Delivery) string,
 ) AutoSubscriber {
 	return &autoSubscriber{
 		subscriber: subscriber{
@@ -50,7 +56,9 @@ func NewAutoSubscriber(
 			qConfig:      config,
 			th2Pin:       pinName,
 			metricsLabel: metric,
+			orderingKey:  orderingKey,
 			lock:         &sync.RWMutex{},
+			stopped:      make(chan struct{}),
 		},
 		handler: handler,
 	}
@@ -62,6 +70,7 @@ func NewManualSubscriber(
 	pinName string,
 	handler ConfirmationHandler,
 	metric string,
+	orderingKey func(delivery amqp.Delivery) string,
 ) Subscriber {
 	return &confirmationSubscriber{
 		subscriber: subscriber{
@@ -69,7 +78,9 @@ func NewManualSubscriber(
 			qConfig:      config,
 			th2Pin:       pinName,
 			metricsLabel: metric,
+			orderingKey:  orderingKey,
 			lock:         &sync.RWMutex{},
+			stopped:      make(chan struct{}),
 		},
 		handler: handler,
 	}
@@ -80,9 +91,21 @@ type subscriber struct {
 	qConfig      *queue.DestinationConfig
 	th2Pin       string
 	metricsLabel string
+	orderingKey  func(delivery amqp.Delivery) string
 
 	lock    *sync.RWMutex
 	started bool
+	closed  bool
+	// cancel stops the consumer of the queue
+	cancel func()
+	// consumed is closed when the consumer doesn't call the handler anymore
+	consumed <-chan struct{}
+	// stopped is closed when the subscriber is closed and its handler is closed
+	stopped chan struct{}
+	// closeErr is the error of closing the handler, it is set before stopped is closed
+	closeErr error
+	// failure is the error of the consumer that cannot be recovered
+	failure error
 }
 
 func (cs *subscriber) Pin() string {
@@ -101,11 +124,22 @@ type ConfirmationHandler interface {
 
 type Subscriber interface {
 	IsStarted() bool
-	Start() error
+	IsClosed() bool
+	Start(ctx context.Context) error
 	Pin() string
+	// Reconfigure applies the changed configuration of the pin.
+	// The running consumer is started again when the deliveries received with the previous configuration are handled
+	Reconfigure(config *queue.DestinationConfig) error
+	// Wait blocks until the subscriber is closed and the deliveries received before are handled
+	Wait(ctx context.Context) error
 	io.Closer
 }
 
+// filtersSetter is implemented by the handlers applying the filters of the pin
+type filtersSetter interface {
+	SetFilters(filters []queue.FilterConfiguration)
+}
+
 type AutoSubscriber interface {
 	Subscriber
 	GetHandler() AutoHandler
@@ -134,44 +168,103 @@ func (cs *confirmationSubscriber) GetHandler() ConfirmationHandler {
 	return cs.handler
 }
 
-func (cs *autoSubscriber) Start() error {
+func (cs *autoSubscriber) Start(ctx context.Context) error {
 	cs.lock.Lock()
 	defer cs.lock.Unlock()
+	if cs.closed {
+		return ClosedSubscriberError
+	}
 	if cs.started {
 		return DoubleStartError
 	}
-	err := cs.connManager.Consumer.Consume(cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel, cs.handler.Handle)
+	return cs.consume(ctx)
+}
+
+// consume starts the consumer of the queue, must be called under the subscriber lock
+func (cs *autoSubscriber) consume(ctx context.Context) error {
+	var cancel func()
+	var consumed <-chan struct{}
+	var err error
+	if cs.qConfig.Retry != nil {
+		// the deliveries are acknowledged after the retry policy is applied
+		cancel, consumed, err = cs.connManager.Consumer.ConsumeWithManualAck(ctx, cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel, cs.consumeOptions(cs.handler),
+			cs.newRetrier().wrapAuto(cs.handler.Handle))
+	} else {
+		cancel, consumed, err = cs.connManager.Consumer.Consume(ctx, cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel, cs.consumeOptions(cs.handler), cs.handler.Handle)
+	}
 	if err != nil {
 		return err
 	}
+	cs.cancel, cs.consumed = cancel, consumed
 	cs.started = true
 	return nil
 	//use th2Pin for metrics
 }
 
+func (cs *autoSubscriber) Reconfigure(config *queue.DestinationConfig) error {
+	return cs.reconfigure(config, cs.handler, cs.consume)
+}
+
 func (cs *autoSubscriber) Close() error {
-	defer cs.Stop()
-	return cs.handler.Close()
+	return cs.close(cs.handler)
 }
 
-func (cs *confirmationSubscriber) Start() error {
+func (cs *confirmationSubscriber) Start(ctx context.Context) error {
 	cs.lock.Lock()
 	defer cs.lock.Unlock()
+	if cs.closed {
+		return ClosedSubscriberError
+	}
 	if cs.started {
 		return DoubleStartError
 	}
-	err := cs.connManager.Consumer.ConsumeWithManualAck(cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel, cs.handler.Handle)
+	return cs.consume(ctx)
+}
+
+// consume starts the consumer of the queue, must be called under the subscriber lock
+func (cs *confirmationSubscriber) consume(ctx context.Context) error {
+	handle := cs.handler.Handle
+	if cs.qConfig.Retry != nil {
+		handle = cs.newRetrier().wrapManual(handle)
+	}
+	cancel, consumed, err := cs.connManager.Consumer.ConsumeWithManualAck(ctx, cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel, cs.consumeOptions(cs.handler), handle)
 	if err != nil {
 		return err
 	}
+	cs.cancel, cs.consumed = cancel, consumed
 	cs.started = true
 	return nil
 	//use th2Pin for metrics
 }
 
+func (cs *confirmationSubscriber) Reconfigure(config *queue.DestinationConfig) error {
+	return cs.reconfigure(config, cs.handler, cs.consume)
+}
+
 func (cs *confirmationSubscriber) Close() error {
-	defer cs.Stop()
-	return cs.handler.Close()
+	return cs.close(cs.handler)
+}
+
+func (cs *subscriber) consumeOptions(handler io.Closer) connCfg.ConsumeOptions {
+	options := connCfg.ConsumeOptions{
+		Exclusive:        cs.qConfig.Exclusive,
+		WaitForExclusive: cs.qConfig.WaitForExclusive,
+		OnFailure: func(err error) {
+			cs.fail(handler, err)
+		},
+	}
+	if workers := cs.qConfig.Workers; workers != nil {
+		options.Workers = workers.Count
+		if workers.PreserveOrder {
+			options.OrderingKey = cs.orderingKey
+		}
+	}
+	return options
+}
+
+// newRetrier must be called under the subscriber lock
+func (cs *subscriber) newRetrier() *retrier {
+	return newRetrier(cs.qConfig.Retry, cs.connManager.Publisher, cs.qConfig.QueueName, cs.th2Pin, cs.metricsLabel)
 }
 
 func (cs *subscriber) IsStarted() bool {
@@ -180,14 +273,118 @@ func (cs *subscriber) IsStarted() bool {
 	return cs.started
 }
 
+// IsClosed returns true if the subscriber was closed and cannot be started again
+func (cs *subscriber) IsClosed() bool {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	return cs.closed
+}
+
 func (cs *subscriber) Stop() {
 	cs.lock.Lock()
 	defer cs.lock.Unlock()
 	cs.started = false
 }
 
+// close cancels the consumer without waiting for the deliveries being handled, so it can be called from the handler.
+// The handler is closed when the consumer doesn't call it anymore
+func (cs *subscriber) close(handler io.Closer) error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.closed {
+		return nil
+	}
+	cs.started = false
+	cs.closed = true
+	if cs.cancel != nil {
+		cs.cancel()
+		cs.cancel = nil
+	}
+	if cs.consumed == nil {
+		cs.closeErr = handler.Close()
+		close(cs.stopped)
+		return cs.closeErr
+	}
+	go cs.closeHandler(handler, cs.consumed)
+	return nil
+}
+
+// reconfigure cancels the running consumer and starts it with the new configuration
+// when the deliveries received before are handled. The subscriber stays started meanwhile
+func (cs *subscriber) reconfigure(config *queue.DestinationConfig, handler any, consume func(ctx context.Context) error) error {
+	cs.lock.Lock()
+	if cs.closed {
+		cs.lock.Unlock()
+		return ClosedSubscriberError
+	}
+	if cs.cancel != nil {
+		cs.cancel()
+		cs.cancel = nil
+	}
+	consumed := cs.consumed
+	cs.lock.Unlock()
+	if consumed != nil {
+		<-consumed
+	}
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.closed {
+		// the handler is closed by close
+		return nil
+	}
+	cs.qConfig = config
+	cs.consumed = nil
+	if setter, ok := handler.(filtersSetter); ok {
+		setter.SetFilters(config.Filters)
+	}
+	if !cs.started {
+		return nil
+	}
+	cs.started = false
+	return consume(context.Background())
+}
+
+func (cs *subscriber) closeHandler(handler io.Closer, consumed <-chan struct{}) {
+	<-consumed
+	err := handler.Close()
+	if err != nil {
+		err = fmt.Errorf("cannot close handler of pin %s: %w", cs.th2Pin, err)
+	}
+	cs.lock.Lock()
+	cs.closeErr = err
+	cs.lock.Unlock()
+	close(cs.stopped)
+}
+
+// fail closes the subscriber whose consumer cannot be recovered
+func (cs *subscriber) fail(handler io.Closer, err error) {
+	cs.lock.Lock()
+	if cs.closed {
+		cs.lock.Unlock()
+		return
+	}
+	cs.failure = fmt.Errorf("subscription to pin %s failed: %w", cs.th2Pin, err)
+	cs.lock.Unlock()
+	_ = cs.close(handler)
+}
+
+// Wait blocks until the subscriber is closed and the deliveries received before are handled.
+// It returns the error of closing the handler or the failure of the consumer
+func (cs *subscriber) Wait(ctx context.Context) error {
+	select {
+	case <-cs.stopped:
+		cs.lock.RLock()
+		defer cs.lock.RUnlock()
+		return errors.Join(cs.failure, cs.closeErr)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type SubscriberMonitor interface {
 	queue.Monitor
+	queue.CompletionWaiter
 	GetSubscriber() Subscriber
 }
 
@@ -213,6 +410,10 @@ func (sub subscriberMonitor) Unsubscribe() error {
 	return nil
 }
 
+func (sub subscriberMonitor) Wait(ctx context.Context) error {
+	return sub.subscriber.Wait(ctx)
+}
+
 type MultiplySubscribeMonitor struct {
 	SubscriberMonitors []SubscriberMonitor
 }
@@ -231,6 +432,16 @@ func (sub MultiplySubscribeMonitor) Unsubscribe() error {
 	return nil
 }
 
+func (sub MultiplySubscribeMonitor) Wait(ctx context.Context) error {
+	var errs []error
+	for _, subM := range sub.SubscriberMonitors {
+		if err := subM.Wait(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func SubscribeAll[T any](
 	router T,
 	pinFoundByAttrs map[string]queue.DestinationConfig,
@@ -250,14 +461,14 @@ func SubscribeAll[T any](
 	return subscribers, nil
 }
 
-func StartAll(subscribers []SubscriberMonitor, logger *zerolog.Logger) error {
+func StartAll(ctx context.Context, subscribers []SubscriberMonitor, logger *zerolog.Logger) error {
 	for _, s := range subscribers {
 		logger.Trace().Str("Pin", s.GetSubscriber().Pin()).Msg("Start subscribing of queue")
 		if s.GetSubscriber().IsStarted() {
 			logger.Trace().Str("Pin", s.GetSubscriber().Pin()).Msg("subscriber already started")
 			continue
 		}
-		err := s.GetSubscriber().Start()
+		err := s.GetSubscriber().Start(ctx)
 		if err != nil {
 			if err == DoubleStartError {
 				logger.Info().Str("Pin", s.GetSubscriber().Pin()).Msg("already started")
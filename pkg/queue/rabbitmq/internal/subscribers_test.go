@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/th2-net/th2-common-go/pkg/queue"
+	connCfg "github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/internal/connection"
+)
+
+type testConsumer struct {
+	options connCfg.ConsumeOptions
+	done    chan struct{}
+}
+
+func (c *testConsumer) Consume(_ context.Context, _ string, _ string, _ string, options connCfg.ConsumeOptions, _ func(delivery amqp.Delivery) error) (func(), <-chan struct{}, error) {
+	c.options = options
+	c.done = make(chan struct{})
+	return func() {}, c.done, nil
+}
+
+func (c *testConsumer) ConsumeWithManualAck(context.Context, string, string, string, connCfg.ConsumeOptions, func(msgDelivery amqp.Delivery, timer *prometheus.Timer) error) (func(), <-chan struct{}, error) {
+	return nil, nil, errors.New("not supported")
+}
+
+func (c *testConsumer) Close() error {
+	return nil
+}
+
+type testHandler struct {
+	closed bool
+}
+
+func (h *testHandler) Handle(amqp.Delivery) error {
+	return nil
+}
+
+func (h *testHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func TestSubscriberIsClosedOnConsumerFailure(t *testing.T) {
+	consumer := &testConsumer{}
+	handler := &testHandler{}
+	sub := NewAutoSubscriber(&connection.Manager{Consumer: consumer}, &queue.DestinationConfig{QueueName: "queue"}, "pin", handler, "test", nil)
+	if err := sub.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("access refused")
+	consumer.options.OnFailure(failure)
+	close(consumer.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := sub.Wait(ctx)
+	assert.ErrorIs(t, err, failure)
+	assert.True(t, sub.IsClosed())
+	assert.True(t, handler.closed, "handler of failed subscription must be closed")
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package internal
+
+import (
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// FirstEmbeddedField follows the path of embedded message fields in the serialized protobuf message
+// and returns the content of the last field. Each element of the path holds the numbers of the fields
+// that can be taken at that level (e.g. the fields of a oneof). The first matching field is taken
+// at each level, so repeated fields are not decoded beyond their first element.
+// It returns false if a field is absent or the data is malformed
+func FirstEmbeddedField(data []byte, path ...[]protowire.Number) ([]byte, bool) {
+	for _, numbers := range path {
+		var ok bool
+		if data, ok = firstBytesField(data, numbers); !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func firstBytesField(data []byte, numbers []protowire.Number) ([]byte, bool) {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+		if wireType == protowire.BytesType && slices.Contains(numbers, number) {
+			value, n := protowire.ConsumeBytes(data)
+			return value, n >= 0
+		}
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+	}
+	return nil, false
+}
@@ -26,4 +26,75 @@ type DestinationConfig struct {
 	Exchange   string                `json:"exchange"`
 	Attributes []string              `json:"attributes"`
 	Filters    []FilterConfiguration `json:"filters"`
+	Batching   *BatchingConfig       `json:"batching,omitempty"`
+	Retry      *RetryPolicy          `json:"retry,omitempty"`
+	Workers    *WorkersConfig        `json:"workers,omitempty"`
+	// Exclusive makes the subscription the only consumer of the queue
+	Exclusive bool `json:"exclusive,omitempty"`
+	// WaitForExclusive makes the subscription consume exclusively once the queue has no consumers.
+	// The exclusive consumption is retried each minConnectionRecoveryTimeout while the queue has any consumer.
+	// It is not the RabbitMQ single active consumer that is set by the x-single-active-consumer queue argument
+	WaitForExclusive bool `json:"waitForExclusive,omitempty"`
+}
+
+// WorkersConfig enables concurrent handling of deliveries of the subscribe pin
+type WorkersConfig struct {
+	// Count is the number of goroutines handling deliveries
+	Count int `json:"count,omitempty"`
+	// PreserveOrder makes deliveries with the same ordering key handled in the order they were received.
+	// The key is the session alias of the first message for message pins and the parent event ID for event pins
+	PreserveOrder bool `json:"preserveOrder,omitempty"`
+}
+
+// BatchingConfig enables accumulation of message groups sent via the pin.
+// Zero values are replaced with defaults.
+type BatchingConfig struct {
+	// MaxBatchSize is the max serialized size of the batch in bytes
+	MaxBatchSize int `json:"maxBatchSize,omitempty"`
+	// MaxGroups is the max number of groups in the batch, zero means no limit
+	MaxGroups int `json:"maxGroups,omitempty"`
+	// FlushInterval is the max time in milliseconds a group stays in the batch before it is sent
+	FlushInterval int `json:"flushInterval,omitempty"`
+	// MaxPendingBytes is the max serialized size of the groups accumulated for all streams of the pin,
+	// including the groups which are being sent. Sends wait while the limit is exceeded
+	MaxPendingBytes int `json:"maxPendingBytes,omitempty"`
+}
+
+// RetryPolicy defines how deliveries which are failed to handle are retried via the subscribe pin.
+// A delivery is failed if the listener returns an error or rejects it.
+// Zero values are replaced with defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of handling attempts including the first one
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Backoff is the delay in milliseconds before the second attempt
+	Backoff int `json:"backoff,omitempty"`
+	// MaxBackoff is the max delay in milliseconds. The delay is doubled for each next attempt up to this value
+	MaxBackoff int `json:"maxBackoff,omitempty"`
+	// DeadLetterExchange is the exchange the delivery is published to after the last failed attempt.
+	// The delivery is rejected without requeue if neither the exchange nor the routing key is set
+	DeadLetterExchange string `json:"deadLetterExchange,omitempty"`
+	// DeadLetterRoutingKey is the routing key used to publish to the dead-letter exchange
+	DeadLetterRoutingKey string `json:"deadLetterRoutingKey,omitempty"`
+}
+
+// ConfigUpdater is implemented by routers that apply changes of pins at runtime
+type ConfigUpdater interface {
+	UpdateConfig(config *RouterConfig)
+}
+
+// FilterMode defines how the filters of a publish pin are applied to a message batch
+type FilterMode int
+
+const (
+	// FilterWholeBatch sends the batch via the pin only if each group of the batch matches the filters.
+	// It is the default mode
+	FilterWholeBatch FilterMode = iota
+	// FilterPerGroup sends the batch made of the groups matching the filters via the pin.
+	// Nothing is sent if none of the groups matches
+	FilterPerGroup
+)
+
+// FilterModeSelector is implemented by message routers that can change the way filters of publish pins are applied
+type FilterModeSelector interface {
+	SetFilterMode(mode FilterMode)
 }
@@ -0,0 +1,510 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Each value is written as a type (1 byte), a length of the value (int32 little-endian)
+// and the value itself. Composite values contain a sequence of other values.
+// Values with unknown types are skipped during decoding.
+const (
+	longType        = 1
+	stringType      = 2
+	intType         = 3
+	messageIDType   = 10
+	metadataType    = 11
+	protocolType    = 12
+	messageTypeType = 13
+	idType          = 14
+	scopeType       = 15
+	eventIDType     = 16
+
+	rawMessageType       = 20
+	rawMessageBodyType   = 21
+	parsedMessageType    = 30
+	parsedMessageRawType = 31
+	messageGroupType     = 40
+	messageListType      = 41
+	groupBatchType       = 50
+	groupListType        = 51
+
+	bookType         = 101
+	sessionGroupType = 102
+	sessionAliasType = 103
+	directionType    = 104
+	sequenceType     = 105
+	subsequenceType  = 106
+	timestampType    = 107
+)
+
+const headerSize = 5
+
+var (
+	ErrTruncated      = errors.New("transport data is truncated")
+	ErrUnexpectedType = errors.New("unexpected value type")
+)
+
+// Encode serializes the batch into the th2 transport protocol
+func Encode(batch *GroupBatch) ([]byte, error) {
+	if batch == nil {
+		return nil, errors.New("nil batch")
+	}
+	e := encoder{buf: make([]byte, 0, 1024)}
+	if err := e.groupBatch(batch); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Decode deserializes the batch from the th2 transport protocol
+func Decode(data []byte) (*GroupBatch, error) {
+	d := decoder{data: data}
+	valueType, value, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	if valueType != groupBatchType {
+		return nil, fmt.Errorf("%w %d: expected group batch", ErrUnexpectedType, valueType)
+	}
+	return decodeGroupBatch(value)
+}
+
+// FirstSessionAlias returns the session alias of the first message in the serialized batch.
+// Unlike Decode it reads only the values on the path to the session alias
+func FirstSessionAlias(data []byte) (string, error) {
+	d := decoder{data: data}
+	valueType, value, err := d.next()
+	if err != nil {
+		return "", err
+	}
+	if valueType != groupBatchType {
+		return "", fmt.Errorf("%w %d: expected group batch", ErrUnexpectedType, valueType)
+	}
+	path := [][]uint8{
+		{groupListType},
+		{messageGroupType},
+		{messageListType},
+		{rawMessageType, parsedMessageType},
+		{messageIDType},
+		{sessionAliasType},
+	}
+	for _, types := range path {
+		if value, err = firstValue(value, types...); value == nil || err != nil {
+			return "", err
+		}
+	}
+	return string(value), nil
+}
+
+// firstValue returns the first value with one of the types in the composite value or nil if there is no such value
+func firstValue(data []byte, types ...uint8) ([]byte, error) {
+	d := decoder{data: data}
+	for d.hasNext() {
+		valueType, value, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(types, valueType) {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+// composite writes the header and fills the length after the content is written
+func (e *encoder) composite(valueType uint8, content func() error) error {
+	e.buf = append(e.buf, valueType, 0, 0, 0, 0)
+	start := len(e.buf)
+	if err := content(); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(e.buf[start-4:start], uint32(len(e.buf)-start))
+	return nil
+}
+
+func (e *encoder) bytes(valueType uint8, value []byte) {
+	e.buf = append(e.buf, valueType)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(len(value)))
+	e.buf = append(e.buf, value...)
+}
+
+func (e *encoder) string(valueType uint8, value string) {
+	e.bytes(valueType, []byte(value))
+}
+
+func (e *encoder) long(valueType uint8, value int64) {
+	e.bytes(valueType, binary.LittleEndian.AppendUint64(nil, uint64(value)))
+}
+
+func (e *encoder) int(valueType uint8, value int32) {
+	e.bytes(valueType, binary.LittleEndian.AppendUint32(nil, uint32(value)))
+}
+
+func (e *encoder) timestamp(value time.Time) {
+	data := binary.LittleEndian.AppendUint64(nil, uint64(value.Unix()))
+	data = binary.LittleEndian.AppendUint32(data, uint32(value.Nanosecond()))
+	e.bytes(timestampType, data)
+}
+
+func (e *encoder) groupBatch(batch *GroupBatch) error {
+	return e.composite(groupBatchType, func() error {
+		e.string(bookType, batch.Book)
+		e.string(sessionGroupType, batch.SessionGroup)
+		return e.composite(groupListType, func() error {
+			for _, group := range batch.Groups {
+				if err := e.group(group); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (e *encoder) group(group *MessageGroup) error {
+	return e.composite(messageGroupType, func() error {
+		return e.composite(messageListType, func() error {
+			for _, msg := range group.Messages {
+				if err := e.message(msg); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (e *encoder) message(msg Message) error {
+	switch m := msg.(type) {
+	case *RawMessage:
+		return e.composite(rawMessageType, func() error {
+			e.commonFields(m)
+			e.bytes(rawMessageBodyType, m.Body)
+			return nil
+		})
+	case *ParsedMessage:
+		return e.composite(parsedMessageType, func() error {
+			e.commonFields(m)
+			e.string(messageTypeType, m.Type)
+			e.bytes(parsedMessageRawType, m.RawBody)
+			return nil
+		})
+	default:
+		return fmt.Errorf("unsupported message type %T", msg)
+	}
+}
+
+func (e *encoder) commonFields(msg Message) {
+	_ = e.composite(messageIDType, func() error {
+		id := msg.GetID()
+		e.string(sessionAliasType, id.SessionAlias)
+		e.bytes(directionType, []byte{byte(id.Direction)})
+		e.long(sequenceType, id.Sequence)
+		_ = e.composite(subsequenceType, func() error {
+			for _, subsequence := range id.Subsequence {
+				e.int(intType, subsequence)
+			}
+			return nil
+		})
+		e.timestamp(id.Timestamp)
+		return nil
+	})
+	if eventID := msg.GetEventID(); eventID != nil {
+		_ = e.composite(eventIDType, func() error {
+			e.string(idType, eventID.ID)
+			e.string(bookType, eventID.Book)
+			e.string(scopeType, eventID.Scope)
+			e.timestamp(eventID.Timestamp)
+			return nil
+		})
+	}
+	_ = e.composite(metadataType, func() error {
+		metadata := msg.GetMetadata()
+		// sorted keys make the encoding deterministic
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.string(stringType, key)
+			e.string(stringType, metadata[key])
+		}
+		return nil
+	})
+	e.string(protocolType, msg.GetProtocol())
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) hasNext() bool {
+	return d.pos < len(d.data)
+}
+
+func (d *decoder) next() (uint8, []byte, error) {
+	if len(d.data)-d.pos < headerSize {
+		return 0, nil, ErrTruncated
+	}
+	valueType := d.data[d.pos]
+	length := int(binary.LittleEndian.Uint32(d.data[d.pos+1 : d.pos+headerSize]))
+	start := d.pos + headerSize
+	if length < 0 || len(d.data)-start < length {
+		return 0, nil, ErrTruncated
+	}
+	d.pos = start + length
+	return valueType, d.data[start:d.pos], nil
+}
+
+// forEach calls the handler for each value in the composite value
+func forEach(data []byte, handler func(valueType uint8, value []byte) error) error {
+	d := decoder{data: data}
+	for d.hasNext() {
+		valueType, value, err := d.next()
+		if err != nil {
+			return err
+		}
+		if err := handler(valueType, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeLong(value []byte) (int64, error) {
+	if len(value) != 8 {
+		return 0, fmt.Errorf("long value must have 8 bytes but has %d", len(value))
+	}
+	return int64(binary.LittleEndian.Uint64(value)), nil
+}
+
+func decodeInt(value []byte) (int32, error) {
+	if len(value) != 4 {
+		return 0, fmt.Errorf("int value must have 4 bytes but has %d", len(value))
+	}
+	return int32(binary.LittleEndian.Uint32(value)), nil
+}
+
+func decodeTimestamp(value []byte) (time.Time, error) {
+	if len(value) != 12 {
+		return time.Time{}, fmt.Errorf("timestamp value must have 12 bytes but has %d", len(value))
+	}
+	seconds := int64(binary.LittleEndian.Uint64(value[:8]))
+	nanos := int64(binary.LittleEndian.Uint32(value[8:]))
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+func decodeGroupBatch(data []byte) (*GroupBatch, error) {
+	batch := &GroupBatch{}
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		switch valueType {
+		case bookType:
+			batch.Book = string(value)
+		case sessionGroupType:
+			batch.SessionGroup = string(value)
+		case groupListType:
+			return forEach(value, func(groupType uint8, groupValue []byte) error {
+				if groupType != messageGroupType {
+					return fmt.Errorf("%w %d: expected message group", ErrUnexpectedType, groupType)
+				}
+				group, err := decodeGroup(groupValue)
+				if err != nil {
+					return err
+				}
+				batch.Groups = append(batch.Groups, group)
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func decodeGroup(data []byte) (*MessageGroup, error) {
+	group := &MessageGroup{}
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		if valueType != messageListType {
+			return nil
+		}
+		return forEach(value, func(messageType uint8, messageValue []byte) error {
+			var msg Message
+			var err error
+			switch messageType {
+			case rawMessageType:
+				msg, err = decodeRawMessage(messageValue)
+			case parsedMessageType:
+				msg, err = decodeParsedMessage(messageValue)
+			default:
+				return fmt.Errorf("%w %d: expected raw or parsed message", ErrUnexpectedType, messageType)
+			}
+			if err != nil {
+				return err
+			}
+			group.Messages = append(group.Messages, msg)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func decodeRawMessage(data []byte) (*RawMessage, error) {
+	msg := &RawMessage{}
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		switch valueType {
+		case rawMessageBodyType:
+			msg.Body = value
+			return nil
+		default:
+			return decodeCommonField(valueType, value, &msg.ID, &msg.EventID, &msg.Metadata, &msg.Protocol)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func decodeParsedMessage(data []byte) (*ParsedMessage, error) {
+	msg := &ParsedMessage{}
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		switch valueType {
+		case messageTypeType:
+			msg.Type = string(value)
+			return nil
+		case parsedMessageRawType:
+			msg.RawBody = value
+			return nil
+		default:
+			return decodeCommonField(valueType, value, &msg.ID, &msg.EventID, &msg.Metadata, &msg.Protocol)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func decodeCommonField(valueType uint8, value []byte,
+	id *MessageID, eventID **EventID, metadata *map[string]string, protocol *string) error {
+	var err error
+	switch valueType {
+	case messageIDType:
+		err = decodeMessageID(value, id)
+	case eventIDType:
+		*eventID, err = decodeEventID(value)
+	case metadataType:
+		*metadata, err = decodeMetadata(value)
+	case protocolType:
+		*protocol = string(value)
+	}
+	return err
+}
+
+func decodeMessageID(data []byte, id *MessageID) error {
+	return forEach(data, func(valueType uint8, value []byte) error {
+		var err error
+		switch valueType {
+		case sessionAliasType:
+			id.SessionAlias = string(value)
+		case directionType:
+			if len(value) != 1 {
+				return fmt.Errorf("direction value must have 1 byte but has %d", len(value))
+			}
+			id.Direction = Direction(value[0])
+		case sequenceType:
+			id.Sequence, err = decodeLong(value)
+		case subsequenceType:
+			err = forEach(value, func(_ uint8, subsequenceValue []byte) error {
+				subsequence, err := decodeInt(subsequenceValue)
+				if err != nil {
+					return err
+				}
+				id.Subsequence = append(id.Subsequence, subsequence)
+				return nil
+			})
+		case timestampType:
+			id.Timestamp, err = decodeTimestamp(value)
+		}
+		return err
+	})
+}
+
+func decodeEventID(data []byte) (*EventID, error) {
+	eventID := &EventID{}
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		var err error
+		switch valueType {
+		case idType:
+			eventID.ID = string(value)
+		case bookType:
+			eventID.Book = string(value)
+		case scopeType:
+			eventID.Scope = string(value)
+		case timestampType:
+			eventID.Timestamp, err = decodeTimestamp(value)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eventID, nil
+}
+
+func decodeMetadata(data []byte) (map[string]string, error) {
+	metadata := make(map[string]string)
+	var key *string
+	err := forEach(data, func(valueType uint8, value []byte) error {
+		if valueType != stringType {
+			return fmt.Errorf("%w %d: expected string in metadata", ErrUnexpectedType, valueType)
+		}
+		if key == nil {
+			k := string(value)
+			key = &k
+			return nil
+		}
+		metadata[*key] = string(value)
+		key = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		return nil, fmt.Errorf("metadata key '%s' has no value", *key)
+	}
+	return metadata, nil
+}
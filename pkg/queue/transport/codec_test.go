@@ -0,0 +1,159 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package transport
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createBatch() *GroupBatch {
+	timestamp := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	return &GroupBatch{
+		Book:         "book",
+		SessionGroup: "group",
+		Groups: []*MessageGroup{
+			{
+				Messages: []Message{
+					&RawMessage{
+						ID: MessageID{
+							SessionAlias: "alias",
+							Direction:    Incoming,
+							Sequence:     42,
+							Subsequence:  []int32{1, 2},
+							Timestamp:    timestamp,
+						},
+						EventID: &EventID{
+							ID:        "event",
+							Book:      "book",
+							Scope:     "scope",
+							Timestamp: timestamp,
+						},
+						Metadata: map[string]string{"prop": "value", "another": "one"},
+						Protocol: "fix",
+						Body:     []byte("hello"),
+					},
+					&ParsedMessage{
+						ID: MessageID{
+							SessionAlias: "alias",
+							Direction:    Outgoing,
+							Sequence:     43,
+							Timestamp:    timestamp,
+						},
+						Metadata: map[string]string{},
+						Protocol: "fix",
+						Type:     "NewOrderSingle",
+						RawBody:  []byte{1, 2, 3},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	batch := createBatch()
+	data, err := Encode(batch)
+	if err != nil {
+		t.Fatal("cannot encode batch:", err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatal("cannot decode batch:", err)
+	}
+	if !reflect.DeepEqual(batch, decoded) {
+		t.Errorf("decoded batch %+v differs from original %+v", decoded, batch)
+	}
+}
+
+func TestEncodeWritesTypeAndLength(t *testing.T) {
+	data, err := Encode(&GroupBatch{Book: "b", SessionGroup: "g"})
+	if err != nil {
+		t.Fatal("cannot encode batch:", err)
+	}
+	expected := []byte{
+		groupBatchType, 17, 0, 0, 0,
+		bookType, 1, 0, 0, 0, 'b',
+		sessionGroupType, 1, 0, 0, 0, 'g',
+		groupListType, 0, 0, 0, 0,
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("unexpected encoding: %v", data)
+	}
+}
+
+func TestDecodeSkipsUnknownValues(t *testing.T) {
+	data := []byte{
+		groupBatchType, 18, 0, 0, 0,
+		bookType, 1, 0, 0, 0, 'b',
+		200, 1, 0, 0, 0, 'x',
+		sessionGroupType, 1, 0, 0, 0, 'g',
+	}
+	batch, err := Decode(data)
+	if err != nil {
+		t.Fatal("cannot decode batch:", err)
+	}
+	if batch.Book != "b" || batch.SessionGroup != "g" {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+}
+
+func TestFirstSessionAlias(t *testing.T) {
+	data, err := Encode(createBatch())
+	if err != nil {
+		t.Fatal("cannot encode batch:", err)
+	}
+	alias, err := FirstSessionAlias(data)
+	if err != nil {
+		t.Fatal("cannot read session alias:", err)
+	}
+	if alias != "alias" {
+		t.Errorf("unexpected session alias: %s", alias)
+	}
+
+	data, err = Encode(&GroupBatch{Book: "book"})
+	if err != nil {
+		t.Fatal("cannot encode batch:", err)
+	}
+	alias, err = FirstSessionAlias(data)
+	if err != nil || alias != "" {
+		t.Errorf("unexpected session alias '%s' or error %v for empty batch", alias, err)
+	}
+
+	if _, err = FirstSessionAlias([]byte{groupBatchType, 1}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeTruncatedData(t *testing.T) {
+	data, err := Encode(createBatch())
+	if err != nil {
+		t.Fatal("cannot encode batch:", err)
+	}
+	_, err = Decode(data[:len(data)-1])
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeUnexpectedType(t *testing.T) {
+	_, err := Decode([]byte{messageGroupType, 0, 0, 0, 0})
+	if !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package transport implements the th2 transport protocol.
+// Unlike the protobuf MessageGroupBatch, the book and the session group are stored once on the batch
+// and the parsed message body is kept as opaque bytes produced by the encoding box.
+package transport
+
+import "time"
+
+type Direction uint8
+
+const (
+	Incoming Direction = 1
+	Outgoing Direction = 2
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Incoming:
+		return "INCOMING"
+	case Outgoing:
+		return "OUTGOING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ProtoName returns the name of the same direction in the protobuf protocol (FIRST or SECOND)
+func (d Direction) ProtoName() string {
+	switch d {
+	case Incoming:
+		return "FIRST"
+	case Outgoing:
+		return "SECOND"
+	default:
+		return ""
+	}
+}
+
+type MessageID struct {
+	SessionAlias string
+	Direction    Direction
+	Sequence     int64
+	Subsequence  []int32
+	Timestamp    time.Time
+}
+
+type EventID struct {
+	ID        string
+	Book      string
+	Scope     string
+	Timestamp time.Time
+}
+
+// Message is either *RawMessage or *ParsedMessage
+type Message interface {
+	GetID() *MessageID
+	GetEventID() *EventID
+	GetMetadata() map[string]string
+	GetProtocol() string
+}
+
+type RawMessage struct {
+	ID       MessageID
+	EventID  *EventID
+	Metadata map[string]string
+	Protocol string
+	Body     []byte
+}
+
+func (m *RawMessage) GetID() *MessageID              { return &m.ID }
+func (m *RawMessage) GetEventID() *EventID           { return m.EventID }
+func (m *RawMessage) GetMetadata() map[string]string { return m.Metadata }
+func (m *RawMessage) GetProtocol() string            { return m.Protocol }
+
+type ParsedMessage struct {
+	ID       MessageID
+	EventID  *EventID
+	Metadata map[string]string
+	Protocol string
+	Type     string
+	// RawBody is the encoded message body as it was received
+	RawBody []byte
+}
+
+func (m *ParsedMessage) GetID() *MessageID              { return &m.ID }
+func (m *ParsedMessage) GetEventID() *EventID           { return m.EventID }
+func (m *ParsedMessage) GetMetadata() map[string]string { return m.Metadata }
+func (m *ParsedMessage) GetProtocol() string            { return m.Protocol }
+
+type MessageGroup struct {
+	Messages []Message
+}
+
+type GroupBatch struct {
+	Book         string
+	SessionGroup string
+	Groups       []*MessageGroup
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	OtlpExporter   = "otlp"
+	StdoutExporter = "stdout"
+)
+
+// Config describes how the spans are exported. It is read from the tracing.json file
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Exporter is either otlp or stdout. The default value is otlp
+	Exporter string `json:"exporter"`
+	// Endpoint is the host:port of the OTLP collector. The default value is localhost:4317
+	Endpoint string `json:"endpoint"`
+	// Insecure disables TLS for the connection to the OTLP collector
+	Insecure bool `json:"insecure"`
+	// SampleRatio is the ratio of traces started by the box that are sampled. The default value is 1
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+}
+
+// NewTracerProvider creates the provider exporting the spans of the service as the config describes
+func NewTracerProvider(ctx context.Context, config Config, serviceName string) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	ratio := 1.0
+	if config.SampleRatio != nil {
+		ratio = *config.SampleRatio
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("sampleRatio must be in [0, 1] but was %v", ratio)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	), nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case "", OtlpExporter:
+		var options []otlptracegrpc.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	case StdoutExporter:
+		return stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown exporter %s", config.Exporter)
+	}
+}
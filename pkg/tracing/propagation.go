@@ -0,0 +1,108 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing propagates the OpenTelemetry trace context through the AMQP headers.
+// It uses the global tracer provider and propagator, so nothing is propagated until they are set
+// by the tracing module or by the box itself
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TracerName = "github.com/th2-net/th2-common-go"
+
+// HeadersCarrier adapts amqp.Table to the propagation.TextMapCarrier
+type HeadersCarrier amqp.Table
+
+func (c HeadersCarrier) Get(key string) string {
+	value, ok := c[key].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+func (c HeadersCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c HeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectHeaders adds the trace context from ctx to the headers.
+// The headers are copied, so the passed table is not modified
+func InjectHeaders(ctx context.Context, headers amqp.Table) amqp.Table {
+	carrier := make(HeadersCarrier, len(headers)+2)
+	for key, value := range headers {
+		carrier[key] = value
+	}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return headers
+	}
+	return amqp.Table(carrier)
+}
+
+// ExtractHeaders returns the context holding the trace context from the headers
+func ExtractHeaders(ctx context.Context, headers amqp.Table) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, HeadersCarrier(headers))
+}
+
+// StartPublishSpan starts the producer span for the data published to the exchange
+func StartPublishSpan(ctx context.Context, exchange string, routingKey string) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, "publish "+exchange,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+		),
+	)
+}
+
+// StartConsumeSpan starts the consumer span for the delivery received by the pin.
+// The span is a child of the trace context from the delivery headers
+func StartConsumeSpan(headers amqp.Table, th2Pin string) (context.Context, trace.Span) {
+	ctx := ExtractHeaders(context.Background(), headers)
+	return otel.Tracer(TracerName).Start(ctx, "process "+th2Pin,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("th2.pin", th2Pin),
+		),
+	)
+}
+
+// EndSpan ends the span marking it as failed if err is not nil
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
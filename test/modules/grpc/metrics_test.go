@@ -0,0 +1,199 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonGrpc "github.com/th2-net/th2-common-go/pkg/grpc"
+	"github.com/th2-net/th2-common-go/pkg/modules/grpc"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func findMetric(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) *dto.Metric {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if value, ok := labels[pair.GetName()]; ok && value != pair.GetValue() {
+					continue metrics
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+// counterValue returns the value of the counter. Collectors are shared by the routers, so tests compare values
+func counterValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	return findMetric(t, registry, name, labels).GetCounter().GetValue()
+}
+
+func TestRouterRecordsCallMetricsAndInvokesInterceptors(t *testing.T) {
+	port := freePort(t)
+	address := commonGrpc.Address{Host: "127.0.0.1", Port: port}
+	config := commonGrpc.Config{
+		ServerConfig: commonGrpc.Server{Endpoint: commonGrpc.Endpoint{Address: address}},
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Endpoints:    map[string]commonGrpc.Endpoint{"server": {Address: address}},
+			},
+		},
+	}
+	registry := prometheus.NewRegistry()
+	var serverCalls, clientCalls atomic.Int32
+	mod, err := grpc.NewWithOptions(config, commonGrpc.Options{
+		Registerer: registry,
+		UnaryServerInterceptors: []googleGrpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (any, error) {
+				serverCalls.Add(1)
+				return handler(ctx, req)
+			},
+		},
+		UnaryClientInterceptors: []googleGrpc.UnaryClientInterceptor{
+			func(ctx context.Context, method string, req, reply any, cc *googleGrpc.ClientConn,
+				invoker googleGrpc.UnaryInvoker, opts ...googleGrpc.CallOption) error {
+				clientCalls.Add(1)
+				return invoker(ctx, method, req, reply, cc, opts...)
+			},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mod.Close() })
+
+	stop, err := mod.GetRouter().StartServerAsync(func(registrar googleGrpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(registrar, health.NewServer())
+	})
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	labels := map[string]string{"service": "grpc.health.v1.Health", "method": "Check", "code": "OK"}
+	counters := []string{"th2_grpc_server_requests_total", "th2_grpc_client_requests_total",
+		"th2_grpc_client_message_size_received_bytes"}
+	before := make(map[string]float64, len(counters))
+	for _, name := range counters {
+		before[name] = counterValue(t, registry, name, labels)
+	}
+
+	conn, err := mod.GetRouter().GetConnection("Health")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, googleGrpc.WaitForReady(true))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), serverCalls.Load(), "server interceptor must be invoked")
+	assert.Equal(t, int32(1), clientCalls.Load(), "client interceptor must be invoked")
+
+	assert.Equal(t, 1.0, counterValue(t, registry, "th2_grpc_server_requests_total", labels)-
+		before["th2_grpc_server_requests_total"])
+	assert.Equal(t, 1.0, counterValue(t, registry, "th2_grpc_client_requests_total", labels)-
+		before["th2_grpc_client_requests_total"])
+	assert.Greater(t, counterValue(t, registry, "th2_grpc_client_message_size_received_bytes", labels),
+		before["th2_grpc_client_message_size_received_bytes"], "response size must be recorded")
+	assert.NotNil(t, findMetric(t, registry, "th2_grpc_server_request_duration_seconds", labels))
+}
+
+var uploadService = googleGrpc.ServiceDesc{
+	ServiceName: "test.Upload",
+	HandlerType: (*any)(nil),
+	Streams: []googleGrpc.StreamDesc{{
+		StreamName: "Upload",
+		Handler: func(_ any, stream googleGrpc.ServerStream) error {
+			for {
+				if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+					if errors.Is(err, io.EOF) {
+						return stream.SendMsg(&emptypb.Empty{})
+					}
+					return err
+				}
+			}
+		},
+		ClientStreams: true,
+	}},
+}
+
+func TestRouterRecordsStreamCallMetricsWithoutReceivingEOF(t *testing.T) {
+	port := freePort(t)
+	address := commonGrpc.Address{Host: "127.0.0.1", Port: port}
+	config := commonGrpc.Config{
+		ServerConfig: commonGrpc.Server{Endpoint: commonGrpc.Endpoint{Address: address}},
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Endpoints:    map[string]commonGrpc.Endpoint{"server": {Address: address}},
+			},
+		},
+	}
+	registry := prometheus.NewRegistry()
+	mod, err := grpc.NewWithOptions(config, commonGrpc.Options{Registerer: registry})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mod.Close() })
+
+	stop, err := mod.GetRouter().StartServerAsync(func(registrar googleGrpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(registrar, health.NewServer())
+		registrar.RegisterService(&uploadService, nil)
+	})
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	conn, err := mod.GetRouter().GetConnection("Health")
+	require.NoError(t, err)
+
+	uploadLabels := map[string]string{"service": "test.Upload", "method": "Upload", "code": "OK"}
+	watchLabels := map[string]string{"service": "grpc.health.v1.Health", "method": "Watch", "code": "Canceled"}
+	uploadsBefore := counterValue(t, registry, "th2_grpc_client_requests_total", uploadLabels)
+	watchesBefore := counterValue(t, registry, "th2_grpc_client_requests_total", watchLabels)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	upload, err := conn.NewStream(ctx, &uploadService.Streams[0], "/test.Upload/Upload", googleGrpc.WaitForReady(true))
+	require.NoError(t, err)
+	require.NoError(t, upload.SendMsg(&emptypb.Empty{}))
+	require.NoError(t, upload.CloseSend())
+	require.NoError(t, upload.RecvMsg(&emptypb.Empty{}))
+	assert.Equal(t, 1.0, counterValue(t, registry, "th2_grpc_client_requests_total", uploadLabels)-uploadsBefore,
+		"the call must be recorded when the single response is received")
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	watch, err := healthpb.NewHealthClient(conn).Watch(watchCtx, &healthpb.HealthCheckRequest{}, googleGrpc.WaitForReady(true))
+	require.NoError(t, err)
+	_, err = watch.Recv()
+	require.NoError(t, err)
+	cancelWatch()
+	assert.Eventually(t, func() bool {
+		return counterValue(t, registry, "th2_grpc_client_requests_total", watchLabels)-watchesBefore == 1
+	}, time.Second, 10*time.Millisecond, "the call must be recorded when its context is done")
+}
@@ -17,10 +17,13 @@
 package grpc
 
 import (
+	"github.com/stretchr/testify/assert"
 	"github.com/th2-net/th2-common-go/pkg/modules/grpc"
 	"github.com/th2-net/th2-common-go/test/modules/internal"
+	googleGrpc "google.golang.org/grpc"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 func TestCanRegisterGrpc(t *testing.T) {
@@ -51,3 +54,43 @@ func TestCanRegisterGrpc(t *testing.T) {
 		t.Fatal("module is nil")
 	}
 }
+
+func TestGrpcModuleDrivesProbesFromServerState(t *testing.T) {
+	cfg := `
+    {
+		"server": {
+			"host": "127.0.0.1",
+			"port": 0
+		},
+		"services": {
+		}
+	}
+    `
+	health := internal.NewTestPrometheus()
+	factory := internal.CreateTestFactoryWithPrometheus(fstest.MapFS{
+		"grpc": &fstest.MapFile{
+			Data: []byte(cfg),
+		},
+	}, health)
+	if err := factory.Register(grpc.NewModule); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := grpc.ModuleID.GetModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, health.Readiness.IsEnabled(), "router without server must be ready")
+
+	stop, err := mod.GetRouter().StartServerAsync(func(registrar googleGrpc.ServiceRegistrar) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, health.Readiness.IsEnabled())
+	assert.True(t, health.Liveness.IsEnabled())
+
+	stop()
+	assert.Eventually(t, func() bool {
+		return !health.Readiness.IsEnabled()
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, health.Liveness.IsEnabled(), "stopped server must not affect liveness")
+}
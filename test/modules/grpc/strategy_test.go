@@ -0,0 +1,151 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonGrpc "github.com/th2-net/th2-common-go/pkg/grpc"
+	"github.com/th2-net/th2-common-go/pkg/modules/grpc"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// startHealthServer starts the server reporting the status to distinguish endpoints
+func startHealthServer(t *testing.T, servingStatus healthpb.HealthCheckResponse_ServingStatus) commonGrpc.Address {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", servingStatus)
+	server := googleGrpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return commonGrpc.Address{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+}
+
+func createHealthClient(t *testing.T, strategy commonGrpc.Strategy) healthpb.HealthClient {
+	return createHealthClientFor(t, strategy, []string{"primary"}, []string{"secondary"})
+}
+
+func createHealthClientFor(t *testing.T, strategy commonGrpc.Strategy, servingAttributes []string, notServingAttributes []string) healthpb.HealthClient {
+	config := commonGrpc.Config{
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Strategy:     strategy,
+				Endpoints: map[string]commonGrpc.Endpoint{
+					"serving": {
+						Attributes: servingAttributes,
+						Address:    startHealthServer(t, healthpb.HealthCheckResponse_SERVING),
+					},
+					"not-serving": {
+						Attributes: notServingAttributes,
+						Address:    startHealthServer(t, healthpb.HealthCheckResponse_NOT_SERVING),
+					},
+				},
+			},
+		},
+	}
+	mod, err := grpc.New(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mod.Close() })
+	conn, err := mod.GetRouter().GetConnection("Health")
+	require.NoError(t, err)
+	return healthpb.NewHealthClient(conn)
+}
+
+func check(t *testing.T, ctx context.Context, client healthpb.HealthClient) healthpb.HealthCheckResponse_ServingStatus {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	response, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	return response.Status
+}
+
+func TestRobinStrategyRotatesEndpoints(t *testing.T) {
+	client := createHealthClient(t, commonGrpc.Strategy{Name: commonGrpc.RobinStrategy})
+
+	first := check(t, context.Background(), client)
+	second := check(t, context.Background(), client)
+	third := check(t, context.Background(), client)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first, third)
+}
+
+func TestFilterStrategyUsesEndpointAttributes(t *testing.T) {
+	client := createHealthClient(t, commonGrpc.Strategy{Name: commonGrpc.FilterStrategy})
+
+	ctx := commonGrpc.WithAttributes(context.Background(), "secondary")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, ctx, client))
+	}
+	ctx = commonGrpc.WithAttributes(context.Background(), "primary")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(t, ctx, client))
+
+	_, err := client.Check(commonGrpc.WithAttributes(ctx, "secondary"), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestWithAttributesDoesNotShareAttributesOfSiblingContexts(t *testing.T) {
+	client := createHealthClientFor(t, commonGrpc.Strategy{Name: commonGrpc.FilterStrategy},
+		[]string{"shared", "primary"}, []string{"shared", "secondary"})
+
+	// the parent attributes are added one by one, so the slice has a spare capacity
+	parent := context.Background()
+	for i := 0; i < 3; i++ {
+		parent = commonGrpc.WithAttributes(parent, "shared")
+	}
+	primary := commonGrpc.WithAttributes(parent, "primary")
+	secondary := commonGrpc.WithAttributes(parent, "secondary")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(t, primary, client))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, secondary, client))
+}
+
+func TestStrategyEndpointsLimitUsedEndpoints(t *testing.T) {
+	client := createHealthClient(t, commonGrpc.Strategy{Endpoints: []string{"not-serving"}})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, context.Background(), client))
+	}
+}
+
+func TestGetConnectionFailsForUnknownStrategyEndpoint(t *testing.T) {
+	mod, err := grpc.New(commonGrpc.Config{
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Strategy:     commonGrpc.Strategy{Endpoints: []string{"unknown"}},
+				Endpoints: map[string]commonGrpc.Endpoint{
+					"first":  {Address: commonGrpc.Address{Host: "127.0.0.1", Port: 1}},
+					"second": {Address: commonGrpc.Address{Host: "127.0.0.1", Port: 2}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer mod.Close()
+	_, err = mod.GetRouter().GetConnection("Health")
+	assert.ErrorContains(t, err, "unknown endpoint")
+}
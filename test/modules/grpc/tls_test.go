@@ -0,0 +1,123 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonGrpc "github.com/th2-net/th2-common-go/pkg/grpc"
+	"github.com/th2-net/th2-common-go/pkg/modules/grpc"
+	"github.com/th2-net/th2-common-go/test/modules/certificates"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func createTLSConfig(t *testing.T, certs certificates.Certificates, clientTLS *commonGrpc.ClientTLSConfig) commonGrpc.Config {
+	port := freePort(t)
+	address := commonGrpc.Address{Host: certificates.ServerName, Port: port}
+	return commonGrpc.Config{
+		ServerConfig: commonGrpc.Server{
+			Endpoint: commonGrpc.Endpoint{Address: address},
+			Workers:  2,
+			TLS: &commonGrpc.ServerTLSConfig{
+				Certificate:         certs.ServerCert,
+				PrivateKey:          certs.ServerKey,
+				ClientCACertificate: certs.CAFile,
+			},
+			KeepAlive: &commonGrpc.ServerKeepAlive{
+				KeepAlive: commonGrpc.KeepAlive{Time: 10000, Timeout: 1000},
+				MinTime:   10000,
+			},
+		},
+		ClientConfig: commonGrpc.Client{
+			TLS:                   clientTLS,
+			KeepAlive:             &commonGrpc.KeepAlive{Time: 10000, Timeout: 1000},
+			MaxReceiveMessageSize: 1024 * 1024,
+		},
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Endpoints: map[string]commonGrpc.Endpoint{
+					"server": {Address: address},
+				},
+			},
+		},
+	}
+}
+
+func checkHealth(t *testing.T, config commonGrpc.Config) error {
+	mod, err := grpc.NewWithOptions(config, commonGrpc.Options{
+		DialOptions: []googleGrpc.DialOption{googleGrpc.WithUserAgent("tls-test")},
+	})
+	require.NoError(t, err)
+	defer mod.Close()
+	stop, err := mod.GetRouter().StartServerAsync(func(registrar googleGrpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(registrar, health.NewServer())
+	})
+	require.NoError(t, err)
+	defer stop()
+
+	conn, err := mod.GetRouter().GetConnection("Health")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestRouterUsesMutualTLS(t *testing.T) {
+	certs := certificates.Create(t)
+	config := createTLSConfig(t, certs, &commonGrpc.ClientTLSConfig{
+		CACertificate: certs.CAFile,
+		Certificate:   certs.ClientCert,
+		PrivateKey:    certs.ClientKey,
+	})
+	assert.NoError(t, checkHealth(t, config))
+}
+
+func TestRouterRejectsClientWithoutCertificate(t *testing.T) {
+	certs := certificates.Create(t)
+	config := createTLSConfig(t, certs, &commonGrpc.ClientTLSConfig{
+		CACertificate: certs.CAFile,
+	})
+	assert.Error(t, checkHealth(t, config))
+}
+
+func TestStartServerFailsWithInvalidTLSConfig(t *testing.T) {
+	mod, err := grpc.New(commonGrpc.Config{
+		ServerConfig: commonGrpc.Server{
+			Endpoint: commonGrpc.Endpoint{Address: commonGrpc.Address{Host: "127.0.0.1", Port: freePort(t)}},
+			TLS:      &commonGrpc.ServerTLSConfig{Certificate: "missing.pem", PrivateKey: "missing-key.pem"},
+		},
+	})
+	require.NoError(t, err)
+	defer mod.Close()
+	_, err = mod.GetRouter().StartServerAsync(func(googleGrpc.ServiceRegistrar) {})
+	assert.ErrorContains(t, err, "cannot load server certificate")
+}
@@ -19,10 +19,14 @@ package internal
 import (
 	"encoding/json"
 	"errors"
+	client "github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/th2-net/th2-common-go/pkg/common"
 	"github.com/th2-net/th2-common-go/pkg/log"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
 	"io/fs"
+	"sync/atomic"
 )
 
 func CreateTestFactory(fileSystem fs.FS) common.Factory {
@@ -32,10 +36,77 @@ func CreateTestFactory(fileSystem fs.FS) common.Factory {
 	}
 }
 
+// CreateTestFactoryWithPrometheus is the same as CreateTestFactory
+// but passes the health arbiters and the metrics registry to the modules as the common factory does
+func CreateTestFactoryWithPrometheus(fileSystem fs.FS, prom *TestPrometheus) common.Factory {
+	return &dummyFactory{
+		store:    make(map[common.ModuleKey]common.Module),
+		provider: prometheusProvider{testProvider: testProvider{fs: fileSystem}, TestPrometheus: prom},
+	}
+}
+
 type testProvider struct {
 	fs fs.FS
 }
 
+type prometheusProvider struct {
+	testProvider
+	*TestPrometheus
+}
+
+var _ prometheus.Health = &TestPrometheus{}
+var _ prometheus.Metrics = &TestPrometheus{}
+
+// TestPrometheus holds the arbiters with flags and the registry which can be checked in tests
+type TestPrometheus struct {
+	Liveness  *TestFlag
+	Readiness *TestFlag
+	Registry  *client.Registry
+
+	livenessArbiter  *metrics.FlagArbiter
+	readinessArbiter *metrics.FlagArbiter
+}
+
+func NewTestPrometheus() *TestPrometheus {
+	liveness := &TestFlag{}
+	readiness := &TestFlag{}
+	return &TestPrometheus{
+		Liveness:         liveness,
+		Readiness:        readiness,
+		Registry:         client.NewRegistry(),
+		livenessArbiter:  metrics.NewFlagArbiter(liveness),
+		readinessArbiter: metrics.NewFlagArbiter(readiness),
+	}
+}
+
+func (h *TestPrometheus) GetLivenessArbiter() *metrics.FlagArbiter {
+	return h.livenessArbiter
+}
+
+func (h *TestPrometheus) GetReadinessArbiter() *metrics.FlagArbiter {
+	return h.readinessArbiter
+}
+
+func (h *TestPrometheus) GetRegistry() *client.Registry {
+	return h.Registry
+}
+
+type TestFlag struct {
+	enabled atomic.Bool
+}
+
+func (f *TestFlag) IsEnabled() bool {
+	return f.enabled.Load()
+}
+
+func (f *TestFlag) Enable() {
+	f.enabled.Store(true)
+}
+
+func (f *TestFlag) Disable() {
+	f.enabled.Store(false)
+}
+
 func (p testProvider) GetBoxConfig() common.BoxConfig {
 	return common.BoxConfig{
 		Name: "test",
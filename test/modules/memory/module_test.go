@@ -0,0 +1,1677 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/th2-net/th2-common-go/pkg/factory"
+	"github.com/th2-net/th2-common-go/pkg/modules/queue"
+	commonQueue "github.com/th2-net/th2-common-go/pkg/queue"
+	"github.com/th2-net/th2-common-go/pkg/queue/memory"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq"
+	"github.com/th2-net/th2-common-go/pkg/queue/rabbitmq/connection"
+	"github.com/th2-net/th2-common-go/pkg/queue/transport"
+	"github.com/th2-net/th2-common-go/test/modules/internal"
+	rabbitmqSupport "github.com/th2-net/th2-common-go/test/modules/rabbitmq"
+	grpcCommon "github.com/th2-net/th2-grpc-common-go"
+	"google.golang.org/protobuf/proto"
+)
+
+const mqCfg = `{
+  "queues": {
+    "pub-pin": {
+      "attributes": ["publish", "raw"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": ""
+    },
+    "sub-pin": {
+      "attributes": ["subscribe", "raw"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "queue"
+    },
+    "event-pub-pin": {
+      "attributes": ["publish", "event"],
+      "exchange": "exchange",
+      "name": "event_key",
+      "queue": ""
+    },
+    "event-sub-pin": {
+      "attributes": ["subscribe", "event"],
+      "exchange": "exchange",
+      "name": "event_key",
+      "queue": "event_queue"
+    },
+    "batched-pub-pin": {
+      "attributes": ["publish", "batched"],
+      "exchange": "exchange",
+      "name": "batched_key",
+      "queue": "",
+      "batching": {
+        "maxGroups": 3,
+        "flushInterval": 60000
+      }
+    },
+    "transport-pub-pin": {
+      "attributes": ["publish", "transport-group"],
+      "exchange": "exchange",
+      "name": "transport_key",
+      "queue": "",
+      "filters": [
+        {
+          "metadata": {
+            "session_alias": {
+              "value": "alias",
+              "operation": "EQUAL"
+            }
+          }
+        }
+      ]
+    },
+    "retry-pub-pin": {
+      "attributes": ["publish", "retry"],
+      "exchange": "exchange",
+      "name": "retry_key",
+      "queue": ""
+    },
+    "retry-sub-pin": {
+      "attributes": ["subscribe", "retry"],
+      "exchange": "exchange",
+      "name": "retry_key",
+      "queue": "retry_queue",
+      "retry": {
+        "maxAttempts": 3,
+        "backoff": 1,
+        "deadLetterExchange": "dlx",
+        "deadLetterRoutingKey": "dead"
+      }
+    },
+    "ordered-pub-pin": {
+      "attributes": ["publish", "ordered"],
+      "exchange": "exchange",
+      "name": "ordered_key",
+      "queue": ""
+    },
+    "ordered-sub-pin": {
+      "attributes": ["subscribe", "ordered"],
+      "exchange": "exchange",
+      "name": "ordered_key",
+      "queue": "ordered_queue",
+      "workers": {
+        "count": 4,
+        "preserveOrder": true
+      }
+    },
+    "parallel-pub-pin": {
+      "attributes": ["publish", "parallel"],
+      "exchange": "exchange",
+      "name": "parallel_key",
+      "queue": ""
+    },
+    "parallel-sub-pin": {
+      "attributes": ["subscribe", "parallel"],
+      "exchange": "exchange",
+      "name": "parallel_key",
+      "queue": "parallel_queue",
+      "workers": {
+        "count": 2
+      }
+    },
+    "standby-sub-pin": {
+      "attributes": ["subscribe", "standby"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "queue",
+      "waitForExclusive": true
+    },
+    "transport-sub-pin": {
+      "attributes": ["subscribe", "transport-group"],
+      "exchange": "exchange",
+      "name": "transport_key",
+      "queue": "transport_queue"
+    }
+  }
+}`
+
+func createModule(t *testing.T) queue.InMemoryModule {
+	return createModuleFor(t, mqCfg)
+}
+
+func createModuleFor(t *testing.T, cfg string) queue.InMemoryModule {
+	factory := internal.CreateTestFactory(fstest.MapFS{
+		"mq": &fstest.MapFile{
+			Data: []byte(cfg),
+		},
+	})
+	if err := factory.Register(queue.NewInMemoryModule); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := queue.ModuleID.GetInMemoryModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mod.Close(); err != nil {
+			t.Error("cannot close module", err)
+		}
+	})
+	return mod
+}
+
+func createBatch() *grpcCommon.MessageGroupBatch {
+	return createBatchFor("alias", 42)
+}
+
+func createBatchFor(sessionAlias string, sequence int64) *grpcCommon.MessageGroupBatch {
+	return &grpcCommon.MessageGroupBatch{
+		Groups: []*grpcCommon.MessageGroup{
+			{
+				Messages: []*grpcCommon.AnyMessage{
+					{
+						Kind: &grpcCommon.AnyMessage_RawMessage{
+							RawMessage: &grpcCommon.RawMessage{
+								Body: []byte("hello"),
+								Metadata: &grpcCommon.RawMessageMetadata{
+									Id: &grpcCommon.MessageID{
+										BookName:     rabbitmqSupport.TestBook,
+										ConnectionId: &grpcCommon.ConnectionID{SessionAlias: sessionAlias},
+										Direction:    grpcCommon.Direction_FIRST,
+										Sequence:     sequence,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCanRegisterInMemoryModule(t *testing.T) {
+	factory := internal.CreateTestFactory(fstest.MapFS{
+		"mq": &fstest.MapFile{
+			Data: []byte(mqCfg),
+		},
+	})
+	if err := factory.Register(queue.NewInMemoryModule); err != nil {
+		t.Fatal(err)
+	}
+	var mod queue.Module
+	mod, err := queue.ModuleID.GetModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mod.Close()
+	if mod == nil {
+		t.Fatal("module is nil")
+	}
+}
+
+func TestInMemoryRoutersMetricsAreHeldBySingleRegistry(t *testing.T) {
+	first := prometheus.NewRegistry()
+	_, _, _, closer, err := rabbitmq.NewInMemoryRoutersWithRegisterer(memory.NewBroker(zerolog.Nop()), &commonQueue.RouterConfig{}, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := prometheus.NewRegistry()
+	_, _, _, _, err = rabbitmq.NewInMemoryRoutersWithRegisterer(memory.NewBroker(zerolog.Nop()), &commonQueue.RouterConfig{}, second)
+	assert.ErrorContains(t, err, "already registered in another registerer",
+		"the values of the routers metrics must not be shared by registries")
+
+	_, _, _, sameCloser, err := rabbitmq.NewInMemoryRoutersWithRegisterer(memory.NewBroker(zerolog.Nop()), &commonQueue.RouterConfig{}, first)
+	if err != nil {
+		t.Fatal("routers must share the registry", err)
+	}
+	assert.NoError(t, closer.Close())
+	assert.NoError(t, sameCloser.Close())
+
+	_, _, _, secondCloser, err := rabbitmq.NewInMemoryRoutersWithRegisterer(memory.NewBroker(zerolog.Nop()), &commonQueue.RouterConfig{}, second)
+	if err != nil {
+		t.Fatal("metrics must be released by closed routers", err)
+	}
+	assert.NoError(t, secondCloser.Close())
+	families, err := first.Gather()
+	assert.NoError(t, err)
+	assert.Empty(t, families)
+}
+
+// metricValue sums the counters and the numbers of histogram observations of the metric with the label
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, label string, value string) float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() == label && pair.GetValue() == value {
+					sum += metric.GetCounter().GetValue() + float64(metric.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+	}
+	return sum
+}
+
+func TestInMemoryRoutersRecordPublicationAndDeliveryMetrics(t *testing.T) {
+	prom := internal.NewTestPrometheus()
+	factory := internal.CreateTestFactoryWithPrometheus(fstest.MapFS{
+		"mq": &fstest.MapFile{Data: []byte(mqCfg)},
+	}, prom)
+	if err := factory.Register(queue.NewInMemoryModule); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := queue.ModuleID.GetInMemoryModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mod.Close() })
+	router := mod.GetMessageRouter()
+
+	metrics := []struct{ name, label, value string }{
+		{"th2_rabbitmq_message_publish_total", "th2_pin", "pub-pin"},
+		{"th2_rabbitmq_message_size_publish_bytes", "th2_pin", "pub-pin"},
+		{"th2_rabbitmq_message_size_subscribe_bytes", "queue", "queue"},
+		{"th2_rabbitmq_message_process_duration_seconds", "queue", "queue"},
+	}
+	before := make([]float64, len(metrics))
+	for index, metric := range metrics {
+		before[index] = metricValue(t, prom.Registry, metric.name, metric.label, metric.value)
+	}
+
+	deliveries := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{
+		Channel: deliveries,
+	}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+	batch := createBatch()
+	if err := router.SendAll(batch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, batch)
+
+	for index, metric := range metrics {
+		assert.Eventually(t, func() bool {
+			return metricValue(t, prom.Registry, metric.name, metric.label, metric.value) > before[index]
+		}, time.Second, 10*time.Millisecond, "%s must be recorded", metric.name)
+	}
+}
+
+func TestInMemoryBrokerSettlesNothingForUnknownDeliveryTag(t *testing.T) {
+	broker := memory.NewBroker(zerolog.Nop())
+	t.Cleanup(func() { _ = broker.Close() })
+	received := make(chan amqp.Delivery, 3)
+	cancel, _, err := broker.ConsumeWithManualAck(context.Background(), "settle_queue", "pin", "type", connection.ConsumeOptions{},
+		func(delivery amqp.Delivery, _ *prometheus.Timer) error {
+			received <- delivery
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	var deliveries []amqp.Delivery
+	receive := func() amqp.Delivery {
+		select {
+		case delivery := <-received:
+			return delivery
+		case <-time.After(time.Second):
+			t.Fatal("delivery was not received")
+			return amqp.Delivery{}
+		}
+	}
+	for _, body := range []string{"first", "second", "third"} {
+		if err := broker.Deliver("settle_queue", []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		deliveries = append(deliveries, receive())
+	}
+	first, second, third := deliveries[0], deliveries[1], deliveries[2]
+	if err := first.Ack(false); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.ErrorIs(t, second.Acknowledger.Ack(first.DeliveryTag, true), memory.ErrUnknownDeliveryTag)
+	assert.ErrorIs(t, second.Acknowledger.Ack(third.DeliveryTag+1, true), memory.ErrUnknownDeliveryTag)
+	stats := broker.Stats("settle_queue")
+	assert.Equal(t, 1, stats.Acknowledged)
+	assert.Equal(t, 2, stats.Unacked, "nothing must be settled when the tag is unknown")
+
+	assert.NoError(t, third.Nack(true, true))
+	for _, expected := range []string{"second", "third"} {
+		redelivered := receive()
+		assert.Equal(t, expected, string(redelivered.Body), "requeued deliveries must keep their order")
+		assert.True(t, redelivered.Redelivered)
+	}
+	assert.Equal(t, 2, broker.Stats("settle_queue").Requeued)
+}
+
+func TestInMemoryMessageRouterDeliversBetweenPins(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	deliveries := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{
+		Channel: deliveries,
+	}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	originalBatch := createBatch()
+	if err := router.SendAll(originalBatch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, originalBatch)
+
+	published, err := mod.GetBroker().PublishedMessages("pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, published, 1) {
+		assert.True(t, proto.Equal(originalBatch, published[0]))
+	}
+	assert.Empty(t, mod.GetBroker().Published("sub-pin"))
+}
+
+func TestInMemoryBrokerRoutesDefaultExchangeOnce(t *testing.T) {
+	broker := createModule(t).GetBroker()
+	broker.Bind("", "direct", "direct")
+	broker.Bind("", "direct", "bound")
+
+	if err := broker.Publish(context.Background(), []byte("data"), "direct", "", "pin", "raw"); err != nil {
+		t.Fatal("cannot publish data", err)
+	}
+	assert.Equal(t, 1, broker.Stats("direct").Pending, "queue with the routing key name must receive the data once")
+	assert.Equal(t, 1, broker.Stats("bound").Pending)
+}
+
+func TestInMemoryEventRouterManualAck(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetEventRouter()
+
+	deliveries := make(chan *grpcCommon.EventBatch, 2)
+	monitor, err := router.SubscribeAllWithManualAck(&rabbitmqSupport.GenericManualListener[grpcCommon.EventBatch]{
+		Channel:        deliveries,
+		OnConfirmation: rabbitmqSupport.Confirm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	batch := &grpcCommon.EventBatch{
+		Events: []*grpcCommon.Event{
+			{
+				Id:   &grpcCommon.EventID{Id: "id", BookName: rabbitmqSupport.TestBook},
+				Name: "test",
+			},
+		},
+	}
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, batch)
+
+	assert.Eventually(t, func() bool {
+		return mod.GetBroker().Stats("event_queue").Acknowledged == 1
+	}, time.Second, 10*time.Millisecond)
+	events, err := mod.GetBroker().PublishedEvents("event-pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, events, 1)
+}
+
+func TestInMemoryRouterSendAllCtxStopsOnCancelledContext(t *testing.T) {
+	mod := createModule(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := mod.GetMessageRouter().SendAllCtx(ctx, createBatch(), "raw")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, mod.GetBroker().Published("pub-pin"))
+}
+
+func TestInMemoryBatchingSenderAccumulatesGroups(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	for i := 0; i < 4; i++ {
+		if err := router.SendAll(createBatch(), "batched"); err != nil {
+			t.Fatal("cannot send batch", err)
+		}
+	}
+	published, err := mod.GetBroker().PublishedMessages("batched-pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, published, 1) {
+		assert.Len(t, published[0].Groups, 3)
+	}
+
+	if err := router.Close(); err != nil {
+		t.Fatal("cannot close router", err)
+	}
+	published, err = mod.GetBroker().PublishedMessages("batched-pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, published, 2, "remaining groups must be sent on close") {
+		assert.Len(t, published[1].Groups, 1)
+	}
+}
+
+func TestInMemoryBatchingSenderBypassesBatchWithProperties(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	if err := router.SendAll(createBatch(), "batched"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	ctx := commonQueue.WithProperties(context.Background(), commonQueue.Properties{MessageID: "message-1"})
+	if err := router.SendAllCtx(ctx, createBatch(), "batched"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	published := mod.GetBroker().Published("batched-pub-pin")
+	if assert.Len(t, published, 2, "accumulated groups must be sent before the batch with properties") {
+		assert.Empty(t, published[0].Properties.MessageID)
+		assert.Equal(t, "message-1", published[1].Properties.MessageID)
+	}
+	messages, err := mod.GetBroker().PublishedMessages("batched-pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, messages, 2) {
+		assert.Len(t, messages[0].Groups, 1)
+		assert.Len(t, messages[1].Groups, 1)
+	}
+}
+
+// createWatchedModule creates the module watching mq.json, the returned function rewrites the file
+func createWatchedModule(t *testing.T, cfg string) (queue.InMemoryModule, func(cfg string)) {
+	dir := t.TempDir()
+	mqFile := filepath.Join(dir, "mq.json")
+	update := func(cfg string) {
+		if err := os.WriteFile(mqFile, []byte(cfg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	update(cfg)
+	provider := factory.NewWatchingFileProviderForFS(os.DirFS(dir), ".json", 10*time.Millisecond, zerolog.Nop())
+	t.Cleanup(func() { _ = provider.(io.Closer).Close() })
+	module, err := queue.NewInMemoryModule(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := module.(queue.InMemoryModule)
+	t.Cleanup(func() { _ = mod.Close() })
+	return mod, update
+}
+
+func TestInMemoryModuleAppliesAddedPins(t *testing.T) {
+	mod, update := createWatchedModule(t, mqCfg)
+
+	router := mod.GetMessageRouter()
+	assert.Error(t, router.SendAll(createBatch(), "added"))
+
+	update(strings.Replace(mqCfg, `"queues": {`, `"queues": {
+    "added-pin": {
+      "attributes": ["publish", "added"],
+      "exchange": "exchange",
+      "name": "added_key",
+      "queue": ""
+    },`, 1))
+	assert.Eventually(t, func() bool {
+		return router.SendAll(createBatch(), "added") == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, mod.GetBroker().Published("added-pin"), 1)
+}
+
+func TestInMemoryModuleStopsSubscriptionOfRemovedPin(t *testing.T) {
+	mod, update := createWatchedModule(t, mqCfg)
+
+	listener := &blockingListener{started: make(chan struct{}, 1), release: make(chan struct{})}
+	monitor, err := mod.GetMessageRouter().SubscribeAll(listener, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update(strings.Replace(mqCfg, `    "sub-pin": {
+      "attributes": ["subscribe", "raw"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "queue"
+    },
+`, "", 1))
+	assert.Eventually(t, listener.closed.Load, time.Second, 10*time.Millisecond, "listener of removed pin must be closed")
+	waitUnsubscribed(t, monitor)
+}
+
+func TestInMemoryModuleResubscribesChangedPin(t *testing.T) {
+	mod, update := createWatchedModule(t, mqCfg)
+	router := mod.GetMessageRouter()
+
+	received := make(chan *grpcCommon.MessageGroupBatch, 100)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: received}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update(strings.Replace(mqCfg, `"name": "key",
+      "queue": "queue"`, `"name": "key",
+      "queue": "changed_queue"`, 1))
+	assert.Eventually(t, func() bool {
+		if err := router.SendAll(createBatch(), "raw"); err != nil {
+			t.Fatal("cannot send batch", err)
+		}
+		return mod.GetBroker().Stats("changed_queue").Delivered > 0
+	}, time.Second, 10*time.Millisecond, "subscription must consume the changed queue")
+
+	// the monitor returned before the change controls the new subscription
+	if err := monitor.Unsubscribe(); err != nil {
+		t.Fatal("cannot unsubscribe", err)
+	}
+	waitUnsubscribed(t, monitor)
+	pending := mod.GetBroker().Stats("changed_queue").Pending
+	if err := router.SendAll(createBatch(), "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Equal(t, pending+1, mod.GetBroker().Stats("changed_queue").Pending)
+}
+
+func createTransportBatch(sessionAlias string) *transport.GroupBatch {
+	return &transport.GroupBatch{
+		Book:         rabbitmqSupport.TestBook,
+		SessionGroup: "group",
+		Groups: []*transport.MessageGroup{
+			{
+				Messages: []transport.Message{
+					&transport.RawMessage{
+						ID: transport.MessageID{
+							SessionAlias: sessionAlias,
+							Direction:    transport.Incoming,
+							Sequence:     42,
+							Timestamp:    time.Now().UTC(),
+						},
+						Metadata: map[string]string{"prop": "value"},
+						Body:     []byte("hello"),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestInMemoryTransportRouterFiltersAndDelivers(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetTransportRouter()
+
+	deliveries := make(chan *transport.GroupBatch, 2)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[transport.GroupBatch]{
+		Channel: deliveries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createTransportBatch("other")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	batch := createTransportBatch("alias")
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	select {
+	case received := <-deliveries:
+		assert.Equal(t, batch, received)
+	case <-time.After(time.Second):
+		t.Fatal("batch was not received")
+	}
+	assert.Len(t, mod.GetBroker().Published("transport-pub-pin"), 1)
+	// transport pins must not be used by the protobuf router
+	assert.Empty(t, mod.GetBroker().Published("pub-pin"))
+	_, err = mod.GetMessageRouter().SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{}, "transport-group")
+	assert.Error(t, err)
+}
+
+type failingListener struct {
+	attempts chan struct{}
+}
+
+func (l *failingListener) Handle(commonQueue.Delivery, *grpcCommon.MessageGroupBatch) error {
+	l.attempts <- struct{}{}
+	return errors.New("cannot handle")
+}
+
+func (l *failingListener) OnClose() error {
+	return nil
+}
+
+func checkDeadLettered(t *testing.T, mod queue.InMemoryModule, attempts <-chan struct{}, expectedError string) {
+	for i := 0; i < 3; i++ {
+		select {
+		case <-attempts:
+		case <-time.After(time.Second):
+			t.Fatalf("attempt %d was not made", i+1)
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return mod.GetBroker().Stats("dead_queue").Pending == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool {
+		return len(attempts) > 0
+	}, 50*time.Millisecond, 10*time.Millisecond)
+
+	published := mod.GetBroker().Published("retry-sub-pin")
+	if assert.Len(t, published, 3, "two retries and the dead-lettered delivery") {
+		assert.Equal(t, int32(1), published[0].Headers["x-th2-retry-count"])
+		assert.Equal(t, "retry_queue.retry.1ms", published[0].RoutingKey, "delivery must wait in the delay queue")
+		deadLettered := published[2]
+		assert.Equal(t, "dlx", deadLettered.Exchange)
+		assert.Equal(t, int32(3), deadLettered.Headers["x-th2-retry-count"])
+		assert.Equal(t, expectedError, deadLettered.Headers["x-th2-error"])
+		assert.Equal(t, "retry_queue", deadLettered.Headers["x-th2-queue"])
+	}
+}
+
+func TestInMemoryRetryPolicyDeadLettersFailedDelivery(t *testing.T) {
+	mod := createModule(t)
+	mod.GetBroker().Bind("dlx", "dead", "dead_queue")
+	router := mod.GetMessageRouter()
+
+	listener := &failingListener{attempts: make(chan struct{}, 10)}
+	monitor, err := router.SubscribeAll(listener, "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createBatch(), "retry"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	checkDeadLettered(t, mod, listener.attempts, "cannot handle")
+}
+
+func TestInMemoryRetryPolicyAppliesToRejectedDelivery(t *testing.T) {
+	mod := createModule(t)
+	mod.GetBroker().Bind("dlx", "dead", "dead_queue")
+	router := mod.GetMessageRouter()
+
+	attempts := make(chan struct{}, 10)
+	monitor, err := router.SubscribeAllWithManualAck(&rabbitmqSupport.GenericManualListener[grpcCommon.MessageGroupBatch]{
+		Channel: make(chan *grpcCommon.MessageGroupBatch, 10),
+		OnConfirmation: func(confirmation commonQueue.Confirmation) {
+			attempts <- struct{}{}
+			_ = confirmation.Reject()
+		},
+	}, "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createBatch(), "retry"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	checkDeadLettered(t, mod, attempts, "rejected by listener")
+	assert.Eventually(t, func() bool {
+		stats := mod.GetBroker().Stats("retry_queue")
+		return stats.Acknowledged == 3 && stats.Unacked == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func retryCfg(retry string) string {
+	return `{
+  "queues": {
+    "retry-pub-pin": {
+      "attributes": ["publish", "retry"],
+      "exchange": "exchange",
+      "name": "retry_key",
+      "queue": ""
+    },
+    "retry-sub-pin": {
+      "attributes": ["subscribe", "retry"],
+      "exchange": "exchange",
+      "name": "retry_key",
+      "queue": "retry_queue",
+      "retry": ` + retry + `
+    }
+  }
+}`
+}
+
+func TestInMemoryRetryPolicyRejectsToQueueDeadLetterExchange(t *testing.T) {
+	mod := createModuleFor(t, retryCfg(`{"maxAttempts": 2, "backoff": 1}`))
+	mod.GetBroker().SetDeadLetterExchange("retry_queue", "queue_dlx", "dead")
+	mod.GetBroker().Bind("queue_dlx", "dead", "dead_queue")
+	router := mod.GetMessageRouter()
+
+	listener := &failingListener{attempts: make(chan struct{}, 10)}
+	monitor, err := router.SubscribeAll(listener, "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createBatch(), "retry"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Eventually(t, func() bool {
+		return mod.GetBroker().Stats("dead_queue").Pending == 1
+	}, time.Second, 10*time.Millisecond)
+	stats := mod.GetBroker().Stats("retry_queue")
+	assert.Equal(t, 1, stats.Rejected, "last attempt must be rejected without requeue")
+	assert.Equal(t, 1, stats.Acknowledged, "retried delivery must be acknowledged")
+	assert.Len(t, listener.attempts, 2)
+}
+
+func TestInMemoryRetryDoesNotBlockNextDelivery(t *testing.T) {
+	mod := createModuleFor(t, retryCfg(`{"maxAttempts": 3, "backoff": 60000}`))
+	router := mod.GetMessageRouter()
+
+	listener := &failingListener{attempts: make(chan struct{}, 10)}
+	monitor, err := router.SubscribeAll(listener, "retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+	for i := 0; i < 2; i++ {
+		if err := router.SendAll(createBatch(), "retry"); err != nil {
+			t.Fatal("cannot send batch", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-listener.attempts:
+		case <-time.After(time.Second):
+			t.Fatalf("delivery %d is blocked by the backoff of the previous one", i+1)
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return mod.GetBroker().Stats("retry_queue").Acknowledged == 2
+	}, time.Second, 10*time.Millisecond, "failed deliveries must be acknowledged after they are published for retry")
+	assert.Equal(t, 2, mod.GetBroker().Stats("retry_queue.retry.60000ms").Pending, "failed deliveries must wait in the delay queue")
+}
+
+type recordingListener struct {
+	mutex     sync.Mutex
+	sequences map[string][]int64
+	handled   chan struct{}
+}
+
+func (l *recordingListener) Handle(_ commonQueue.Delivery, batch *grpcCommon.MessageGroupBatch) error {
+	id := batch.Groups[0].Messages[0].GetRawMessage().Metadata.Id
+	// give other workers a chance to overtake the batch
+	time.Sleep(time.Duration(id.Sequence%3) * time.Millisecond)
+	l.mutex.Lock()
+	l.sequences[id.ConnectionId.SessionAlias] = append(l.sequences[id.ConnectionId.SessionAlias], id.Sequence)
+	l.mutex.Unlock()
+	l.handled <- struct{}{}
+	return nil
+}
+
+func (l *recordingListener) OnClose() error {
+	return nil
+}
+
+func TestInMemoryWorkersPreserveOrderPerSessionAlias(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	listener := &recordingListener{sequences: make(map[string][]int64), handled: make(chan struct{}, 60)}
+	monitor, err := router.SubscribeAll(listener, "ordered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	aliases := []string{"alias1", "alias2", "alias3"}
+	for i := 0; i < 60; i++ {
+		if err := router.SendAll(createBatchFor(aliases[i%3], int64(i)), "ordered"); err != nil {
+			t.Fatal("cannot send batch", err)
+		}
+	}
+	for i := 0; i < 60; i++ {
+		select {
+		case <-listener.handled:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d batches are handled", i)
+		}
+	}
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	for _, alias := range aliases {
+		if assert.Len(t, listener.sequences[alias], 20) {
+			assert.IsIncreasing(t, listener.sequences[alias], "batches of %s are reordered", alias)
+		}
+	}
+}
+
+type blockingListener struct {
+	started chan struct{}
+	release chan struct{}
+	closed  atomic.Bool
+}
+
+func (l *blockingListener) Handle(commonQueue.Delivery, *grpcCommon.MessageGroupBatch) error {
+	l.started <- struct{}{}
+	<-l.release
+	return nil
+}
+
+func (l *blockingListener) OnClose() error {
+	l.closed.Store(true)
+	return nil
+}
+
+func TestInMemoryWorkersHandleDeliveriesConcurrently(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	listener := &blockingListener{started: make(chan struct{}, 2), release: make(chan struct{})}
+	monitor, err := router.SubscribeAll(listener, "parallel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+	defer close(listener.release)
+
+	for i := 0; i < 2; i++ {
+		if err := router.SendAll(createBatch(), "parallel"); err != nil {
+			t.Fatal("cannot send batch", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-listener.started:
+		case <-time.After(time.Second):
+			t.Fatalf("batch %d is not handled while the previous one is in progress", i+1)
+		}
+	}
+}
+
+func TestInMemoryWaitForExclusiveWaitsForActiveConsumer(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	active := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: active}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	standby := make(chan *grpcCommon.MessageGroupBatch, 1)
+	standbyMonitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: standby}, "standby")
+	if err != nil {
+		t.Fatal("standby consumer must not fail", err)
+	}
+	defer standbyMonitor.Unsubscribe()
+
+	batch := createBatch()
+	if err := router.SendAll(batch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, active, batch)
+	assert.Empty(t, standby)
+}
+
+func TestInMemoryUnsubscribeStopsConsumerAndAllowsResubscribing(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	first := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: first}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := createBatch()
+	if err := router.SendAll(batch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, first, batch)
+	if err := monitor.Unsubscribe(); err != nil {
+		t.Fatal("cannot unsubscribe", err)
+	}
+
+	if err := router.SendAll(batch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Equal(t, 1, mod.GetBroker().Stats("queue").Pending, "batch must stay in the queue")
+	assert.Empty(t, first)
+
+	second := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err = router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: second}, "raw")
+	if err != nil {
+		t.Fatal("cannot subscribe the pin again", err)
+	}
+	defer monitor.Unsubscribe()
+	rabbitmqSupport.CheckReceiveBatch(t, second, batch)
+}
+
+func waitUnsubscribed(t *testing.T, monitor commonQueue.Monitor) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := monitor.(commonQueue.CompletionWaiter).Wait(ctx); err != nil {
+		t.Fatal("subscription is not completed", err)
+	}
+}
+
+func TestInMemoryUnsubscribeClosesListenerAfterHandling(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	listener := &blockingListener{started: make(chan struct{}, 1), release: make(chan struct{})}
+	monitor, err := router.SubscribeAll(listener, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.SendAll(createBatch(), "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	select {
+	case <-listener.started:
+	case <-time.After(time.Second):
+		t.Fatal("delivery was not handled")
+	}
+
+	unsubscribed := make(chan error, 1)
+	go func() { unsubscribed <- monitor.Unsubscribe() }()
+	select {
+	case err := <-unsubscribed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe waits for the listener")
+	}
+	assert.False(t, listener.closed.Load(), "listener must not be closed while it handles a delivery")
+
+	close(listener.release)
+	waitUnsubscribed(t, monitor)
+	assert.True(t, listener.closed.Load())
+}
+
+type unsubscribingListener struct {
+	monitor      chan commonQueue.Monitor
+	unsubscribed chan error
+}
+
+func (l *unsubscribingListener) Handle(commonQueue.Delivery, *grpcCommon.MessageGroupBatch) error {
+	l.unsubscribed <- (<-l.monitor).Unsubscribe()
+	return nil
+}
+
+func (l *unsubscribingListener) OnClose() error {
+	return nil
+}
+
+func TestInMemoryUnsubscribeFromListener(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	listener := &unsubscribingListener{monitor: make(chan commonQueue.Monitor, 1), unsubscribed: make(chan error, 1)}
+	monitor, err := router.SubscribeAll(listener, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.monitor <- monitor
+	if err := router.SendAll(createBatch(), "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	select {
+	case err := <-listener.unsubscribed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe from the listener is blocked")
+	}
+	// the pin is released when the listener returns
+	assert.Eventually(t, func() bool {
+		resubscribed, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{}, "raw")
+		if err != nil {
+			return false
+		}
+		return resubscribed.Unsubscribe() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInMemoryUnsubscribeRequeuesUnconfirmedDeliveries(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetEventRouter()
+
+	deliveries := make(chan *grpcCommon.EventBatch, 1)
+	monitor, err := router.SubscribeAllWithManualAck(&rabbitmqSupport.GenericManualListener[grpcCommon.EventBatch]{
+		Channel:        deliveries,
+		OnConfirmation: func(commonQueue.Confirmation) {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := &grpcCommon.EventBatch{
+		Events: []*grpcCommon.Event{
+			{
+				Id:   &grpcCommon.EventID{Id: "id", BookName: rabbitmqSupport.TestBook},
+				Name: "test",
+			},
+		},
+	}
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, batch)
+	if err := monitor.Unsubscribe(); err != nil {
+		t.Fatal("cannot unsubscribe", err)
+	}
+	waitUnsubscribed(t, monitor)
+	stats := mod.GetBroker().Stats("event_queue")
+	assert.Equal(t, 0, stats.Unacked)
+	assert.Equal(t, 1, stats.Requeued)
+	assert.Equal(t, 1, stats.Pending)
+
+	monitor, err = router.SubscribeAllWithManualAck(&rabbitmqSupport.GenericManualListener[grpcCommon.EventBatch]{
+		Channel:        deliveries,
+		OnConfirmation: rabbitmqSupport.Confirm,
+	})
+	if err != nil {
+		t.Fatal("cannot subscribe the pin again", err)
+	}
+	defer monitor.Unsubscribe()
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, batch)
+	assert.Eventually(t, func() bool {
+		return mod.GetBroker().Stats("event_queue").Acknowledged == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInMemoryWaitForExclusiveTakesOverAfterUnsubscribe(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	active := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: active}, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	standby := make(chan *grpcCommon.MessageGroupBatch, 1)
+	standbyMonitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{Channel: standby}, "standby")
+	if err != nil {
+		t.Fatal("standby consumer must not fail", err)
+	}
+	defer standbyMonitor.Unsubscribe()
+
+	if err := monitor.Unsubscribe(); err != nil {
+		t.Fatal("cannot unsubscribe", err)
+	}
+	batch := createBatch()
+	if err := router.SendAll(batch, "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, standby, batch)
+	assert.Empty(t, active)
+}
+
+func TestInMemoryModuleRegistersMetricsInFactoryRegistry(t *testing.T) {
+	prom := internal.NewTestPrometheus()
+	factory := internal.CreateTestFactoryWithPrometheus(fstest.MapFS{
+		"mq": &fstest.MapFile{
+			Data: []byte(mqCfg),
+		},
+	}, prom)
+	if err := factory.Register(queue.NewInMemoryModule); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := queue.ModuleID.GetInMemoryModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mod.Close()
+
+	if err := mod.GetMessageRouter().SendAll(createBatch(), "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "th2_message_publish_total")
+}
+
+type deliveryListener struct {
+	deliveries chan commonQueue.Delivery
+}
+
+func (l *deliveryListener) Handle(delivery commonQueue.Delivery, _ *grpcCommon.MessageGroupBatch) error {
+	l.deliveries <- delivery
+	return nil
+}
+
+func (l *deliveryListener) OnClose() error {
+	return nil
+}
+
+func TestInMemoryDeliveryExposesPublishedProperties(t *testing.T) {
+	mod := createModule(t)
+	router := mod.GetMessageRouter()
+
+	listener := &deliveryListener{deliveries: make(chan commonQueue.Delivery, 1)}
+	monitor, err := router.SubscribeAll(listener, "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	timestamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx := commonQueue.WithProperties(context.Background(), commonQueue.Properties{
+		Headers:       map[string]any{"source": "test"},
+		MessageID:     "message-1",
+		CorrelationID: "correlation-1",
+		ContentType:   "application/x-protobuf",
+		Timestamp:     timestamp,
+		Priority:      3,
+	})
+	if err := router.SendAllCtx(ctx, createBatch(), "raw"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	select {
+	case delivery := <-listener.deliveries:
+		assert.Equal(t, "sub-pin", delivery.Pin)
+		assert.Equal(t, "exchange", delivery.Exchange)
+		assert.Equal(t, "key", delivery.RoutingKey)
+		assert.NotZero(t, delivery.DeliveryTag)
+		assert.Equal(t, "test", delivery.Headers["source"])
+		assert.Equal(t, "message-1", delivery.MessageID)
+		assert.Equal(t, "correlation-1", delivery.CorrelationID)
+		assert.Equal(t, "application/x-protobuf", delivery.ContentType)
+		assert.True(t, timestamp.Equal(delivery.Timestamp))
+		assert.Equal(t, uint8(3), delivery.Priority)
+		assert.NotNil(t, delivery.Context)
+	case <-time.After(time.Second):
+		t.Fatal("batch is not delivered")
+	}
+
+	published := mod.GetBroker().Published("pub-pin")
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, "message-1", published[0].Properties.MessageID)
+		assert.Equal(t, "test", published[0].Headers["source"])
+	}
+}
+
+func TestInMemoryRawRouterManualAck(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		confirm    func(confirmation commonQueue.Confirmation)
+		checkStats func(stats memory.QueueStats) bool
+	}{
+		{
+			name:    "confirm",
+			confirm: rabbitmqSupport.Confirm,
+			checkStats: func(stats memory.QueueStats) bool {
+				return stats.Acknowledged == 1 && stats.Unacked == 0
+			},
+		},
+		{
+			name:    "reject",
+			confirm: rabbitmqSupport.Reject,
+			checkStats: func(stats memory.QueueStats) bool {
+				return stats.Rejected == 1 && stats.Unacked == 0
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mod := createModule(t)
+			router := mod.GetMessageRouter()
+
+			deliveries := make(chan []byte, 1)
+			monitor, err := router.SubscribeRawAllWithManualAck(rabbitmqSupport.TestRawManualListener{
+				Channel:        deliveries,
+				OnConfirmation: tc.confirm,
+			}, "raw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer monitor.Unsubscribe()
+
+			if err := router.SendRawAll([]byte("raw data"), "raw"); err != nil {
+				t.Fatal("cannot send data", err)
+			}
+			select {
+			case data := <-deliveries:
+				assert.Equal(t, []byte("raw data"), data)
+			case <-time.After(time.Second):
+				t.Fatal("data is not delivered")
+			}
+			assert.Eventually(t, func() bool {
+				return tc.checkStats(mod.GetBroker().Stats("queue"))
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+const filtersCfg = `{
+  "queues": {
+    "pub-pin": {
+      "attributes": ["publish"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": ""
+    },
+    "sub-pin": {
+      "attributes": ["subscribe"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "queue",
+      "filters": [
+        {
+          "metadata": {
+            "session_alias": {"value": "keep", "operation": "EQUAL"}
+          }
+        }
+      ]
+    },
+    "event-pub-pin": {
+      "attributes": ["publish", "event"],
+      "exchange": "exchange",
+      "name": "event_key",
+      "queue": "",
+      "filters": [
+        {
+          "metadata": {
+            "status": {"value": "SUCCESS", "operation": "EQUAL"}
+          }
+        }
+      ]
+    },
+    "event-sub-pin": {
+      "attributes": ["subscribe", "event"],
+      "exchange": "exchange",
+      "name": "event_key",
+      "queue": "event_queue",
+      "filters": [
+        {
+          "metadata": {
+            "event_type": {"value": "Order", "operation": "EQUAL"}
+          }
+        }
+      ]
+    }
+  }
+}`
+
+func createGroupsBatch(sessionAliases ...string) *grpcCommon.MessageGroupBatch {
+	batch := &grpcCommon.MessageGroupBatch{}
+	for index, alias := range sessionAliases {
+		batch.Groups = append(batch.Groups, createBatchFor(alias, int64(index+1)).Groups...)
+	}
+	return batch
+}
+
+func TestInMemorySubscriberDropsGroupsNotMatchingFilters(t *testing.T) {
+	mod := createModuleFor(t, filtersCfg)
+	router := mod.GetMessageRouter()
+
+	deliveries := make(chan *grpcCommon.MessageGroupBatch, 2)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.MessageGroupBatch]{
+		Channel: deliveries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	batch := createGroupsBatch("drop", "keep", "drop")
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	if err := router.SendAll(createGroupsBatch("drop")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, &grpcCommon.MessageGroupBatch{Groups: batch.Groups[1:2]})
+	assert.Eventually(t, func() bool {
+		stats := mod.GetBroker().Stats("queue")
+		return stats.Delivered == 2 && stats.Pending == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, deliveries, "batch without matching groups must not be delivered")
+}
+
+func TestInMemoryManualSubscriberConfirmsDroppedBatch(t *testing.T) {
+	mod := createModuleFor(t, filtersCfg)
+	router := mod.GetMessageRouter()
+
+	deliveries := make(chan *grpcCommon.MessageGroupBatch, 1)
+	monitor, err := router.SubscribeAllWithManualAck(&rabbitmqSupport.GenericManualListener[grpcCommon.MessageGroupBatch]{
+		Channel:        deliveries,
+		OnConfirmation: rabbitmqSupport.Confirm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createGroupsBatch("drop")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Eventually(t, func() bool {
+		stats := mod.GetBroker().Stats("queue")
+		return stats.Acknowledged == 1 && stats.Unacked == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, deliveries, "batch without matching groups must not be delivered")
+}
+
+func TestInMemoryRawSubscriberAppliesFilters(t *testing.T) {
+	mod := createModuleFor(t, filtersCfg)
+	router := mod.GetMessageRouter()
+
+	deliveries := make(chan []byte, 3)
+	monitor, err := router.SubscribeRawAll(rabbitmqSupport.TestRawListener{Channel: deliveries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	mixed := createGroupsBatch("drop", "keep")
+	matching, err := proto.Marshal(createGroupsBatch("keep"))
+	if err != nil {
+		t.Fatal("cannot marshal batch", err)
+	}
+	if err := router.SendAll(mixed); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	if err := router.SendAll(createGroupsBatch("drop")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	if err := router.SendRawAll(matching, "publish"); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+
+	for _, expected := range []*grpcCommon.MessageGroupBatch{
+		{Groups: mixed.Groups[1:]},
+		createGroupsBatch("keep"),
+	} {
+		select {
+		case data := <-deliveries:
+			batch := &grpcCommon.MessageGroupBatch{}
+			if err := proto.Unmarshal(data, batch); err != nil {
+				t.Fatal("cannot unmarshal batch", err)
+			}
+			assert.True(t, proto.Equal(expected, batch), "unexpected batch: %v", batch)
+		case <-time.After(time.Second):
+			t.Fatal("batch is not delivered")
+		}
+	}
+	assert.Eventually(t, func() bool {
+		stats := mod.GetBroker().Stats("queue")
+		return stats.Delivered == 3 && stats.Pending == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, deliveries, "batch without matching groups must not be delivered")
+}
+
+func TestInMemoryRawManualSubscriberConfirmsDroppedBatch(t *testing.T) {
+	mod := createModuleFor(t, filtersCfg)
+	router := mod.GetMessageRouter()
+
+	deliveries := make(chan []byte, 1)
+	monitor, err := router.SubscribeRawAllWithManualAck(rabbitmqSupport.TestRawManualListener{
+		Channel:        deliveries,
+		OnConfirmation: rabbitmqSupport.Confirm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	if err := router.SendAll(createGroupsBatch("drop")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Eventually(t, func() bool {
+		stats := mod.GetBroker().Stats("queue")
+		return stats.Acknowledged == 1 && stats.Unacked == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, deliveries, "batch without matching groups must not be delivered")
+}
+
+func TestInMemoryEventRouterAppliesFilters(t *testing.T) {
+	mod := createModuleFor(t, filtersCfg)
+	router := mod.GetEventRouter()
+
+	deliveries := make(chan *grpcCommon.EventBatch, 1)
+	monitor, err := router.SubscribeAll(&rabbitmqSupport.GenericListener[grpcCommon.EventBatch]{
+		Channel: deliveries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Unsubscribe()
+
+	failed := &grpcCommon.EventBatch{Events: []*grpcCommon.Event{
+		{Id: &grpcCommon.EventID{Id: "failed"}, Type: "Order", Status: grpcCommon.EventStatus_FAILED},
+	}}
+	if err := router.SendAll(failed); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	events, err := mod.GetBroker().PublishedEvents("event-pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, events, "batch not matching the filter of publish pin must not be sent")
+
+	mixed := &grpcCommon.EventBatch{Events: []*grpcCommon.Event{
+		{Id: &grpcCommon.EventID{Id: "order"}, Type: "Order"},
+		{Id: &grpcCommon.EventID{Id: "other"}, Type: "Other"},
+	}}
+	if err := router.SendAll(mixed); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	rabbitmqSupport.CheckReceiveBatch(t, deliveries, &grpcCommon.EventBatch{Events: mixed.Events[:1]})
+}
+
+func TestInMemoryRawSenderAppliesFilters(t *testing.T) {
+	mod := createModuleFor(t, `{
+  "queues": {
+    "pub-pin": {
+      "attributes": ["publish"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "",
+      "filters": [
+        {
+          "metadata": {
+            "session_alias": {"value": "keep", "operation": "EQUAL"}
+          }
+        }
+      ]
+    }
+  }
+}`)
+	router := mod.GetMessageRouter()
+	for _, alias := range []string{"drop", "keep"} {
+		data, err := proto.Marshal(createGroupsBatch(alias))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := router.SendRawAll(data); err != nil {
+			t.Fatal("cannot send data", err)
+		}
+	}
+	assert.Len(t, mod.GetBroker().Published("pub-pin"), 1)
+	assert.Error(t, router.SendRawAll([]byte("not a batch")), "data that cannot be filtered must be rejected")
+}
+
+const splitFiltersCfg = `{
+  "queues": {
+    "pub-pin": {
+      "attributes": ["publish"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "",
+      "filters": [
+        {
+          "metadata": {
+            "session_alias": {"value": "keep", "operation": "EQUAL"}
+          }
+        }
+      ]
+    },
+    "transport-pub-pin": {
+      "attributes": ["publish", "transport-group"],
+      "exchange": "exchange",
+      "name": "transport_key",
+      "queue": "",
+      "filters": [
+        {
+          "metadata": {
+            "session_alias": {"value": "keep", "operation": "EQUAL"}
+          }
+        }
+      ]
+    }
+  }
+}`
+
+func filteredGroups(t *testing.T, prom *internal.TestPrometheus) float64 {
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "th2_message_filtered_groups_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetValue() == "pub-pin" {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestInMemoryRouterSplitsBatchInPerGroupFilterMode(t *testing.T) {
+	prom := internal.NewTestPrometheus()
+	factory := internal.CreateTestFactoryWithPrometheus(fstest.MapFS{
+		"mq": &fstest.MapFile{
+			Data: []byte(splitFiltersCfg),
+		},
+	}, prom)
+	if err := factory.Register(queue.NewInMemoryModule); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := queue.ModuleID.GetInMemoryModule(factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mod.Close()
+	router := mod.GetMessageRouter()
+	batch := createGroupsBatch("drop", "keep", "drop")
+
+	dropped := filteredGroups(t, prom)
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	assert.Empty(t, mod.GetBroker().Published("pub-pin"), "whole batch must be skipped by default")
+	assert.Equal(t, dropped+3, filteredGroups(t, prom))
+
+	selector, ok := router.(commonQueue.FilterModeSelector)
+	if !ok {
+		t.Fatal("message router does not support filter modes")
+	}
+	selector.SetFilterMode(commonQueue.FilterPerGroup)
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	data, err := proto.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := router.SendRawAll(data); err != nil {
+		t.Fatal("cannot send data", err)
+	}
+	if err := router.SendAll(createGroupsBatch("drop")); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	published, err := mod.GetBroker().PublishedMessages("pub-pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &grpcCommon.MessageGroupBatch{Groups: batch.Groups[1:2]}
+	if assert.Len(t, published, 2) {
+		assert.True(t, proto.Equal(expected, published[0]), "groups sent via router must be selected")
+		assert.True(t, proto.Equal(expected, published[1]), "groups sent as raw data must be selected")
+	}
+	assert.Equal(t, dropped+8, filteredGroups(t, prom))
+}
+
+func TestInMemoryTransportRouterSplitsBatchInPerGroupFilterMode(t *testing.T) {
+	mod := createModuleFor(t, splitFiltersCfg)
+	router := mod.GetTransportRouter()
+	selector, ok := router.(commonQueue.FilterModeSelector)
+	if !ok {
+		t.Fatal("transport router does not support filter modes")
+	}
+	selector.SetFilterMode(commonQueue.FilterPerGroup)
+
+	batch := createTransportBatch("drop")
+	batch.Groups = append(batch.Groups, createTransportBatch("keep").Groups...)
+	if err := router.SendAll(batch); err != nil {
+		t.Fatal("cannot send batch", err)
+	}
+	published := mod.GetBroker().Published("transport-pub-pin")
+	if assert.Len(t, published, 1) {
+		decoded, err := transport.Decode(published[0].Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, batch.Groups[1:], decoded.Groups)
+	}
+}
@@ -17,10 +17,19 @@
 package prometheus
 
 import (
-	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
-	"github.com/th2-net/th2-common-go/test/modules/internal"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"testing"
 	"testing/fstest"
+	"time"
+
+	client "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	metricsServer "github.com/th2-net/th2-common-go/pkg/metrics/prometheus"
+	"github.com/th2-net/th2-common-go/pkg/modules/prometheus"
+	"github.com/th2-net/th2-common-go/test/modules/internal"
 )
 
 func TestCanRegisterPrometheus(t *testing.T) {
@@ -49,3 +58,102 @@ func TestCanRegisterPrometheus(t *testing.T) {
 		t.Fatal("module is nil")
 	}
 }
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestPrometheusModulesUseOwnRegistries(t *testing.T) {
+	port := freePort(t)
+	first, err := prometheus.New(metricsServer.Configuration{Host: "127.0.0.1", Port: port, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = first.Close() })
+	second, err := prometheus.New(metricsServer.Configuration{Host: "127.0.0.1", Port: freePort(t)})
+	if err != nil {
+		t.Fatal("second module must not conflict with the first one", err)
+	}
+	t.Cleanup(func() { _ = second.Close() })
+	assert.NotSame(t, first.GetRegistry(), second.GetRegistry())
+
+	counter := client.NewCounter(client.CounterOpts{Name: "box_custom_total", Help: "custom metric of the box"})
+	first.GetRegistry().MustRegister(counter)
+	counter.Inc()
+
+	body := scrape(t, port)
+	assert.Contains(t, body, "box_custom_total 1")
+	assert.Contains(t, body, "th2_readiness")
+}
+
+func scrape(t *testing.T, port int) string {
+	var body string
+	assert.Eventually(t, func() bool {
+		response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+		if err != nil {
+			return false
+		}
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		body = string(data)
+		return err == nil && response.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	return body
+}
+
+func TestPrometheusModuleExposesDefaultRegistry(t *testing.T) {
+	counter := client.NewCounter(client.CounterOpts{Name: "box_default_registry_total", Help: "metric registered globally"})
+	client.MustRegister(counter)
+	t.Cleanup(func() { client.Unregister(counter) })
+	counter.Inc()
+
+	port := freePort(t)
+	mod, err := prometheus.New(metricsServer.Configuration{Host: "127.0.0.1", Port: port, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mod.Close() })
+	body := scrape(t, port)
+	assert.Contains(t, body, "box_default_registry_total 1")
+	assert.Contains(t, body, "th2_liveness")
+	assert.Contains(t, body, "go_goroutines")
+
+	isolatedPort := freePort(t)
+	isolated, err := prometheus.New(metricsServer.Configuration{Host: "127.0.0.1", Port: isolatedPort, Enabled: true, DisableDefaultRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = isolated.Close() })
+	body = scrape(t, isolatedPort)
+	assert.NotContains(t, body, "box_default_registry_total")
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestPrometheusModuleReportsMetricsCollectedByBothRegistries(t *testing.T) {
+	port := freePort(t)
+	mod, err := prometheus.New(metricsServer.Configuration{Host: "127.0.0.1", Port: port, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = mod.Close() })
+
+	opts := client.CounterOpts{Name: "box_duplicated_total", Help: "metric registered in both registries"}
+	global := client.NewCounter(opts)
+	client.MustRegister(global)
+	t.Cleanup(func() { client.Unregister(global) })
+	mod.GetRegistry().MustRegister(client.NewCounter(opts))
+
+	assert.Eventually(t, func() bool {
+		response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+		if err != nil {
+			return false
+		}
+		defer response.Body.Close()
+		return response.StatusCode == http.StatusInternalServerError
+	}, time.Second, 10*time.Millisecond, "duplicated metrics must not be served partially")
+}
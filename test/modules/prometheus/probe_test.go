@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/th2-net/th2-common-go/pkg/metrics"
+	"github.com/th2-net/th2-common-go/pkg/metrics/prometheus"
+)
+
+type probeResponse struct {
+	Status   string                  `json:"status"`
+	Monitors []metrics.MonitorStatus `json:"monitors"`
+}
+
+func probe(t *testing.T, handler http.Handler) (int, probeResponse) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, prometheus.ReadinessPath, nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var response probeResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal("cannot decode response", err)
+	}
+	return recorder.Code, response
+}
+
+func TestProbeHandlerReportsMonitors(t *testing.T) {
+	arbiter := metrics.NewFlagArbiter()
+	first := arbiter.RegisterMonitor("first")
+	second := arbiter.RegisterMonitor("second")
+	handler := prometheus.NewProbeHandler(arbiter)
+
+	first.Enable()
+	code, response := probe(t, handler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, probeResponse{
+		Status: "DOWN",
+		Monitors: []metrics.MonitorStatus{
+			{Name: "first", Enabled: true},
+			{Name: "second", Enabled: false},
+		},
+	}, response)
+
+	second.Enable()
+	code, response = probe(t, handler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", response.Status)
+}
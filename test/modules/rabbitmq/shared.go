@@ -201,6 +201,16 @@ func (h RawAmqpHolder) Consume(queue amqp.Queue) <-chan amqp.Delivery {
 	return deliveries
 }
 
+// TryConsume starts consuming and returns the error instead of failing the test.
+// The channel of the holder is closed by the broker if the consuming is refused
+func (h RawAmqpHolder) TryConsume(queue amqp.Queue) (<-chan amqp.Delivery, error) {
+	return h.ch.Consume(
+		queue.Name,
+		fmt.Sprintf("test-consumer-%d", time.Now().UnixNano()),
+		true, false,
+		false, false, amqp.Table{})
+}
+
 type TestRawListener struct {
 	Channel chan []byte
 }
@@ -214,6 +224,21 @@ func (t TestRawListener) Handle(delivery queue.Delivery, data []byte) error {
 	return nil
 }
 
+type TestRawManualListener struct {
+	Channel        chan []byte
+	OnConfirmation func(confirmation queue.Confirmation)
+}
+
+func (t TestRawManualListener) OnClose() error {
+	return nil
+}
+
+func (t TestRawManualListener) Handle(_ queue.Delivery, data []byte, confirm queue.Confirmation) error {
+	t.Channel <- data
+	t.OnConfirmation(confirm)
+	return nil
+}
+
 type GenericListener[T any] struct {
 	Channel chan *T
 }
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2026 Exactpro (Exactpro Systems Limited)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"context"
+	"net"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonGrpc "github.com/th2-net/th2-common-go/pkg/grpc"
+	"github.com/th2-net/th2-common-go/pkg/modules/grpc"
+	"github.com/th2-net/th2-common-go/pkg/modules/queue"
+	"github.com/th2-net/th2-common-go/pkg/modules/tracing"
+	commonQueue "github.com/th2-net/th2-common-go/pkg/queue"
+	commonTracing "github.com/th2-net/th2-common-go/pkg/tracing"
+	"github.com/th2-net/th2-common-go/test/modules/internal"
+	grpcCommon "github.com/th2-net/th2-grpc-common-go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	googleGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const mqCfg = `{
+  "queues": {
+    "pub-pin": {
+      "attributes": ["publish", "raw"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": ""
+    },
+    "sub-pin": {
+      "attributes": ["subscribe", "raw"],
+      "exchange": "exchange",
+      "name": "key",
+      "queue": "queue"
+    }
+  }
+}`
+
+// startTracing registers the tracing module and records the spans instead of exporting them
+func startTracing(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.New(commonTracing.Config{}, "test")
+	require.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+type contextListener struct {
+	contexts chan context.Context
+}
+
+func (l *contextListener) Handle(delivery commonQueue.Delivery, _ *grpcCommon.MessageGroupBatch) error {
+	l.contexts <- delivery.Context
+	return nil
+}
+
+func (l *contextListener) OnClose() error {
+	return nil
+}
+
+func TestTraceContextIsPropagatedThroughQueue(t *testing.T) {
+	recorder := startTracing(t)
+	factory := internal.CreateTestFactory(fstest.MapFS{
+		"mq": &fstest.MapFile{Data: []byte(mqCfg)},
+	})
+	require.NoError(t, factory.Register(queue.NewInMemoryModule))
+	mod, err := queue.ModuleID.GetInMemoryModule(factory)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mod.Close() })
+
+	listener := &contextListener{contexts: make(chan context.Context, 1)}
+	monitor, err := mod.GetMessageRouter().SubscribeAll(listener, "raw")
+	require.NoError(t, err)
+	defer monitor.Unsubscribe()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	require.NoError(t, mod.GetMessageRouter().SendAllCtx(ctx, &grpcCommon.MessageGroupBatch{}, "raw"))
+	parent.End()
+
+	select {
+	case deliveryCtx := <-listener.contexts:
+		spanContext := trace.SpanContextFromContext(deliveryCtx)
+		assert.Equal(t, parent.SpanContext().TraceID(), spanContext.TraceID(), "listener must continue the trace")
+	case <-time.After(time.Second):
+		t.Fatal("batch is not delivered")
+	}
+
+	published := mod.GetBroker().Published("pub-pin")
+	if assert.Len(t, published, 1) {
+		assert.Contains(t, published[0].Headers, "traceparent")
+	}
+	assert.Eventually(t, func() bool {
+		kinds := make(map[trace.SpanKind]bool)
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+				kinds[span.SpanKind()] = true
+			}
+		}
+		return kinds[trace.SpanKindProducer] && kinds[trace.SpanKindConsumer]
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTraceContextIsPropagatedThroughGrpc(t *testing.T) {
+	startTracing(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+	address := commonGrpc.Address{Host: "127.0.0.1", Port: port}
+	config := commonGrpc.Config{
+		ServerConfig: commonGrpc.Server{Endpoint: commonGrpc.Endpoint{Address: address}},
+		ServicesMap: commonGrpc.Services{
+			"health": {
+				ServiceClass: "grpc.health.v1.Health",
+				Endpoints:    map[string]commonGrpc.Endpoint{"server": {Address: address}},
+			},
+		},
+	}
+	serverContexts := make(chan context.Context, 1)
+	mod, err := grpc.NewWithOptions(config, commonGrpc.Options{
+		UnaryServerInterceptors: []googleGrpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *googleGrpc.UnaryServerInfo, handler googleGrpc.UnaryHandler) (any, error) {
+				serverContexts <- ctx
+				return handler(ctx, req)
+			},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mod.Close() })
+	stop, err := mod.GetRouter().StartServerAsync(func(registrar googleGrpc.ServiceRegistrar) {
+		healthpb.RegisterHealthServer(registrar, health.NewServer())
+	})
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	conn, err := mod.GetRouter().GetConnection("Health")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx, parent := otel.Tracer("test").Start(ctx, "parent")
+	defer parent.End()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, googleGrpc.WaitForReady(true))
+	require.NoError(t, err)
+
+	serverCtx := <-serverContexts
+	assert.Equal(t, parent.SpanContext().TraceID(), trace.SpanContextFromContext(serverCtx).TraceID(),
+		"server must continue the trace of the client")
+}
+
+func TestModuleExportsSpansToStdout(t *testing.T) {
+	mod, err := tracing.New(commonTracing.Config{Enabled: true, Exporter: commonTracing.StdoutExporter}, "test")
+	require.NoError(t, err)
+	assert.True(t, mod.IsEnabled())
+	assert.NoError(t, mod.Close())
+}
+
+func TestModuleRejectsUnknownExporter(t *testing.T) {
+	_, err := tracing.New(commonTracing.Config{Enabled: true, Exporter: "unknown"}, "test")
+	assert.Error(t, err)
+}